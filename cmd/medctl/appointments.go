@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/justanamir/medappoint/internal/api"
+	"github.com/justanamir/medappoint/internal/config"
+	dbconn "github.com/justanamir/medappoint/internal/db"
+	"github.com/justanamir/medappoint/internal/db/gen"
+)
+
+func appointmentsCmd(ctx context.Context, cfg config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: medctl appointments list|cancel [flags]")
+	}
+	switch args[0] {
+	case "list":
+		return listAppointments(ctx, cfg, args[1:])
+	case "cancel":
+		return cancelAppointment(ctx, cfg, args[1:])
+	}
+	return fmt.Errorf("unknown appointments action %q", args[0])
+}
+
+// listAppointments prints one clinic day, like GET /v1/admin/appointments,
+// and audits the read the same way.
+func listAppointments(ctx context.Context, cfg config.Config, args []string) error {
+	fs := flag.NewFlagSet("appointments list", flag.ContinueOnError)
+	date := fs.String("date", "", "day to list, YYYY-MM-DD (default today)")
+	clinicID := fs.Int64("clinic-id", 0, "only this clinic")
+	providerID := fs.Int64("provider-id", 0, "only this provider")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	loc, _ := time.LoadLocation("Asia/Kuala_Lumpur")
+	day := time.Now().In(loc)
+	if *date != "" {
+		d, err := time.ParseInLocation("2006-01-02", *date, loc)
+		if err != nil {
+			return errors.New("-date must be YYYY-MM-DD")
+		}
+		day = d
+	}
+	dayStart := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+
+	pg, kr, err := connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer pg.Close()
+	q := gen.New(dbconn.Encrypted(pg.Pool, kr))
+
+	p := gen.ListAllAppointmentsOnDateParams{DayStart: dayStart, DayEnd: dayStart.AddDate(0, 0, 1)}
+	if *clinicID > 0 {
+		p.ClinicIds = []int64{*clinicID}
+	}
+	rows, err := q.ListAllAppointmentsOnDate(ctx, p)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSTART\tEND\tSTATUS\tCLINIC\tPROVIDER\tPATIENT\tSERVICE")
+	seen := map[int64]bool{}
+	var patientIDs []int64
+	for _, a := range rows {
+		if *providerID > 0 && a.ProviderID != *providerID {
+			continue
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			a.ID,
+			a.StartTime.In(loc).Format("2006-01-02 15:04"),
+			a.EndTime.In(loc).Format("15:04"),
+			a.Status, a.ClinicName, a.ProviderName, deref(a.PatientName), a.ServiceName)
+		if !seen[a.PatientID] {
+			seen[a.PatientID] = true
+			patientIDs = append(patientIDs, a.PatientID)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if len(patientIDs) == 0 {
+		return nil
+	}
+	return q.CreateAuditEventsForPatients(ctx, gen.CreateAuditEventsForPatientsParams{
+		ActorRole:    auditRole,
+		Action:       api.AuditAppointmentList,
+		ResourceType: api.AuditResClinicDay,
+		PatientIds:   patientIDs,
+		ClinicID:     optInt8(*clinicID),
+	})
+}
+
+// cancelAppointment cancels a scheduled visit on nobody's behalf (cancelled_by
+// stays empty); past visits need -force.
+func cancelAppointment(ctx context.Context, cfg config.Config, args []string) error {
+	fs := flag.NewFlagSet("appointments cancel", flag.ContinueOnError)
+	id := fs.Int64("id", 0, "appointment id")
+	reason := fs.String("reason", "", "cancellation reason (at most 500 characters)")
+	force := fs.Bool("force", false, "cancel even if the visit has already started")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *id <= 0 {
+		return errors.New("-id is required")
+	}
+	var why *string
+	if s := strings.TrimSpace(*reason); s != "" {
+		if len(s) > 500 {
+			return errors.New("reason must be at most 500 characters")
+		}
+		why = &s
+	}
+
+	pg, kr, err := connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer pg.Close()
+	q := gen.New(dbconn.Encrypted(pg.Pool, kr))
+
+	appt, err := q.GetAppointment(ctx, *id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("appointment %d not found", *id)
+	}
+	if err != nil {
+		return err
+	}
+	if !appt.StartTime.After(time.Now()) && !*force {
+		return fmt.Errorf("appointment %d started at %s; use -force to cancel it anyway", appt.ID, appt.StartTime.Format(time.RFC3339))
+	}
+	row, err := q.CancelAppointment(ctx, gen.CancelAppointmentParams{Reason: why, ID: appt.ID})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("appointment %d is %s, not scheduled", appt.ID, appt.Status)
+	}
+	if err != nil {
+		return err
+	}
+	if err := q.CreateAuditEvent(ctx, gen.CreateAuditEventParams{
+		ActorRole:    auditRole,
+		Action:       api.AuditAppointmentCancel,
+		ResourceType: api.AuditResAppointment,
+		ResourceID:   optInt8(row.ID),
+		PatientID:    optInt8(row.PatientID),
+		ClinicID:     optInt8(row.ClinicID),
+	}); err != nil {
+		return fmt.Errorf("cancelled, but the audit write failed: %w", err)
+	}
+	fmt.Printf("cancelled appointment %d\n", row.ID)
+	return nil
+}
+
+func optInt8(v int64) pgtype.Int8 {
+	return pgtype.Int8{Int64: v, Valid: v != 0}
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/justanamir/medappoint/internal/config"
+	"github.com/justanamir/medappoint/internal/importer"
+)
+
+// importCmd loads services, providers and weekly availability for a clinic
+// from CSV files or one JSON batch, in a single transaction; see
+// internal/importer for the CSV columns. The report is printed as JSON
+// either way, and a batch with problems is an error.
+func importCmd(ctx context.Context, cfg config.Config, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	var (
+		jsonFile = fs.String("json", "", "JSON batch file (instead of the CSV flags)")
+		clinicID = fs.Int64("clinic-id", 0, "existing clinic to import into")
+		name     = fs.String("clinic-name", "", "name of a clinic to create")
+		tz       = fs.String("clinic-timezone", "", "IANA timezone of a clinic to create")
+		address  = fs.String("clinic-address", "", "address of a clinic to create")
+		dryRun   = fs.Bool("dry-run", false, "validate and roll back")
+	)
+	files := map[string]*string{}
+	for _, section := range importer.Sections {
+		files[section] = fs.String(section, "", section+" CSV file")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var b importer.Batch
+	if *jsonFile != "" {
+		data, err := os.ReadFile(*jsonFile)
+		if err == nil {
+			err = json.Unmarshal(data, &b)
+		}
+		if err != nil {
+			return fmt.Errorf("read batch %s: %w", *jsonFile, err)
+		}
+	} else {
+		b.Clinic = importer.Clinic{ID: *clinicID, Name: *name, Timezone: *tz}
+		if *address != "" {
+			b.Clinic.Address = address
+		}
+		for _, section := range importer.Sections {
+			if *files[section] == "" {
+				continue
+			}
+			if err := readCSV(&b, section, *files[section]); err != nil {
+				return fmt.Errorf("read %s: %w", *files[section], err)
+			}
+		}
+	}
+
+	pg, kr, err := connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer pg.Close()
+	rep, err := importer.Run(ctx, pg.Pool, kr, b, *dryRun)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(rep)
+	if !rep.OK() {
+		return fmt.Errorf("%d problems in the batch", len(rep.Problems))
+	}
+	return nil
+}
+
+func readCSV(b *importer.Batch, section, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return importer.ReadCSV(b, section, f)
+}
@@ -0,0 +1,93 @@
+// Command medctl is the operations tool that ships next to the server. It
+// reads the same environment (and .env) as cmd/server.
+//
+//	medctl migrate [-dir migrations] up [n] | down [n] | status
+//	medctl create-admin -email ops@example.com [-password-stdin]
+//	medctl reset-password -email someone@example.com [-password-stdin]
+//	medctl appointments list [-date YYYY-MM-DD] [-clinic-id N] [-provider-id N]
+//	medctl appointments cancel -id N [-reason "..."] [-force]
+//	medctl import [-dry-run] (-clinic-id N | -clinic-name ... -clinic-timezone ...) [-services f.csv] [-providers f.csv] [-availability f.csv] | -json batch.json
+//	medctl check-config [-dir migrations]
+//
+// Changes made here don't reach a running server's next-available cache
+// until it expires (NEXT_AVAILABLE_TTL_SECONDS).
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/justanamir/medappoint/internal/config"
+	dbconn "github.com/justanamir/medappoint/internal/db"
+	"github.com/justanamir/medappoint/internal/db/gen"
+	"github.com/justanamir/medappoint/internal/fieldcrypt"
+)
+
+// auditRole is the actor role on audit entries written by medctl.
+const auditRole = "medctl"
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, cfg config.Config, args []string) error
+}
+
+var commands = map[string]command{
+	"migrate":        {"[-dir migrations] up [n] | down [n] | status", migrateCmd},
+	"create-admin":   {"-email ADDRESS [-password-stdin]", createAdminCmd},
+	"reset-password": {"-email ADDRESS [-password-stdin]", resetPasswordCmd},
+	"appointments":   {"list [-date YYYY-MM-DD] [-clinic-id N] [-provider-id N] | cancel -id N [-reason TEXT] [-force]", appointmentsCmd},
+	"import":         {"[-dry-run] (-clinic-id N | -clinic-name NAME -clinic-timezone TZ) [-services F] [-providers F] [-availability F] | -json F", importCmd},
+	"check-config":   {"[-dir migrations]", checkConfigCmd},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+	_ = godotenv.Load()
+	cfg := config.FromEnv()
+	if err := cmd.run(context.Background(), cfg, os.Args[2:]); err != nil {
+		slog.New(slog.NewTextHandler(os.Stderr, nil)).Error(os.Args[1]+" failed", "err", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	names := []string{"migrate", "create-admin", "reset-password", "appointments", "import", "check-config"}
+	var b strings.Builder
+	b.WriteString("usage: medctl <command> [flags]\n\n")
+	for _, n := range names {
+		fmt.Fprintf(&b, "  %-15s %s\n", n, commands[n].usage)
+	}
+	fmt.Fprint(os.Stderr, b.String())
+}
+
+// connect opens the database and loads the field keys, as the server does;
+// wrap the pool or a transaction with dbconn.Encrypted before querying.
+func connect(ctx context.Context, cfg config.Config) (*dbconn.Handle, *fieldcrypt.Keyring, error) {
+	pg, err := dbconn.Connect(ctx, cfg.PGConnString(""))
+	if err != nil {
+		return nil, nil, err
+	}
+	kek, err := fieldcrypt.LoadKEK(cfg.FieldKEKFile)
+	if err != nil {
+		pg.Close()
+		return nil, nil, err
+	}
+	kr, err := dbconn.LoadKeyring(ctx, gen.New(pg.Pool), kek)
+	if err != nil {
+		pg.Close()
+		return nil, nil, fmt.Errorf("load field keys: %w", err)
+	}
+	return pg, kr, nil
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/justanamir/medappoint/internal/config"
+	dbconn "github.com/justanamir/medappoint/internal/db"
+)
+
+// Migrations are the NNNN_name.up.sql / NNNN_name.down.sql pairs in -dir.
+// The applied version is kept in schema_migrations the way golang-migrate
+// keeps it (a single row of version and dirty), so either tool can pick up
+// where the other left off. Each step runs in one transaction together
+// with the version change, so a failed step leaves nothing behind.
+
+type migration struct {
+	version  int64
+	name     string
+	up, down string // file paths; down may be empty
+}
+
+var migrationFile = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+func migrateCmd(ctx context.Context, cfg config.Config, args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dir := fs.String("dir", "migrations", "directory with the .up.sql and .down.sql files")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		return errors.New("usage: medctl migrate [-dir migrations] up [n] | down [n] | status")
+	}
+	action := fs.Arg(0)
+	steps := 0
+	if fs.NArg() == 2 {
+		n, err := strconv.Atoi(fs.Arg(1))
+		if err != nil || n < 1 {
+			return errors.New("n must be a positive number of steps")
+		}
+		steps = n
+	}
+
+	migs, err := loadMigrations(*dir)
+	if err != nil {
+		return err
+	}
+	pg, err := dbconn.Connect(ctx, cfg.PGConnString(""))
+	if err != nil {
+		return err
+	}
+	defer pg.Close()
+	cur, dirty, err := schemaVersion(ctx, pg.Pool)
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "status":
+		fmt.Printf("version %d", cur)
+		if dirty {
+			fmt.Print(" (dirty)")
+		}
+		fmt.Println()
+		for _, m := range migs {
+			if m.version > cur {
+				fmt.Printf("pending %04d_%s\n", m.version, m.name)
+			}
+		}
+		return nil
+	case "up", "down":
+	default:
+		return fmt.Errorf("unknown migrate action %q", action)
+	}
+	if dirty {
+		return fmt.Errorf("schema_migrations is dirty at version %d; repair the schema by hand and clear the flag first", cur)
+	}
+
+	if action == "up" {
+		applied := 0
+		for _, m := range migs {
+			if m.version <= cur || (steps > 0 && applied == steps) {
+				continue
+			}
+			v := m.version
+			if err := step(ctx, pg.Pool, m.up, &v); err != nil {
+				return fmt.Errorf("%04d_%s up: %w", m.version, m.name, err)
+			}
+			fmt.Printf("applied %04d_%s\n", m.version, m.name)
+			applied++
+		}
+		if applied == 0 {
+			fmt.Println("no change")
+		}
+		return nil
+	}
+
+	if steps == 0 {
+		steps = 1
+	}
+	for ; steps > 0 && cur > 0; steps-- {
+		i := sort.Search(len(migs), func(i int) bool { return migs[i].version >= cur })
+		if i == len(migs) || migs[i].version != cur {
+			return fmt.Errorf("no migration file for applied version %d", cur)
+		}
+		m := migs[i]
+		if m.down == "" {
+			return fmt.Errorf("%04d_%s has no down migration", m.version, m.name)
+		}
+		var prev *int64
+		if i > 0 {
+			prev = &migs[i-1].version
+		}
+		if err := step(ctx, pg.Pool, m.down, prev); err != nil {
+			return fmt.Errorf("%04d_%s down: %w", m.version, m.name, err)
+		}
+		fmt.Printf("reverted %04d_%s\n", m.version, m.name)
+		cur = 0
+		if prev != nil {
+			cur = *prev
+		}
+	}
+	return nil
+}
+
+func loadMigrations(dir string) ([]migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*migration{}
+	for _, e := range entries {
+		m := migrationFile.FindStringSubmatch(e.Name())
+		if m == nil || e.IsDir() {
+			continue
+		}
+		v, _ := strconv.ParseInt(m[1], 10, 64)
+		mig := byVersion[v]
+		if mig == nil {
+			mig = &migration{version: v, name: m[2]}
+			byVersion[v] = mig
+		} else if mig.name != m[2] {
+			return nil, fmt.Errorf("two migrations numbered %d", v)
+		}
+		path := filepath.Join(dir, e.Name())
+		if m[3] == "up" {
+			mig.up = path
+		} else {
+			mig.down = path
+		}
+	}
+	out := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" {
+			return nil, fmt.Errorf("%04d_%s has no up migration", m.version, m.name)
+		}
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].version < out[j].version })
+	return out, nil
+}
+
+// schemaVersion reads the applied version; 0 when nothing has been applied.
+func schemaVersion(ctx context.Context, pool *pgxpool.Pool) (int64, bool, error) {
+	var exists bool
+	if err := pool.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil || !exists {
+		return 0, false, err
+	}
+	var v int64
+	var dirty bool
+	err := pool.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&v, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	return v, dirty, err
+}
+
+// step runs one migration file and records version (nil: none applied).
+func step(ctx context.Context, pool *pgxpool.Pool, path string, version *int64) error {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	// no arguments: pgx sends it as a simple query, so several statements are fine
+	if _, err := tx.Exec(ctx, string(body)); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)`); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `TRUNCATE schema_migrations`); err != nil {
+		return err
+	}
+	if version != nil {
+		if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)`, *version); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
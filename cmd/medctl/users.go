@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/justanamir/medappoint/internal/auth"
+	"github.com/justanamir/medappoint/internal/config"
+	dbconn "github.com/justanamir/medappoint/internal/db"
+	"github.com/justanamir/medappoint/internal/db/gen"
+	"github.com/justanamir/medappoint/internal/rbac"
+)
+
+const minPasswordLen = 8
+
+func createAdminCmd(ctx context.Context, cfg config.Config, args []string) error {
+	email, fromStdin, err := userFlags("create-admin", args)
+	if err != nil {
+		return err
+	}
+	pw, generated, err := readPassword(fromStdin)
+	if err != nil {
+		return err
+	}
+	hash, err := auth.HashPassword(pw)
+	if err != nil {
+		return err
+	}
+
+	pg, kr, err := connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer pg.Close()
+	tx, err := pg.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	q := gen.New(dbconn.Encrypted(tx, kr))
+
+	u, err := q.CreateUser(ctx, gen.CreateUserParams{Email: email, PasswordHash: hash, Role: rbac.RoleAdmin})
+	if err != nil {
+		return fmt.Errorf("create user: %w", err)
+	}
+	// no clinic: an admin everywhere
+	if _, err := q.CreateRoleAssignment(ctx, gen.CreateRoleAssignmentParams{
+		UserID: u.ID,
+		Role:   rbac.RoleAdmin,
+	}); err != nil {
+		return fmt.Errorf("assign role: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	fmt.Printf("created admin %s (user %d)\n", email, u.ID)
+	if generated {
+		fmt.Printf("password: %s\n", pw)
+	}
+	return nil
+}
+
+func resetPasswordCmd(ctx context.Context, cfg config.Config, args []string) error {
+	email, fromStdin, err := userFlags("reset-password", args)
+	if err != nil {
+		return err
+	}
+	pw, generated, err := readPassword(fromStdin)
+	if err != nil {
+		return err
+	}
+	hash, err := auth.HashPassword(pw)
+	if err != nil {
+		return err
+	}
+
+	pg, kr, err := connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer pg.Close()
+	n, err := gen.New(dbconn.Encrypted(pg.Pool, kr)).UpdateUserPassword(ctx, gen.UpdateUserPasswordParams{
+		Email:        email,
+		PasswordHash: hash,
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("no user with email %s", email)
+	}
+	fmt.Printf("password reset for %s\n", email)
+	if generated {
+		fmt.Printf("password: %s\n", pw)
+	}
+	return nil
+}
+
+func userFlags(name string, args []string) (email string, fromStdin bool, err error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&email, "email", "", "user email")
+	fs.BoolVar(&fromStdin, "password-stdin", false, "read the password from the first line of stdin instead of generating one")
+	if err := fs.Parse(args); err != nil {
+		return "", false, err
+	}
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" || !strings.Contains(email, "@") {
+		return "", false, errors.New("-email is required")
+	}
+	return email, fromStdin, nil
+}
+
+// readPassword takes the first line of stdin, or makes up a password that
+// is printed once; it never comes in as a flag, which would end up in
+// shell history and the process list.
+func readPassword(fromStdin bool) (pw string, generated bool, err error) {
+	if !fromStdin {
+		b := make([]byte, 18)
+		if _, err := rand.Read(b); err != nil {
+			return "", false, err
+		}
+		return base64.RawURLEncoding.EncodeToString(b), true, nil
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", false, errors.New("no password on stdin")
+	}
+	pw = strings.TrimRight(line, "\r\n")
+	if len(pw) < minPasswordLen {
+		return "", false, fmt.Errorf("password must be at least %d characters", minPasswordLen)
+	}
+	return pw, false, nil
+}
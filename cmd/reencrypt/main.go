@@ -0,0 +1,261 @@
+// Command reencrypt re-seals every encrypted column under the newest data
+// key: legacy plaintext gets encrypted, values under older key versions are
+// rotated forward, and values sealed under the bare column name are bound to
+// "table.column" (their blind indexes change with it, so phone and dob search
+// only finds them after this run). It is safe to re-run and to run while the server is
+// up; rows that change mid-run are skipped and picked up next time.
+//
+//	go run ./cmd/reencrypt [-rotate] [-batch 500]
+//	go run ./cmd/reencrypt -decrypt   # write plaintext back before rolling back 0011
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+
+	"github.com/joho/godotenv"
+	"github.com/justanamir/medappoint/internal/config"
+	dbconn "github.com/justanamir/medappoint/internal/db"
+	"github.com/justanamir/medappoint/internal/db/gen"
+	"github.com/justanamir/medappoint/internal/fieldcrypt"
+)
+
+func main() {
+	rotate := flag.Bool("rotate", false, "create a new data key version first")
+	decrypt := flag.Bool("decrypt", false, "write plaintext back instead of encrypting")
+	batch := flag.Int("batch", 500, "rows per query")
+	flag.Parse()
+
+	_ = godotenv.Load()
+	cfg := config.FromEnv()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	if err := run(context.Background(), cfg, logger, *rotate, *decrypt, int32(*batch)); err != nil {
+		logger.Error("reencrypt failed", "err", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, cfg config.Config, logger *slog.Logger, rotate, decrypt bool, batch int32) error {
+	pg, err := dbconn.Connect(ctx, cfg.PGConnString(""))
+	if err != nil {
+		return err
+	}
+	defer pg.Close()
+	// raw queries: this command reads and writes ciphertext itself
+	q := gen.New(pg.Pool)
+
+	kek, err := fieldcrypt.LoadKEK(cfg.FieldKEKFile)
+	if err != nil {
+		return err
+	}
+	if rotate {
+		v, err := dbconn.RotateDataKey(ctx, q, kek)
+		if err != nil {
+			return err
+		}
+		logger.Info("created data key", "version", v)
+	}
+	kr, err := dbconn.LoadKeyring(ctx, q, kek)
+	if err != nil {
+		return err
+	}
+	r := resealer{kr: kr, decrypt: decrypt}
+
+	var updated, skipped int64
+	for after := int64(0); ; {
+		rows, err := q.ListPatientCiphertexts(ctx, gen.ListPatientCiphertextsParams{AfterID: after, RowLimit: batch})
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			after = row.ID
+			phone, c1, err := r.reseal("patients.phone", row.PhoneCt)
+			if err != nil {
+				return err
+			}
+			dob, c2, err := r.reseal("patients.dob", row.DobCt)
+			if err != nil {
+				return err
+			}
+			if !c1 && !c2 {
+				continue
+			}
+			n, err := q.UpdatePatientCiphertexts(ctx, gen.UpdatePatientCiphertextsParams{
+				PhoneCt:    phone,
+				DobCt:      dob,
+				ID:         row.ID,
+				OldPhoneCt: row.PhoneCt,
+				OldDobCt:   row.DobCt,
+			})
+			if err != nil {
+				return err
+			}
+			updated += n
+			skipped += 1 - n
+		}
+		if int32(len(rows)) < batch {
+			break
+		}
+	}
+	logger.Info("patients done", "updated", updated, "skipped", skipped)
+
+	updated, skipped = 0, 0
+	for after := int64(0); ; {
+		rows, err := q.ListAppointmentNoteCiphertexts(ctx, gen.ListAppointmentNoteCiphertextsParams{AfterID: after, RowLimit: batch})
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			after = row.ID
+			notes, changed, err := r.reseal("appointments.notes", row.NotesCt)
+			if err != nil {
+				return err
+			}
+			if !changed {
+				continue
+			}
+			n, err := q.UpdateAppointmentNoteCiphertext(ctx, gen.UpdateAppointmentNoteCiphertextParams{
+				NotesCt:    notes,
+				ID:         row.ID,
+				OldNotesCt: row.NotesCt,
+			})
+			if err != nil {
+				return err
+			}
+			updated += n
+			skipped += 1 - n
+		}
+		if int32(len(rows)) < batch {
+			break
+		}
+	}
+	logger.Info("appointment notes done", "updated", updated, "skipped", skipped)
+
+	updated, skipped = 0, 0
+	for after := int64(0); ; {
+		rows, err := q.ListIntakeAnswerCiphertexts(ctx, gen.ListIntakeAnswerCiphertextsParams{AfterID: after, RowLimit: batch})
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			after = row.ID
+			answers, changed, err := r.reseal("intake_responses.answers", &row.AnswersCt)
+			if err != nil {
+				return err
+			}
+			if !changed {
+				continue
+			}
+			n, err := q.UpdateIntakeAnswerCiphertext(ctx, gen.UpdateIntakeAnswerCiphertextParams{
+				AnswersCt:    *answers,
+				ID:           row.ID,
+				OldAnswersCt: row.AnswersCt,
+			})
+			if err != nil {
+				return err
+			}
+			updated += n
+			skipped += 1 - n
+		}
+		if int32(len(rows)) < batch {
+			break
+		}
+	}
+	logger.Info("intake answers done", "updated", updated, "skipped", skipped)
+
+	updated, skipped = 0, 0
+	for after := int64(0); ; {
+		rows, err := q.ListVisitNoteCiphertexts(ctx, gen.ListVisitNoteCiphertextsParams{AfterID: after, RowLimit: batch})
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			after = row.ID
+			body, changed, err := r.reseal("visit_notes.body", &row.BodyCt)
+			if err != nil {
+				return err
+			}
+			if !changed {
+				continue
+			}
+			n, err := q.UpdateVisitNoteCiphertext(ctx, gen.UpdateVisitNoteCiphertextParams{
+				BodyCt:    *body,
+				ID:        row.ID,
+				OldBodyCt: row.BodyCt,
+			})
+			if err != nil {
+				return err
+			}
+			updated += n
+			skipped += 1 - n
+		}
+		if int32(len(rows)) < batch {
+			break
+		}
+	}
+	logger.Info("visit notes done", "updated", updated, "skipped", skipped)
+
+	updated, skipped = 0, 0
+	for after := int64(0); ; {
+		rows, err := q.ListAttachmentFilenameCiphertexts(ctx, gen.ListAttachmentFilenameCiphertextsParams{AfterID: after, RowLimit: batch})
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			after = row.ID
+			filename, changed, err := r.reseal("attachments.filename", &row.FilenameCt)
+			if err != nil {
+				return err
+			}
+			if !changed {
+				continue
+			}
+			n, err := q.UpdateAttachmentFilenameCiphertext(ctx, gen.UpdateAttachmentFilenameCiphertextParams{
+				FilenameCt:    *filename,
+				ID:            row.ID,
+				OldFilenameCt: row.FilenameCt,
+			})
+			if err != nil {
+				return err
+			}
+			updated += n
+			skipped += 1 - n
+		}
+		if int32(len(rows)) < batch {
+			break
+		}
+	}
+	logger.Info("attachment filenames done", "updated", updated, "skipped", skipped)
+	return nil
+}
+
+type resealer struct {
+	kr      *fieldcrypt.Keyring
+	decrypt bool
+}
+
+// reseal returns the value to store for field and whether it differs from v.
+// Blind indexes are kept for phone and dob so search keeps working.
+func (r resealer) reseal(field string, v *string) (*string, bool, error) {
+	if v == nil {
+		return nil, false, nil
+	}
+	if r.decrypt {
+		if !fieldcrypt.IsEncrypted(*v) {
+			return v, false, nil
+		}
+		pt, err := r.kr.Decrypt(field, *v)
+		return &pt, true, err
+	}
+	if r.kr.IsCurrent(field, *v) {
+		return v, false, nil
+	}
+	pt, err := r.kr.Decrypt(field, *v)
+	if err != nil {
+		return nil, false, err
+	}
+	ct, err := r.kr.Encrypt(field, pt, dbconn.Searchable(field))
+	return &ct, true, err
+}
@@ -14,9 +14,14 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/joho/godotenv"
 	"github.com/justanamir/medappoint/internal/api"
+	"github.com/justanamir/medappoint/internal/auth"
 	"github.com/justanamir/medappoint/internal/config"
 	dbconn "github.com/justanamir/medappoint/internal/db"
 	"github.com/justanamir/medappoint/internal/db/gen"
+	"github.com/justanamir/medappoint/internal/fieldcrypt"
+	"github.com/justanamir/medappoint/internal/oidc"
+	"github.com/justanamir/medappoint/internal/rbac"
+	"github.com/justanamir/medappoint/internal/storage"
 )
 
 var Version = "0.2.0-day2"
@@ -27,6 +32,21 @@ func main() {
 
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
 
+	if err := cfg.Validate(); err != nil {
+		logger.Error("invalid config", "err", err)
+		os.Exit(1)
+	}
+	keys, err := auth.LoadKeySet(auth.KeySetOptions{
+		Alg:            cfg.JWTAlg,
+		Secret:         cfg.JWTSecret,
+		SigningKeyFile: cfg.JWTSigningKeyFile,
+		VerifyKeyFiles: cfg.JWTVerifyKeyFiles,
+	})
+	if err != nil {
+		logger.Error("jwt keys load fail", "err", err)
+		os.Exit(1)
+	}
+
 	ctx := context.Background()
 	pg, err := dbconn.Connect(ctx, cfg.PGConnString(""))
 	if err != nil {
@@ -35,27 +55,76 @@ func main() {
 	}
 	defer pg.Close()
 
-	queries := gen.New(pg.Pool)
+	kek, err := fieldcrypt.LoadKEK(cfg.FieldKEKFile)
+	if err != nil {
+		logger.Error("field kek load fail", "err", err)
+		os.Exit(1)
+	}
+	keyring, err := dbconn.LoadKeyring(ctx, gen.New(pg.Pool), kek)
+	if err != nil {
+		logger.Error("field keys load fail", "err", err)
+		os.Exit(1)
+	}
+
+	// rows from before 0011 decrypt as-is but phone/dob search can't find them
+	if n, err := gen.New(pg.Pool).CountPlaintextPatients(ctx); err != nil {
+		logger.Warn("plaintext patient check failed", "err", err)
+	} else if n > 0 {
+		logger.Warn("patients with unencrypted phone/dob are missing from phone and dob search; run `go run ./cmd/reencrypt`", "patients", n)
+	}
+
+	queries := gen.New(dbconn.Encrypted(pg.Pool, keyring))
+
+	store, err := newStore(cfg)
+	if err != nil {
+		logger.Error("attachment storage init fail", "err", err)
+		os.Exit(1)
+	}
 	r := api.NewRouter()
 
 	root := chi.NewRouter()
 	root.Use(api.RecoverJSON)
 	root.Mount("/", r)
 
+	jd := api.JWKSDeps{Keys: keys}
+	r.Get("/.well-known/jwks.json", jd.JWKSHandler)
+
 	// v1 routes
 	r.Route("/v1", func(r chi.Router) {
-		ad := api.AuthDeps{Cfg: cfg, Q: queries}
+		ad := api.AuthDeps{Cfg: cfg, Q: queries, Keys: keys}
 		r.Post("/auth/register", ad.RegisterHandler)
 		r.Post("/auth/login", ad.LoginHandler)
 
+		if cfg.OIDCIssuer != "" {
+			od := api.OIDCDeps{
+				Cfg:  cfg,
+				Q:    queries,
+				Keys: keys,
+				OIDC: oidc.New(oidc.Config{
+					Issuer:       cfg.OIDCIssuer,
+					ClientID:     cfg.OIDCClientID,
+					ClientSecret: cfg.OIDCClientSecret,
+					RedirectURL:  cfg.OIDCRedirectURL,
+				}),
+				States: oidc.NewStateStore(10 * time.Minute),
+			}
+			r.Get("/auth/oidc/login", od.LoginHandler)
+			r.Get("/auth/oidc/callback", od.CallbackHandler)
+		}
+
 		cd := api.ClinicDeps{Q: queries}
 		r.Get("/clinics", cd.ListClinicsHandler)
+		r.Get("/clinics/{id}", cd.GetClinicHandler)
 
-		pd := api.ProviderDeps{Q: queries}
+		next := api.NewNextAvailable(cfg.NextAvailableHorizonDays, cfg.NextAvailableTTLSeconds)
+		pd := api.ProviderDeps{Q: queries, Next: next}
 		r.Get("/providers", pd.ListProvidersHandler)
+		r.Get("/providers/{id}", pd.GetProviderHandler)
+		r.Get("/providers/{id}/services", pd.ListServicesHandler)
 
 		sd := api.ServiceDeps{Q: queries}
 		r.Get("/services", sd.ListServicesHandler)
+		r.Get("/services/{id}", sd.GetServiceHandler)
 
 		avd := api.AvailabilityDeps{Q: queries}
 		r.Get("/availabilities", avd.ListByProviderHandler)
@@ -63,24 +132,138 @@ func main() {
 		sh := api.SlotDeps{Q: queries}
 		r.Get("/slots", sh.ListSlotsHandler)
 
-		ah := api.AppointmentDeps{Q: queries}
-		r.Post("/appointments", ah.CreateHandler)
+		ind := api.IntakeDeps{Q: queries}
+		r.Get("/services/{id}/intake-form", ind.GetServiceFormHandler)
+		r.Get("/intake-forms/{id}", ind.GetFormHandler)
+
+		att := api.AttachmentDeps{
+			Q:         queries,
+			Store:     store,
+			MaxBytes:  int64(cfg.AttachmentMaxBytes),
+			URLSecret: []byte(cfg.AttachmentURLSecret),
+			URLTTL:    time.Duration(cfg.AttachmentURLTTLMinutes) * time.Minute,
+		}
+		r.Get("/attachments/{id}/download", att.DownloadHandler)
+
+		// Second factor: these also accept the short-lived tokens issued by login.
+		mfa := api.MFADeps{Cfg: cfg, Q: queries, Keys: keys}
+		r.With(api.WithAuth(keys, auth.PurposeMFA)).Post("/auth/mfa/verify", mfa.VerifyHandler)
+		r.Group(func(er chi.Router) {
+			er.Use(api.WithAuth(keys, auth.PurposeMFAEnroll))
+			er.Post("/me/mfa/enroll", mfa.EnrollHandler)
+			er.Post("/me/mfa/confirm", mfa.ConfirmHandler)
+		})
 
 		// 🔒 Protected (requires Authorization: Bearer <token>)
 		r.Group(func(pr chi.Router) {
-			pr.Use(api.WithAuth(cfg))
+			pr.Use(api.WithAuth(keys), api.WithGrants(queries))
+			pr.Post("/me/mfa/recovery-codes", mfa.RegenerateRecoveryCodesHandler)
+			pr.Delete("/me/mfa", mfa.DisableHandler)
+			pr.Route("/admin/security-policy", func(sr chi.Router) {
+				sr.Use(api.RequirePermission(rbac.SecurityManage))
+				sr.Get("/", mfa.GetPolicyHandler)
+				sr.Put("/", mfa.UpdatePolicyHandler)
+			})
+
 			md := api.MeDeps{Cfg: cfg, Q: queries}
 			pr.Get("/me/appointments", md.ListMyAppointments)
+			pr.Get("/me/profile", md.GetProfile)
+			pr.Patch("/me/profile", md.UpdateProfile)
+			pr.Get("/me/dependents", md.ListDependents)
+			pr.Post("/me/dependents", md.CreateDependent)
+			pr.Delete("/me/dependents/{id}", md.RemoveDependent)
+
+			ah := api.AppointmentDeps{
+				Q:    queries,
+				Pool: pg.Pool,
+				Keys: keyring,
+				Next: next,
+				Limits: api.BookingLimits{
+					MaxUpcoming:         cfg.PatientMaxUpcoming,
+					OnePerServicePerDay: cfg.PatientOnePerServicePerDay,
+				},
+			}
+			idem := api.Idempotent(queries)
+			pr.With(idem).Post("/appointments", ah.CreateHandler)
+			pr.With(idem).Delete("/appointments/{id}", ah.CancelHandler)
+			pr.With(idem).Patch("/appointments/{id}", ah.RescheduleHandler)
+			pr.Put("/appointments/{id}/intake", ind.SubmitHandler)
+			pr.Get("/appointments/{id}/intake", ind.GetHandler)
+			pr.Post("/appointments/{id}/complete", ah.CompleteHandler)
+
+			vn := api.VisitNoteDeps{Q: queries}
+			pr.Get("/appointments/{id}/visit-notes", vn.ListHandler)
+			pr.Post("/appointments/{id}/visit-notes", vn.WriteHandler)
+			pr.Post("/appointments/{id}/visit-notes/sign", vn.SignHandler)
+
+			pr.Get("/appointments/{id}/attachments", att.ListHandler)
+			pr.Post("/appointments/{id}/attachments", att.UploadHandler)
+			pr.Delete("/appointments/{id}/attachments/{attachmentID}", att.DeleteHandler)
+			pr.With(api.RequirePermission(rbac.CatalogWrite)).Put("/admin/services/{id}/intake-form", ind.PutServiceFormHandler)
+
+			rsd := api.ResourceDeps{Q: queries, Next: next}
+			pr.Route("/admin/resources", func(sr chi.Router) {
+				sr.Use(api.RequirePermission(rbac.CatalogWrite))
+				sr.Get("/", rsd.ListResources)
+				sr.Post("/", rsd.CreateResource)
+				sr.Patch("/{id}", rsd.UpdateResource)
+				sr.Get("/{id}/availability", rsd.GetAvailability)
+				sr.Put("/{id}/availability", rsd.PutAvailability)
+			})
+			pr.With(api.RequirePermission(rbac.CatalogWrite)).Put("/admin/services/{id}/resource-kinds", rsd.PutServiceKinds)
+
+			brd := api.BookingRuleDeps{Q: queries, Next: next}
+			pr.With(api.RequirePermission(rbac.CatalogWrite)).Put("/admin/clinics/{id}/booking-rules", brd.PutClinicRules)
+			pr.With(api.RequirePermission(rbac.CatalogWrite)).Put("/admin/services/{id}/booking-rules", brd.PutServiceRules)
+
+			imd := api.ImportDeps{Pool: pg.Pool, Keys: keyring, Next: next}
+			pr.With(api.RequirePermission(rbac.CatalogWrite)).Post("/admin/import", imd.ImportHandler)
 
-			ah := api.AppointmentDeps{Q: queries}
-			pr.Delete("/appointments/{id}", ah.CancelHandler)
+			pr.Route("/admin/providers/{id}/services/{serviceID}", func(sr chi.Router) {
+				sr.Use(api.RequirePermission(rbac.CatalogWrite))
+				sr.Put("/", pd.PutServiceHandler)
+				sr.Delete("/", pd.DeleteServiceHandler)
+			})
 
 			psd := api.ProviderScheduleDeps{Cfg: cfg, Q: queries}
 			pr.Get("/providers/{id}/appointments", psd.ListProviderDayAppointments)
+			pr.Get("/providers/{id}/schedule", psd.ListProviderSchedule)
 
 			ad := api.AdminDeps{Cfg: cfg, Q: queries}
-			pr.Get("/admin/appointments", ad.ListDayAppointments)
+			pr.With(api.RequirePermission(rbac.AppointmentsRead)).Get("/admin/appointments", ad.ListDayAppointments)
 
+			patd := api.PatientDeps{Q: queries}
+			pr.With(api.RequirePermission(rbac.PatientsRead)).Get("/admin/patients", patd.SearchPatientsHandler)
+
+			aud := api.AuditDeps{Q: queries}
+			pr.With(api.RequirePermission(rbac.AuditRead)).Get("/admin/audit", aud.ListAuditHandler)
+
+			repd := api.ReportDeps{Q: queries}
+			pr.Route("/admin/reports", func(sr chi.Router) {
+				sr.Use(api.RequirePermission(rbac.ReportsRead))
+				sr.Get("/bookings", repd.BookingsHandler)
+				sr.Get("/provider-utilization", repd.ProviderUtilizationHandler)
+				sr.Get("/lead-times", repd.LeadTimesHandler)
+				sr.Get("/busiest-hours", repd.BusiestHoursHandler)
+			})
+
+			rd := api.RoleDeps{Q: queries, Pool: pg.Pool, Keys: keyring}
+			pr.Route("/admin/role-assignments", func(sr chi.Router) {
+				sr.Use(api.RequirePermission(rbac.StaffManage))
+				sr.Get("/", rd.ListRoleAssignments)
+				sr.Post("/", rd.CreateRoleAssignment)
+				sr.Delete("/{id}", rd.DeleteRoleAssignment)
+			})
+
+			prv := api.PrivacyDeps{Q: queries, Store: store}
+			pr.Get("/me/export", prv.ExportHandler)
+			pr.Post("/me/erasure-requests", prv.RequestErasureHandler)
+			pr.Route("/admin/erasure-requests", func(sr chi.Router) {
+				sr.Use(api.RequirePermission(rbac.PatientsErase))
+				sr.Get("/", prv.ListErasureRequestsHandler)
+				sr.Post("/{id}/approve", prv.ApproveErasureHandler)
+				sr.Post("/{id}/reject", prv.RejectErasureHandler)
+			})
 		})
 
 	})
@@ -94,6 +277,19 @@ func main() {
 		IdleTimeout:       60 * time.Second,
 	}
 
+	// stored Idempotency-Key responses expire after a day
+	go func() {
+		t := time.NewTicker(time.Hour)
+		defer t.Stop()
+		for range t.C {
+			if n, err := queries.DeleteExpiredIdempotencyKeys(ctx); err != nil {
+				logger.Error("idempotency key purge failed", "err", err)
+			} else if n > 0 {
+				logger.Info("purged idempotency keys", "count", n)
+			}
+		}
+	}()
+
 	errCh := make(chan error, 1)
 	go func() {
 		logger.Info("server starting", "port", cfg.Port, "env", cfg.Env, "version", Version)
@@ -116,3 +312,11 @@ func main() {
 	defer cancel()
 	_ = hs.Shutdown(ctxShutdown)
 }
+
+// newStore picks the attachment backend named by STORAGE_BACKEND.
+func newStore(cfg config.Config) (storage.Store, error) {
+	if cfg.StorageBackend == "s3" {
+		return storage.NewS3(cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket, cfg.S3AccessKey, cfg.S3SecretKey)
+	}
+	return storage.NewLocal(cfg.StorageDir)
+}
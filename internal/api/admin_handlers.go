@@ -1,11 +1,15 @@
 package api
 
 import (
+	"fmt"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/justanamir/medappoint/internal/config"
 	"github.com/justanamir/medappoint/internal/db/gen"
+	"github.com/justanamir/medappoint/internal/rbac"
 )
 
 type AdminDeps struct {
@@ -13,50 +17,166 @@ type AdminDeps struct {
 	Q   *gen.Queries
 }
 
-// GET /v1/admin/appointments?date=YYYY-MM-DD
+// maxDayListDays bounds a JSON day list; downloads are streamed in batches
+// of exportBatch and may cover up to maxExportDays.
+const (
+	maxDayListDays = 31
+	maxExportDays  = 366
+	exportBatch    = 500
+)
+
+// GET /v1/admin/appointments?date=YYYY-MM-DD | ?from=&to= [&clinic_id=][&format=json|csv|xlsx]
+// Requires appointments:read; results are limited to the caller's clinics.
+// to is inclusive. CSV and XLSX are printable day lists with times in each
+// clinic's timezone; notes are left out of them.
 func (d AdminDeps) ListDayAppointments(w http.ResponseWriter, r *http.Request) {
-	_, ok := UserIDFromCtx(r)
+	format, ok := exportFormat(w, r)
 	if !ok {
-		ErrorJSON(w, http.StatusUnauthorized, "unauthorized", nil)
 		return
 	}
-	role, _ := RoleFromCtx(r)
-	if role != "admin" {
-		ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
-		return
+	scope := GrantsFromCtx(r).Scope(rbac.AppointmentsRead)
+	clinicIDs := scope.Filter()
+	if s := r.URL.Query().Get("clinic_id"); s != "" {
+		cid, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || cid <= 0 {
+			ErrorJSON(w, http.StatusBadRequest, "invalid clinic_id", nil)
+			return
+		}
+		if !scope.Allows(cid) {
+			ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
+			return
+		}
+		clinicIDs = []int64{cid}
 	}
 
 	loc, _ := time.LoadLocation("Asia/Kuala_Lumpur")
-	dateStr := r.URL.Query().Get("date")
-
-	var dayStart time.Time
-	var err error
-	if dateStr == "" {
-		now := time.Now().In(loc)
-		dayStart = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
-	} else {
-		dayStart, err = time.ParseInLocation("2006-01-02", dateStr, loc)
-		if err != nil {
-			ErrorJSON(w, http.StatusBadRequest, "date must be YYYY-MM-DD", nil)
-			return
-		}
+	maxDays := maxDayListDays
+	if format != formatJSON {
+		maxDays = maxExportDays
+	}
+	dayStart, dayEnd, ok := parseDayRange(w, r, loc, maxDays)
+	if !ok {
+		return
+	}
+	if format != formatJSON {
+		d.exportDayList(w, r, format, dayStart, dayEnd, clinicIDs)
+		return
 	}
-	dayEnd := dayStart.Add(24 * time.Hour)
 
 	rows, err := d.Q.ListAllAppointmentsOnDate(r.Context(), gen.ListAllAppointmentsOnDateParams{
-		StartTime:   dayStart,
-		StartTime_2: dayEnd,
+		DayStart:  dayStart,
+		DayEnd:    dayEnd,
+		ClinicIds: clinicIDs,
 	})
 	if err != nil {
 		ErrorJSON(w, http.StatusInternalServerError, "failed to load appointments", nil)
 		return
 	}
+	patientIDs := make([]int64, 0, len(rows))
+	for _, a := range rows {
+		patientIDs = append(patientIDs, a.PatientID)
+	}
+	auditPatients(r, d.Q, auditEvent{
+		Action:       AuditAppointmentList,
+		ResourceType: AuditResClinicDay,
+	}, patientIDs)
 
 	JSON(w, http.StatusOK, struct {
 		Date         string      `json:"date"`
+		To           string      `json:"to"`
 		Appointments interface{} `json:"appointments"`
 	}{
 		Date:         dayStart.Format("2006-01-02"),
+		To:           dayEnd.AddDate(0, 0, -1).Format("2006-01-02"),
 		Appointments: rows,
 	})
 }
+
+var dayListHeader = []string{"date", "start", "end", "clinic", "provider", "patient", "service", "status", "appointment_id"}
+
+// exportDayList streams the range page by page. Once the download has
+// started, errors can only be logged; the client is left with a short file.
+func (d AdminDeps) exportDayList(w http.ResponseWriter, r *http.Request, format string, dayStart, dayEnd time.Time, clinicIDs []int64) {
+	name := "appointments_" + dayStart.Format("20060102")
+	if last := dayEnd.AddDate(0, 0, -1); !last.Equal(dayStart) {
+		name += "_" + last.Format("20060102")
+	}
+	tw, err := startExport(w, format, name, dayListHeader)
+	if err != nil {
+		slog.Error("day list export failed", "err", err)
+		return
+	}
+	zones := zoneCache{}
+	arg := gen.ListAppointmentsForExportParams{
+		RangeStart: dayStart,
+		RangeEnd:   dayEnd,
+		ClinicIds:  clinicIDs,
+		RowLimit:   exportBatch,
+	}
+	for {
+		rows, err := d.Q.ListAppointmentsForExport(r.Context(), arg)
+		if err != nil {
+			slog.Error("day list export failed", "err", err)
+			return
+		}
+		patientIDs := make([]int64, 0, len(rows))
+		for _, a := range rows {
+			date, start, end := localTimes(a.StartTime, a.EndTime, zones.get(a.ClinicTimezone))
+			if err := tw.Row(date, start, end, a.ClinicName, a.ProviderName, strOrEmpty(a.PatientName), a.ServiceName, a.Status, strconv.FormatInt(a.ID, 10)); err != nil {
+				slog.Error("day list export failed", "err", err)
+				return
+			}
+			patientIDs = append(patientIDs, a.PatientID)
+		}
+		auditPatients(r, d.Q, auditEvent{
+			Action:       AuditAppointmentExport,
+			ResourceType: AuditResClinicDay,
+		}, patientIDs)
+		if len(rows) < exportBatch {
+			break
+		}
+		last := rows[len(rows)-1]
+		arg.AfterStart, arg.AfterProviderID, arg.AfterID = last.StartTime, last.ProviderID, last.ID
+		if err := tw.Flush(); err != nil {
+			slog.Error("day list export failed", "err", err)
+			return
+		}
+	}
+	if err := tw.Close(); err != nil {
+		slog.Error("day list export failed", "err", err)
+	}
+}
+
+// parseDayRange reads ?date= or ?from=&to= (inclusive, clinic time; default
+// today) and returns the half-open range [start, end).
+func parseDayRange(w http.ResponseWriter, r *http.Request, loc *time.Location, maxDays int) (time.Time, time.Time, bool) {
+	q := r.URL.Query()
+	now := time.Now().In(loc)
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	var err error
+	if s := q.Get("date"); s != "" {
+		if from, err = time.ParseInLocation("2006-01-02", s, loc); err != nil {
+			ErrorJSON(w, http.StatusBadRequest, "date must be YYYY-MM-DD", nil)
+			return from, from, false
+		}
+		return from, from.AddDate(0, 0, 1), true
+	}
+	if s := q.Get("from"); s != "" {
+		if from, err = time.ParseInLocation("2006-01-02", s, loc); err != nil {
+			ErrorJSON(w, http.StatusBadRequest, "from must be YYYY-MM-DD", nil)
+			return from, from, false
+		}
+	}
+	to := from
+	if s := q.Get("to"); s != "" {
+		if to, err = time.ParseInLocation("2006-01-02", s, loc); err != nil {
+			ErrorJSON(w, http.StatusBadRequest, "to must be YYYY-MM-DD", nil)
+			return from, from, false
+		}
+	}
+	if to.Before(from) || to.After(from.AddDate(0, 0, maxDays-1)) {
+		ErrorJSON(w, http.StatusBadRequest, fmt.Sprintf("to must be on or after from and at most %d days later", maxDays), nil)
+		return from, from, false
+	}
+	return from, to.AddDate(0, 0, 1), true
+}
@@ -1,38 +1,75 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	dbconn "github.com/justanamir/medappoint/internal/db"
 	"github.com/justanamir/medappoint/internal/db/gen"
+	"github.com/justanamir/medappoint/internal/fieldcrypt"
+	"github.com/justanamir/medappoint/internal/rbac"
 	"github.com/justanamir/medappoint/internal/slots"
 )
 
 type AppointmentDeps struct {
-	Q *gen.Queries
+	Q      *gen.Queries
+	Pool   *pgxpool.Pool       // bookings run in a transaction holding the patient's lock
+	Keys   *fieldcrypt.Keyring // seals notes written through that transaction
+	Next   *NextAvailable
+	Limits BookingLimits
+}
+
+// BookingLimits caps what one patient may hold at once. Zero / false turns a
+// check off. Overlapping visits for one patient are always refused; the
+// database enforces that with appointments_patient_no_overlap.
+type BookingLimits struct {
+	MaxUpcoming         int  // scheduled visits still ahead
+	OnePerServicePerDay bool // same service twice on one clinic day
 }
 
 type createApptReq struct {
 	ProviderID int64  `json:"provider_id"`
-	PatientID  int64  `json:"patient_id"`
+	PatientID  int64  `json:"patient_id"` // self or a dependent; defaults to self for patients
 	ServiceID  int64  `json:"service_id"`
 	StartTime  string `json:"start_time"` // RFC3339, e.g. "2025-08-25T09:00:00+08:00"
 	Notes      string `json:"notes"`
 }
 
+// CreateHandler: POST /v1/appointments
+// Patients book for themselves or a dependent (patient_id may be omitted for
+// self); staff need appointments:write in the provider's clinic and must pass
+// patient_id.
 func (d AppointmentDeps) CreateHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	uid, ok := UserIDFromCtx(r)
+	if !ok || uid <= 0 {
+		ErrorJSON(w, http.StatusUnauthorized, "unauthorized", nil)
+		return
+	}
+
 	var req createApptReq
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		ErrorJSON(w, http.StatusBadRequest, "invalid json", nil)
 		return
 	}
+	if req.PatientID <= 0 {
+		if p, err := d.Q.GetPatientByUserID(ctx, uid); err == nil {
+			req.PatientID = p.ID
+		}
+	}
 	if req.ProviderID <= 0 || req.PatientID <= 0 || req.ServiceID <= 0 || req.StartTime == "" {
 		ErrorJSON(w, http.StatusBadRequest, "missing required fields", "provider_id, patient_id, service_id, start_time")
 		return
@@ -45,17 +82,10 @@ func (d AppointmentDeps) CreateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Load service to get duration
-	svc, err := d.Q.GetService(ctx, req.ServiceID)
-	if err != nil {
+	if _, err := d.Q.GetService(ctx, req.ServiceID); err != nil {
 		ErrorJSON(w, http.StatusNotFound, "service not found", nil)
 		return
 	}
-	if svc.DurationMin <= 0 {
-		ErrorJSON(w, http.StatusBadRequest, "invalid service duration", nil)
-		return
-	}
-	end := start.Add(time.Duration(svc.DurationMin) * time.Minute)
 
 	// Confirm provider exists (also gives us clinic_id)
 	prov, err := d.Q.GetProvider(ctx, req.ProviderID)
@@ -64,6 +94,23 @@ func (d AppointmentDeps) CreateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The provider must offer the service; their override wins over the
+	// service's default duration
+	durationMin, offered, err := serviceDuration(r, d.Q, req.ProviderID, req.ServiceID)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to load provider services", nil)
+		return
+	}
+	if !offered {
+		ErrorJSON(w, http.StatusUnprocessableEntity, "provider does not offer this service", nil)
+		return
+	}
+	end := start.Add(time.Duration(durationMin) * time.Minute)
+	if !GrantsFromCtx(r).Can(rbac.AppointmentsWrite, prov.ClinicID) && !ownsPatient(ctx, d.Q, uid, req.PatientID) {
+		ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
+		return
+	}
+
 	// Basic “not in the past” check
 	now := time.Now().In(start.Location())
 	if !start.After(now) {
@@ -71,17 +118,281 @@ func (d AppointmentDeps) CreateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Clinic/service lead time, advance window and same-day cutoff, judged
+	// in clinic time like the slot list
+	win, err := bookingWindow(ctx, d.Q, req.ServiceID)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to load booking rules", nil)
+		return
+	}
+	clinicLoc, _ := time.LoadLocation("Asia/Kuala_Lumpur")
+	if err := win.Check(start, now, clinicLoc); err != nil {
+		ErrorJSON(w, http.StatusUnprocessableEntity, err.Error(), nil)
+		return
+	}
+
+	// The patient's limits are counted and the booking inserted under the
+	// patient's advisory lock, so two concurrent bookings can't both pass.
+	tx, err := d.Pool.Begin(ctx)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to start booking", nil)
+		return
+	}
+	defer func() { _ = tx.Rollback(context.WithoutCancel(ctx)) }()
+	txq := gen.New(dbconn.Encrypted(tx, d.Keys))
+	if err := txq.LockPatientBookings(ctx, req.PatientID); err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to start booking", nil)
+		return
+	}
+	if !d.checkPatientLimits(w, r, txq, req.PatientID, req.ServiceID, 0, start, end, clinicLoc) {
+		return
+	}
+
+	if !d.checkProviderSlot(w, r, req.ProviderID, prov.ClinicID, 0, start, end) {
+		return
+	}
+	resourceIDs, ok := allocateResources(w, r, txq, prov.ClinicID, req.ServiceID, start, end)
+	if !ok {
+		return
+	}
+
+	// All good — create appointment
+	var notesPtr *string
+	if req.Notes != "" {
+		notesPtr = &req.Notes
+	}
+	row, err := txq.CreateAppointment(ctx, gen.CreateAppointmentParams{
+		ClinicID:    prov.ClinicID,
+		ProviderID:  req.ProviderID,
+		PatientID:   req.PatientID,
+		ServiceID:   req.ServiceID,
+		StartTime:   start,
+		EndTime:     end,
+		Notes:       notesPtr,
+		ResourceIds: resourceIDs,
+	})
+	if name, ok := exclusionViolation(err); ok {
+		// lost a race for the provider, a resource or the patient's time
+		if name == patientOverlapConstraint {
+			ErrorJSON(w, http.StatusConflict, "patient already has an appointment at that time", nil)
+		} else {
+			ErrorJSON(w, http.StatusConflict, "time overlaps an existing appointment", nil)
+		}
+		return
+	}
+	if err != nil {
+		ErrorJSON(w, http.StatusBadRequest, "failed to create appointment", nil)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to create appointment", nil)
+		return
+	}
+	if len(resourceIDs) > 0 {
+		// the rooms are shared, so other providers' next slot may move too
+		d.Next.InvalidateAll()
+	} else {
+		d.Next.Invalidate(row.ProviderID)
+	}
+	audit(r, d.Q, auditEvent{
+		Action:       AuditAppointmentCreate,
+		ResourceType: AuditResAppointment,
+		ResourceID:   row.ID,
+		PatientID:    row.PatientID,
+		ClinicID:     row.ClinicID,
+	})
+
+	JSON(w, http.StatusCreated, row)
+}
+
+type rescheduleApptReq struct {
+	StartTime string `json:"start_time"` // RFC3339, e.g. "2025-08-25T09:00:00+08:00"
+}
+
+// RescheduleHandler: PATCH /v1/appointments/{id}   body: {"start_time": "..."}
+// Moves a scheduled, future visit to another start with the same provider,
+// service and length. The same checks as a new booking apply, ignoring the
+// visit itself; its rooms and equipment are picked again for the new time.
+// Patient or staff with appointments:write in the clinic, as for cancel.
+func (d AppointmentDeps) RescheduleHandler(w http.ResponseWriter, r *http.Request) {
+	uid, ok := UserIDFromCtx(r)
+	if !ok || uid <= 0 {
+		ErrorJSON(w, http.StatusUnauthorized, "unauthorized", nil)
+		return
+	}
+	ctx := r.Context()
+	appt, ok := loadAppointment(w, r, d.Q)
+	if !ok {
+		return
+	}
+	if !GrantsFromCtx(r).Can(rbac.AppointmentsWrite, appt.ClinicID) && !ownsPatient(ctx, d.Q, uid, appt.PatientID) {
+		ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
+		return
+	}
+
+	var req rescheduleApptReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		ErrorJSON(w, http.StatusBadRequest, "invalid json", nil)
+		return
+	}
+	start, err := time.Parse(time.RFC3339, req.StartTime)
+	if err != nil {
+		ErrorJSON(w, http.StatusBadRequest, "start_time must be RFC3339 (with timezone)", "e.g. 2025-08-25T09:00:00+08:00")
+		return
+	}
+	end := start.Add(appt.EndTime.Sub(appt.StartTime))
+
+	now := time.Now().In(start.Location())
+	if appt.Status != "scheduled" || !appt.StartTime.After(now) {
+		ErrorJSON(w, http.StatusConflict, "only a scheduled, future appointment can be rescheduled", nil)
+		return
+	}
+	if !start.After(now) {
+		ErrorJSON(w, http.StatusBadRequest, "cannot book a past time", nil)
+		return
+	}
+	win, err := bookingWindow(ctx, d.Q, appt.ServiceID)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to load booking rules", nil)
+		return
+	}
+	clinicLoc, _ := time.LoadLocation("Asia/Kuala_Lumpur")
+	if err := win.Check(start, now, clinicLoc); err != nil {
+		ErrorJSON(w, http.StatusUnprocessableEntity, err.Error(), nil)
+		return
+	}
+
+	tx, err := d.Pool.Begin(ctx)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to start booking", nil)
+		return
+	}
+	defer func() { _ = tx.Rollback(context.WithoutCancel(ctx)) }()
+	txq := gen.New(dbconn.Encrypted(tx, d.Keys))
+	if err := txq.LockPatientBookings(ctx, appt.PatientID); err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to start booking", nil)
+		return
+	}
+	if !d.checkPatientLimits(w, r, txq, appt.PatientID, appt.ServiceID, appt.ID, start, end, clinicLoc) {
+		return
+	}
+	if !d.checkProviderSlot(w, r, appt.ProviderID, appt.ClinicID, appt.ID, start, end) {
+		return
+	}
+	// let go of the old holds first so the visit can keep its own room
+	if err := txq.ReleaseAppointmentResources(ctx, appt.ID); err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to release resources", nil)
+		return
+	}
+	resourceIDs, ok := allocateResources(w, r, txq, appt.ClinicID, appt.ServiceID, start, end)
+	if !ok {
+		return
+	}
+
+	row, err := txq.RescheduleAppointment(ctx, gen.RescheduleAppointmentParams{
+		StartTime: start,
+		EndTime:   end,
+		ID:        appt.ID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// cancelled or completed since we loaded it
+		ErrorJSON(w, http.StatusConflict, "only a scheduled, future appointment can be rescheduled", nil)
+		return
+	}
+	held := []int64{}
+	if err == nil && len(resourceIDs) > 0 {
+		held, err = txq.HoldAppointmentResources(ctx, gen.HoldAppointmentResourcesParams{
+			AppointmentID: row.ID,
+			StartTime:     row.StartTime,
+			EndTime:       row.EndTime,
+			ResourceIds:   resourceIDs,
+		})
+	}
+	if name, ok := exclusionViolation(err); ok {
+		if name == patientOverlapConstraint {
+			ErrorJSON(w, http.StatusConflict, "patient already has an appointment at that time", nil)
+		} else {
+			ErrorJSON(w, http.StatusConflict, "time overlaps an existing appointment", nil)
+		}
+		return
+	}
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to reschedule appointment", nil)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to reschedule appointment", nil)
+		return
+	}
+	// the old time and any rooms it held are free again
+	d.Next.InvalidateAll()
+	audit(r, d.Q, auditEvent{
+		Action:       AuditAppointmentReschedule,
+		ResourceType: AuditResAppointment,
+		ResourceID:   row.ID,
+		PatientID:    row.PatientID,
+		ClinicID:     row.ClinicID,
+	})
+
+	JSON(w, http.StatusOK, rescheduledAppt{Appointment: row, ResourceIds: held})
+}
+
+type rescheduledAppt struct {
+	gen.Appointment
+	ResourceIds []int64 `json:"resource_ids"`
+}
+
+// checkPatientLimits enforces d.Limits and the no-overlap rule for a new
+// booking, or for moving excludeID, and writes the error response when one
+// is hit. q must hold the patient's lock (LockPatientBookings) until the
+// booking is written.
+func (d AppointmentDeps) checkPatientLimits(w http.ResponseWriter, r *http.Request, q *gen.Queries, patientID, serviceID, excludeID int64, start, end time.Time, loc *time.Location) bool {
+	l := d.Limits
+	day := start.In(loc)
+	dayStart := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+	c, err := q.CountPatientBookingConflicts(r.Context(), gen.CountPatientBookingConflictsParams{
+		ServiceID: serviceID,
+		DayStart:  dayStart,
+		DayEnd:    dayStart.AddDate(0, 0, 1),
+		EndTime:   end,
+		StartTime: start,
+		PatientID: patientID,
+		ExcludeID: excludeID,
+	})
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to check patient bookings", nil)
+		return false
+	}
+	switch {
+	case c.Overlapping > 0:
+		ErrorJSON(w, http.StatusConflict, "patient already has an appointment at that time", nil)
+	case l.OnePerServicePerDay && c.SameServiceDay > 0:
+		ErrorJSON(w, http.StatusConflict, "patient already has this service booked that day", nil)
+	case l.MaxUpcoming > 0 && c.Upcoming >= int64(l.MaxUpcoming):
+		ErrorJSON(w, http.StatusUnprocessableEntity, "patient has reached the limit of upcoming appointments", map[string]int{"max_upcoming": l.MaxUpcoming})
+	default:
+		return true
+	}
+	return false
+}
+
+// checkProviderSlot checks that [start, end) fits inside one of the
+// provider's availability windows, isn't on a closed day and doesn't
+// overlap their other bookings (excludeID is the one being moved, or 0).
+// It writes the error response when the slot is unusable.
+func (d AppointmentDeps) checkProviderSlot(w http.ResponseWriter, r *http.Request, providerID, clinicID, excludeID int64, start, end time.Time) bool {
+	ctx := r.Context()
 	// Ensure request fits inside an availability window for that weekday
 	// Convert Go weekday (0=Sun..6=Sat) => DB weekday (1=Mon..7=Sun)
 	goWD := int(start.Weekday()) // 0..6
 	dbWD := ((goWD + 6) % 7) + 1 // 1..7
 	avRows, err := d.Q.GetProviderWeekdayAvailability(ctx, gen.GetProviderWeekdayAvailabilityParams{
-		ProviderID: req.ProviderID,
+		ProviderID: providerID,
 		Weekday:    int32(dbWD),
 	})
 	if err != nil {
 		ErrorJSON(w, http.StatusInternalServerError, "failed to load availability", nil)
-		return
+		return false
 	}
 	loc := start.Location()
 	dayStart := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc)
@@ -99,22 +410,33 @@ func (d AppointmentDeps) CreateHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	if !withinAvail {
 		ErrorJSON(w, http.StatusBadRequest, "requested time is outside provider availability", nil)
-		return
+		return false
+	}
+	blocked, err := isBlackout(ctx, d.Q, providerID, clinicID, dayStart)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to load blackouts", nil)
+		return false
+	}
+	if blocked {
+		ErrorJSON(w, http.StatusBadRequest, "provider is not available on that date", nil)
+		return false
 	}
 
 	// Check existing appointments on that date for overlaps
-	dayEnd := dayStart.Add(24 * time.Hour)
 	appts, err := d.Q.ListProviderAppointmentsOnDate(ctx, gen.ListProviderAppointmentsOnDateParams{
-		ProviderID:  req.ProviderID,
+		ProviderID:  providerID,
 		StartTime:   dayStart,
-		StartTime_2: dayEnd,
+		StartTime_2: dayStart.Add(24 * time.Hour),
 	})
 	if err != nil {
 		ErrorJSON(w, http.StatusInternalServerError, "failed to check overlaps", nil)
-		return
+		return false
 	}
 	booked := make([]slots.BookedRange, 0, len(appts))
 	for _, ap := range appts {
+		if ap.ID == excludeID {
+			continue
+		}
 		booked = append(booked, slots.BookedRange{
 			Start: ap.StartTime.In(loc),
 			End:   ap.EndTime.In(loc),
@@ -122,36 +444,44 @@ func (d AppointmentDeps) CreateHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	if overlapsAny(start, end, booked) {
 		ErrorJSON(w, http.StatusConflict, "time overlaps an existing appointment", nil)
-		return
+		return false
 	}
+	return true
+}
 
-	// All good — create appointment
-	var notesPtr *string
-	if req.Notes != "" {
-		notesPtr = &req.Notes
+// allocateResources picks a free room/machine of each kind the service
+// needs for [start, end), writing the error response when it can't.
+func allocateResources(w http.ResponseWriter, r *http.Request, q *gen.Queries, clinicID, serviceID int64, start, end time.Time) ([]int64, bool) {
+	ctx := r.Context()
+	kinds, err := q.ListServiceResourceKinds(ctx, serviceID)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to load service resources", nil)
+		return nil, false
 	}
-	row, err := d.Q.CreateAppointment(ctx, gen.CreateAppointmentParams{
-		ClinicID:   prov.ClinicID,
-		ProviderID: req.ProviderID,
-		PatientID:  req.PatientID,
-		ServiceID:  req.ServiceID,
-		StartTime:  start,
-		EndTime:    end,
-		Notes:      notesPtr,
-	})
+	loc := start.Location()
+	dayStart := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc)
+	dbWD := ((int(start.Weekday()) + 6) % 7) + 1
+	pool, err := loadResourcePool(ctx, q, clinicID, kinds, dbWD, dayStart, dayStart.Add(24*time.Hour))
 	if err != nil {
-		// could add pg error code handling here for uniqueness/exclusion constraint
-		ErrorJSON(w, http.StatusBadRequest, "failed to create appointment", nil)
-		return
+		ErrorJSON(w, http.StatusInternalServerError, "failed to load resources", nil)
+		return nil, false
+	}
+	resourceIDs, ok := slots.Allocate(start, end, loc, kinds, pool)
+	if !ok {
+		ErrorJSON(w, http.StatusConflict, "no required room or equipment is free at that time", kinds)
+		return nil, false
 	}
+	return resourceIDs, true
+}
 
-	JSON(w, http.StatusCreated, row)
+type cancelApptReq struct {
+	Reason string `json:"reason"` // optional, shown in the patient's history
 }
 
-// CancelHandler: DELETE /v1/appointments/{id}
+// CancelHandler: DELETE /v1/appointments/{id}   body (optional): {"reason": "..."}
 // Rules:
 // - Patient can cancel their own appointment
-// - Provider/Admin can cancel any (simple policy for now)
+// - Staff need appointments:write in the appointment's clinic
 // - Only 'scheduled' can be cancelled; returns 409 if already not-cancellable
 func (d AppointmentDeps) CancelHandler(w http.ResponseWriter, r *http.Request) {
 	// must be authenticated
@@ -160,7 +490,6 @@ func (d AppointmentDeps) CancelHandler(w http.ResponseWriter, r *http.Request) {
 		ErrorJSON(w, http.StatusUnauthorized, "unauthorized", nil)
 		return
 	}
-	role, _ := RoleFromCtx(r)
 
 	// parse path param (robust: try chi param, then fallback to last path segment)
 	idStr := chi.URLParam(r, "id")
@@ -185,18 +514,10 @@ func (d AppointmentDeps) CancelHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// if patient, ensure it's theirs
-	if role == "patient" {
-		// map user -> patient_id
-		p, err := d.Q.GetPatientByUserID(ctx, uid)
-		if err != nil {
-			ErrorJSON(w, http.StatusForbidden, "patient profile not found", nil)
-			return
-		}
-		if p.ID != appt.PatientID {
-			ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
-			return
-		}
+	// staff with clinic rights, or the patient themselves
+	if !GrantsFromCtx(r).Can(rbac.AppointmentsWrite, appt.ClinicID) && !ownsPatient(ctx, d.Q, uid, appt.PatientID) {
+		ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
+		return
 	}
 
 	// don't allow cancelling past appointments
@@ -206,17 +527,98 @@ func (d AppointmentDeps) CancelHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// optional body; an empty one is fine
+	var req cancelApptReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		ErrorJSON(w, http.StatusBadRequest, "invalid json", nil)
+		return
+	}
+	var reason *string
+	if s := strings.TrimSpace(req.Reason); s != "" {
+		if len(s) > 500 {
+			ErrorJSON(w, http.StatusUnprocessableEntity, "reason must be at most 500 characters", nil)
+			return
+		}
+		reason = &s
+	}
+
 	// perform cancellation
-	row, err := d.Q.CancelAppointment(ctx, apptID)
+	row, err := d.Q.CancelAppointment(ctx, gen.CancelAppointmentParams{
+		Reason:      reason,
+		CancelledBy: pgtype.Int8{Int64: uid, Valid: true},
+		ID:          apptID,
+	})
 	if err != nil {
 		// If status wasn't 'scheduled', our WHERE matched 0 rows and sqlc will surface an error.
 		ErrorJSON(w, http.StatusConflict, "cannot cancel appointment (maybe already cancelled?)", nil)
 		return
 	}
+	// any rooms it held are free again for everyone
+	d.Next.InvalidateAll()
+	audit(r, d.Q, auditEvent{
+		Action:       AuditAppointmentCancel,
+		ResourceType: AuditResAppointment,
+		ResourceID:   row.ID,
+		PatientID:    row.PatientID,
+		ClinicID:     row.ClinicID,
+	})
 
 	JSON(w, http.StatusOK, row)
 }
 
+// POST /v1/appointments/{id}/complete
+// Marks a visit that has started as completed, which lets the provider
+// sign their visit notes. Clinic staff or the appointment's own provider.
+func (d AppointmentDeps) CompleteHandler(w http.ResponseWriter, r *http.Request) {
+	uid, _ := UserIDFromCtx(r)
+	ctx := r.Context()
+	appt, ok := loadAppointment(w, r, d.Q)
+	if !ok {
+		return
+	}
+	grants := GrantsFromCtx(r)
+	allowed := grants.Can(rbac.AppointmentsWrite, appt.ClinicID)
+	if !allowed && grants.Can(rbac.ScheduleWriteOwn, appt.ClinicID) {
+		prov, err := d.Q.GetProvider(ctx, appt.ProviderID)
+		allowed = err == nil && prov.UserID == uid
+	}
+	if !allowed {
+		ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
+		return
+	}
+
+	row, err := d.Q.CompleteAppointment(ctx, appt.ID)
+	if err != nil {
+		ErrorJSON(w, http.StatusConflict, "only a scheduled appointment that has started can be completed", nil)
+		return
+	}
+	audit(r, d.Q, auditEvent{
+		Action:       AuditAppointmentComplete,
+		ResourceType: AuditResAppointment,
+		ResourceID:   row.ID,
+		PatientID:    row.PatientID,
+		ClinicID:     row.ClinicID,
+	})
+
+	JSON(w, http.StatusOK, row)
+}
+
+// loadAppointment reads the {id} path parameter and fetches the
+// appointment, writing the 400/404 itself when it can't.
+func loadAppointment(w http.ResponseWriter, r *http.Request, q *gen.Queries) (gen.Appointment, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		ErrorJSON(w, http.StatusBadRequest, "invalid appointment id", nil)
+		return gen.Appointment{}, false
+	}
+	appt, err := q.GetAppointment(r.Context(), id)
+	if err != nil {
+		ErrorJSON(w, http.StatusNotFound, "appointment not found", nil)
+		return gen.Appointment{}, false
+	}
+	return appt, true
+}
+
 // ---- local helpers (mirror those in slots but local to this file) ----
 
 func windowTimes(dayStart time.Time, startHHMM, endHHMM string) (time.Time, time.Time, error) {
@@ -256,3 +658,18 @@ func overlapsAny(s, e time.Time, booked []slots.BookedRange) bool {
 	}
 	return false
 }
+
+// patientOverlapConstraint keeps one patient's scheduled visits apart
+// (migration 0021).
+const patientOverlapConstraint = "appointments_patient_no_overlap"
+
+// exclusionViolation reports whether err is a Postgres exclusion_violation,
+// i.e. an overlapping booking slipped in between our check and the insert,
+// and which constraint caught it.
+func exclusionViolation(err error) (constraint string, ok bool) {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23P01" {
+		return pgErr.ConstraintName, true
+	}
+	return "", false
+}
@@ -0,0 +1,348 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/justanamir/medappoint/internal/db/gen"
+	"github.com/justanamir/medappoint/internal/rbac"
+	"github.com/justanamir/medappoint/internal/storage"
+)
+
+// AttachmentDeps serves files uploaded against an appointment. Access
+// follows CancelHandler: the patient (or a guardian) and clinic staff.
+// Downloads go through short-lived signed links so they work from a plain
+// <a href> without the bearer token.
+type AttachmentDeps struct {
+	Q         *gen.Queries
+	Store     storage.Store
+	MaxBytes  int64
+	URLSecret []byte
+	URLTTL    time.Duration
+}
+
+// Sniffed content types accepted for upload; anything else is 415.
+var attachmentTypes = map[string]bool{
+	"application/pdf": true,
+	"image/jpeg":      true,
+	"image/png":       true,
+}
+
+const maxAttachmentsPerAppointment = 20
+
+type attachmentView struct {
+	ID            int64     `json:"id"`
+	AppointmentID int64     `json:"appointment_id"`
+	Filename      string    `json:"filename"`
+	ContentType   string    `json:"content_type"`
+	SizeBytes     int64     `json:"size_bytes"`
+	Sha256        string    `json:"sha256"`
+	CreatedAt     time.Time `json:"created_at"`
+	DownloadURL   string    `json:"download_url"`
+	URLExpiresAt  time.Time `json:"url_expires_at"`
+}
+
+func (d AttachmentDeps) view(a gen.Attachment) attachmentView {
+	exp := time.Now().Add(d.URLTTL).Truncate(time.Second)
+	return attachmentView{
+		ID:            a.ID,
+		AppointmentID: a.AppointmentID,
+		Filename:      a.Filename,
+		ContentType:   a.ContentType,
+		SizeBytes:     a.SizeBytes,
+		Sha256:        a.Sha256,
+		CreatedAt:     a.CreatedAt,
+		DownloadURL: fmt.Sprintf("/v1/attachments/%d/download?expires=%d&sig=%s",
+			a.ID, exp.Unix(), d.sign(a.ID, exp.Unix())),
+		URLExpiresAt: exp,
+	}
+}
+
+// sign is the HMAC that makes a download link valid until expires.
+func (d AttachmentDeps) sign(id, expires int64) string {
+	mac := hmac.New(sha256.New, d.URLSecret)
+	fmt.Fprintf(mac, "attachment:%d:%d", id, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// canAccess mirrors CancelHandler's ownership rule; perm is
+// appointments:write for changes and appointments:read for viewing.
+func (d AttachmentDeps) canAccess(r *http.Request, uid int64, appt gen.Appointment, perm rbac.Permission) bool {
+	return GrantsFromCtx(r).Can(perm, appt.ClinicID) || ownsPatient(r.Context(), d.Q, uid, appt.PatientID)
+}
+
+// POST /v1/appointments/{id}/attachments   multipart/form-data, field "file"
+func (d AttachmentDeps) UploadHandler(w http.ResponseWriter, r *http.Request) {
+	uid, _ := UserIDFromCtx(r)
+	ctx := r.Context()
+	appt, ok := loadAppointment(w, r, d.Q)
+	if !ok {
+		return
+	}
+	if !d.canAccess(r, uid, appt, rbac.AppointmentsWrite) {
+		ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
+		return
+	}
+	if appt.Status == "cancelled" {
+		ErrorJSON(w, http.StatusConflict, "appointment is cancelled", nil)
+		return
+	}
+	n, err := d.Q.CountAttachmentsByAppointment(ctx, appt.ID)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to count attachments", nil)
+		return
+	}
+	if n >= maxAttachmentsPerAppointment {
+		ErrorJSON(w, http.StatusConflict, fmt.Sprintf("at most %d attachments per appointment", maxAttachmentsPerAppointment), nil)
+		return
+	}
+
+	// headroom for the multipart envelope; the file itself is checked below
+	r.Body = http.MaxBytesReader(w, r.Body, d.MaxBytes+64<<10)
+	mr, err := r.MultipartReader()
+	if err != nil {
+		ErrorJSON(w, http.StatusBadRequest, "expected multipart/form-data", nil)
+		return
+	}
+	var (
+		data     []byte
+		filename string
+	)
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			ErrorJSON(w, http.StatusBadRequest, "invalid multipart body", nil)
+			return
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+		filename = cleanFilename(part.FileName())
+		data, err = io.ReadAll(io.LimitReader(part, d.MaxBytes+1))
+		if err != nil {
+			ErrorJSON(w, http.StatusRequestEntityTooLarge, "file too large", nil)
+			return
+		}
+		break
+	}
+	if filename == "" || len(data) == 0 {
+		ErrorJSON(w, http.StatusUnprocessableEntity, "file is required", nil)
+		return
+	}
+	if int64(len(data)) > d.MaxBytes {
+		ErrorJSON(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("file exceeds %d bytes", d.MaxBytes), nil)
+		return
+	}
+	// trust the bytes, not the client's Content-Type
+	ctype, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	if !attachmentTypes[ctype] {
+		ErrorJSON(w, http.StatusUnsupportedMediaType, "only PDF, JPEG and PNG files are accepted", ctype)
+		return
+	}
+
+	var rnd [16]byte
+	if _, err := rand.Read(rnd[:]); err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to store file", nil)
+		return
+	}
+	key := fmt.Sprintf("appointments/%d/%s", appt.ID, hex.EncodeToString(rnd[:]))
+	sum := sha256.Sum256(data)
+	if err := d.Store.Put(ctx, key, bytes.NewReader(data), int64(len(data)), ctype); err != nil {
+		slog.Error("attachment put failed", "key", key, "err", err)
+		ErrorJSON(w, http.StatusBadGateway, "failed to store file", nil)
+		return
+	}
+
+	att, err := d.Q.CreateAttachment(ctx, gen.CreateAttachmentParams{
+		AppointmentID: appt.ID,
+		StorageKey:    key,
+		Filename:      filename,
+		ContentType:   ctype,
+		SizeBytes:     int64(len(data)),
+		Sha256:        hex.EncodeToString(sum[:]),
+		UploadedBy:    pgtype.Int8{Int64: uid, Valid: true},
+	})
+	if err != nil {
+		_ = d.Store.Delete(ctx, key)
+		ErrorJSON(w, http.StatusInternalServerError, "failed to save attachment", nil)
+		return
+	}
+	audit(r, d.Q, auditEvent{
+		Action:       AuditAttachmentUpload,
+		ResourceType: AuditResAttachment,
+		ResourceID:   att.ID,
+		PatientID:    appt.PatientID,
+		ClinicID:     appt.ClinicID,
+	})
+
+	JSON(w, http.StatusCreated, d.view(att))
+}
+
+// GET /v1/appointments/{id}/attachments
+// Each item carries a fresh signed download_url.
+func (d AttachmentDeps) ListHandler(w http.ResponseWriter, r *http.Request) {
+	uid, _ := UserIDFromCtx(r)
+	appt, ok := loadAppointment(w, r, d.Q)
+	if !ok {
+		return
+	}
+	if !d.canAccess(r, uid, appt, rbac.AppointmentsRead) {
+		ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
+		return
+	}
+	rows, err := d.Q.ListAttachmentsByAppointment(r.Context(), appt.ID)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to load attachments", nil)
+		return
+	}
+	out := make([]attachmentView, 0, len(rows))
+	for _, a := range rows {
+		out = append(out, d.view(a))
+	}
+	audit(r, d.Q, auditEvent{
+		Action:       AuditAttachmentList,
+		ResourceType: AuditResAppointment,
+		ResourceID:   appt.ID,
+		PatientID:    appt.PatientID,
+		ClinicID:     appt.ClinicID,
+	})
+
+	JSON(w, http.StatusOK, out)
+}
+
+// DELETE /v1/appointments/{id}/attachments/{attachmentID}
+func (d AttachmentDeps) DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	uid, _ := UserIDFromCtx(r)
+	ctx := r.Context()
+	appt, ok := loadAppointment(w, r, d.Q)
+	if !ok {
+		return
+	}
+	if !d.canAccess(r, uid, appt, rbac.AppointmentsWrite) {
+		ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
+		return
+	}
+	attID, err := strconv.ParseInt(chi.URLParam(r, "attachmentID"), 10, 64)
+	if err != nil || attID <= 0 {
+		ErrorJSON(w, http.StatusBadRequest, "invalid attachment id", nil)
+		return
+	}
+	key, err := d.Q.DeleteAttachment(ctx, gen.DeleteAttachmentParams{ID: attID, AppointmentID: appt.ID})
+	if errors.Is(err, pgx.ErrNoRows) {
+		ErrorJSON(w, http.StatusNotFound, "attachment not found", nil)
+		return
+	}
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to delete attachment", nil)
+		return
+	}
+	// the row is gone; a failed object delete only leaves an orphan blob
+	if err := d.Store.Delete(ctx, key); err != nil {
+		slog.Error("attachment delete failed", "key", key, "err", err)
+	}
+	audit(r, d.Q, auditEvent{
+		Action:       AuditAttachmentDelete,
+		ResourceType: AuditResAttachment,
+		ResourceID:   attID,
+		PatientID:    appt.PatientID,
+		ClinicID:     appt.ClinicID,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /v1/attachments/{id}/download?expires=<unix>&sig=<hex>
+// Public route: the signature is the authorization.
+func (d AttachmentDeps) DownloadHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		ErrorJSON(w, http.StatusBadRequest, "invalid attachment id", nil)
+		return
+	}
+	expires, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+	if err != nil {
+		ErrorJSON(w, http.StatusForbidden, "invalid or expired link", nil)
+		return
+	}
+	sig, err := hex.DecodeString(r.URL.Query().Get("sig"))
+	want, _ := hex.DecodeString(d.sign(id, expires))
+	if err != nil || !hmac.Equal(sig, want) || time.Now().Unix() > expires {
+		ErrorJSON(w, http.StatusForbidden, "invalid or expired link", nil)
+		return
+	}
+
+	ctx := r.Context()
+	att, err := d.Q.GetAttachment(ctx, id)
+	if err != nil {
+		ErrorJSON(w, http.StatusNotFound, "attachment not found", nil)
+		return
+	}
+	body, err := d.Store.Get(ctx, att.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		ErrorJSON(w, http.StatusNotFound, "attachment not found", nil)
+		return
+	}
+	if err != nil {
+		slog.Error("attachment get failed", "key", att.StorageKey, "err", err)
+		ErrorJSON(w, http.StatusBadGateway, "failed to read file", nil)
+		return
+	}
+	defer body.Close()
+
+	if appt, err := d.Q.GetAppointment(ctx, att.AppointmentID); err == nil {
+		audit(r, d.Q, auditEvent{
+			Action:       AuditAttachmentDownload,
+			ResourceType: AuditResAttachment,
+			ResourceID:   att.ID,
+			PatientID:    appt.PatientID,
+			ClinicID:     appt.ClinicID,
+		})
+	}
+
+	w.Header().Set("Content-Type", att.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(att.SizeBytes, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": att.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, no-store")
+	if _, err := io.Copy(w, body); err != nil {
+		slog.Error("attachment download interrupted", "id", att.ID, "err", err)
+	}
+}
+
+// cleanFilename keeps the base name only, without control characters.
+func cleanFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "." || name == "/" {
+		return ""
+	}
+	if len(name) > 255 {
+		name = strings.ToValidUTF8(name[:255], "")
+	}
+	return name
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/justanamir/medappoint/internal/db/gen"
+)
+
+// Audit actions and resource types recorded in audit_log.
+const (
+	AuditAppointmentCreate     = "appointment.create"
+	AuditAppointmentCancel     = "appointment.cancel"
+	AuditAppointmentReschedule = "appointment.reschedule"
+	AuditAppointmentList       = "appointment.list"
+	AuditAppointmentComplete   = "appointment.complete"
+	AuditAppointmentExport     = "appointment.export"
+	AuditPatientRead           = "patient.read"
+	AuditPatientUpdate         = "patient.update"
+	AuditPatientSearch         = "patient.search"
+	AuditPatientCreate         = "patient.create"
+	AuditPatientUnlink         = "patient.unlink"
+	AuditPatientExport         = "patient.export"
+	AuditErasureRequest        = "patient.erasure_request"
+	AuditErasureReject         = "patient.erasure_reject"
+	AuditPatientErase          = "patient.erase"
+	AuditIntakeSubmit          = "intake.submit"
+	AuditIntakeRead            = "intake.read"
+	AuditVisitNoteWrite        = "visit_note.write"
+	AuditVisitNoteSign         = "visit_note.sign"
+	AuditVisitNoteRead         = "visit_note.read"
+	AuditAttachmentUpload      = "attachment.upload"
+	AuditAttachmentList        = "attachment.list"
+	AuditAttachmentDownload    = "attachment.download"
+	AuditAttachmentDelete      = "attachment.delete"
+
+	AuditResAppointment      = "appointment"
+	AuditResProviderSchedule = "provider_schedule"
+	AuditResClinicDay        = "clinic_day"
+	AuditResPatient          = "patient"
+	AuditResErasureRequest   = "erasure_request"
+	AuditResIntake           = "intake_response"
+	AuditResVisitNote        = "visit_note"
+	AuditResAttachment       = "attachment"
+)
+
+// auditEvent describes one access to patient data; zero IDs are stored as NULL.
+type auditEvent struct {
+	Action       string
+	ResourceType string
+	ResourceID   int64
+	PatientID    int64
+	ClinicID     int64
+}
+
+// audit records ev for the current caller. It is best-effort: a failed insert
+// is logged but never fails the request that already happened.
+func audit(r *http.Request, q *gen.Queries, ev auditEvent) {
+	uid, role, reqID, ip := auditActor(r)
+	err := q.CreateAuditEvent(context.WithoutCancel(r.Context()), gen.CreateAuditEventParams{
+		ActorUserID:  uid,
+		ActorRole:    role,
+		Action:       ev.Action,
+		ResourceType: ev.ResourceType,
+		ResourceID:   optInt8(ev.ResourceID),
+		PatientID:    optInt8(ev.PatientID),
+		ClinicID:     optInt8(ev.ClinicID),
+		RequestID:    reqID,
+		Ip:           ip,
+	})
+	if err != nil {
+		slog.Error("audit write failed", "action", ev.Action, "request_id", reqID, "err", err)
+	}
+}
+
+// auditPatients records a list read as one row per distinct patient returned.
+func auditPatients(r *http.Request, q *gen.Queries, ev auditEvent, patientIDs []int64) {
+	ids := uniqIDs(patientIDs)
+	if len(ids) == 0 {
+		return
+	}
+	uid, role, reqID, ip := auditActor(r)
+	err := q.CreateAuditEventsForPatients(context.WithoutCancel(r.Context()), gen.CreateAuditEventsForPatientsParams{
+		ActorUserID:  uid,
+		ActorRole:    role,
+		Action:       ev.Action,
+		ResourceType: ev.ResourceType,
+		ResourceID:   optInt8(ev.ResourceID),
+		PatientIds:   ids,
+		ClinicID:     optInt8(ev.ClinicID),
+		RequestID:    reqID,
+		Ip:           ip,
+	})
+	if err != nil {
+		slog.Error("audit write failed", "action", ev.Action, "request_id", reqID, "err", err)
+	}
+}
+
+func auditActor(r *http.Request) (pgtype.Int8, string, string, string) {
+	uid, _ := UserIDFromCtx(r)
+	role, _ := RoleFromCtx(r)
+	// middleware.RealIP has already swapped in the client IP when proxied.
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	return optInt8(uid), role, middleware.GetReqID(r.Context()), ip
+}
+
+func optInt8(v int64) pgtype.Int8 {
+	return pgtype.Int8{Int64: v, Valid: v > 0}
+}
+
+func uniqIDs(in []int64) []int64 {
+	seen := make(map[int64]bool, len(in))
+	out := make([]int64, 0, len(in))
+	for _, id := range in {
+		if id > 0 && !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
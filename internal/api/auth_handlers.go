@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/justanamir/medappoint/internal/auth"
 	"github.com/justanamir/medappoint/internal/config"
@@ -11,8 +12,9 @@ import (
 )
 
 type AuthDeps struct {
-	Cfg config.Config
-	Q   *gen.Queries
+	Cfg  config.Config
+	Q    *gen.Queries
+	Keys *auth.KeySet
 }
 
 type registerRequest struct {
@@ -60,13 +62,7 @@ func (d AuthDeps) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tok, err := auth.SignJWT(d.Cfg.JWTSecret, d.Cfg.JWTIssuer, d.Cfg.JWTTTLMinutes, u.ID, u.Role)
-	if err != nil {
-		ErrorJSON(w, http.StatusInternalServerError, "token error", nil)
-		return
-	}
-
-	JSON(w, http.StatusCreated, tokenResponse{Token: tok})
+	d.startSession(w, r, u, http.StatusCreated)
 }
 
 type loginRequest struct {
@@ -98,11 +94,65 @@ func (d AuthDeps) LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tok, err := auth.SignJWT(d.Cfg.JWTSecret, d.Cfg.JWTIssuer, d.Cfg.JWTTTLMinutes, u.ID, u.Role)
+	d.startSession(w, r, u, http.StatusOK)
+}
+
+type mfaChallengeResponse struct {
+	MFARequired           bool   `json:"mfa_required,omitempty"`
+	MFAEnrollmentRequired bool   `json:"mfa_enrollment_required,omitempty"`
+	MFAToken              string `json:"mfa_token"`
+}
+
+// mfaTokenTTL bounds the gap between password and second factor.
+const mfaTokenTTL = 5 * time.Minute
+
+// startSession runs after the password check; see issueSession.
+func (d AuthDeps) startSession(w http.ResponseWriter, r *http.Request, u gen.User, status int) {
+	issueSession(w, r, d.Q, d.Keys, d.Cfg, u, status)
+}
+
+// issueSession runs once a user has proven who they are, by password or
+// SSO: users with TOTP enabled get an MFA challenge token, staff (anyone
+// holding a clinic role) without it get an enrollment token when the
+// security policy demands MFA, everyone else gets an access token.
+func issueSession(w http.ResponseWriter, r *http.Request, q *gen.Queries, keys *auth.KeySet, cfg config.Config, u gen.User, status int) {
+	ctx := r.Context()
+
+	purpose := ""
+	if m, err := q.GetUserMFA(ctx, u.ID); err == nil && m.EnabledAt.Valid {
+		purpose = auth.PurposeMFA
+	} else if staff, err := isStaff(ctx, q, u.ID); err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to load permissions", nil)
+		return
+	} else if staff {
+		pol, err := q.GetSecurityPolicy(ctx)
+		if err != nil {
+			ErrorJSON(w, http.StatusInternalServerError, "failed to load security policy", nil)
+			return
+		}
+		if pol.RequireStaffMfa {
+			purpose = auth.PurposeMFAEnroll
+		}
+	}
+
+	if purpose == "" {
+		tok, err := keys.SignJWT(cfg.JWTIssuer, cfg.JWTTTLMinutes, u.ID, u.Role)
+		if err != nil {
+			ErrorJSON(w, http.StatusInternalServerError, "token error", nil)
+			return
+		}
+		JSON(w, status, tokenResponse{Token: tok})
+		return
+	}
+
+	tok, err := keys.SignPurposeJWT(cfg.JWTIssuer, mfaTokenTTL, u.ID, u.Role, purpose)
 	if err != nil {
 		ErrorJSON(w, http.StatusInternalServerError, "token error", nil)
 		return
 	}
-
-	JSON(w, http.StatusOK, tokenResponse{Token: tok})
+	JSON(w, status, mfaChallengeResponse{
+		MFARequired:           purpose == auth.PurposeMFA,
+		MFAEnrollmentRequired: purpose == auth.PurposeMFAEnroll,
+		MFAToken:              tok,
+	})
 }
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/justanamir/medappoint/internal/auth"
+	"github.com/justanamir/medappoint/internal/config"
+	"github.com/justanamir/medappoint/internal/db/gen"
+)
+
+// fakeDB answers queries by sqlc name with canned rows; each row lists the
+// values in scan order. Queries without an entry return no rows.
+type fakeDB map[string][][]interface{}
+
+func queryName(sql string) string {
+	name, _, _ := strings.Cut(strings.TrimPrefix(sql, "-- name: "), " ")
+	return name
+}
+
+func (f fakeDB) Exec(context.Context, string, ...interface{}) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, nil
+}
+
+func (f fakeDB) Query(_ context.Context, sql string, _ ...interface{}) (pgx.Rows, error) {
+	return &fakeRows{rows: f[queryName(sql)], i: -1}, nil
+}
+
+func (f fakeDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	rows, _ := f.Query(ctx, sql, args...)
+	return fakeRow{rows.(*fakeRows)}
+}
+
+type fakeRows struct {
+	rows [][]interface{}
+	i    int
+}
+
+func (r *fakeRows) Close()                                       {}
+func (r *fakeRows) Err() error                                   { return nil }
+func (r *fakeRows) CommandTag() pgconn.CommandTag                { return pgconn.CommandTag{} }
+func (r *fakeRows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+func (r *fakeRows) Values() ([]interface{}, error)               { return r.rows[r.i], nil }
+func (r *fakeRows) RawValues() [][]byte                          { return nil }
+func (r *fakeRows) Conn() *pgx.Conn                              { return nil }
+
+func (r *fakeRows) Next() bool {
+	r.i++
+	return r.i < len(r.rows)
+}
+
+func (r *fakeRows) Scan(dest ...interface{}) error {
+	for n, v := range r.rows[r.i] {
+		if n < len(dest) && v != nil {
+			reflect.ValueOf(dest[n]).Elem().Set(reflect.ValueOf(v))
+		}
+	}
+	return nil
+}
+
+type fakeRow struct{ rows *fakeRows }
+
+func (r fakeRow) Scan(dest ...interface{}) error {
+	if !r.rows.Next() {
+		return pgx.ErrNoRows
+	}
+	return r.rows.Scan(dest...)
+}
+
+// clinicGrant is a receptionist assignment for clinic 7.
+var clinicGrant = []interface{}{int64(1), int64(42), pgtype.Int8{Int64: 7, Valid: true}, "receptionist"}
+
+func TestIssueSessionStaffByAssignment(t *testing.T) {
+	keys := auth.NewHMACKeySet("test-secret")
+	u := gen.User{ID: 42, Email: "reception@example.com", Role: "patient"}
+	policy := [][]interface{}{{true, true}}
+
+	tests := []struct {
+		name       string
+		db         fakeDB
+		wantEnroll bool
+	}{
+		{"patient account with clinic grant", fakeDB{"ListRoleAssignmentsByUser": {clinicGrant}, "GetSecurityPolicy": policy}, true},
+		{"patient account without grants", fakeDB{"GetSecurityPolicy": policy}, false},
+		{"grant but policy off", fakeDB{"ListRoleAssignmentsByUser": {clinicGrant}, "GetSecurityPolicy": {{true, false}}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/v1/auth/login", nil)
+			issueSession(w, r, gen.New(tt.db), keys, config.Config{JWTIssuer: "test", JWTTTLMinutes: 5}, u, http.StatusOK)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body)
+			}
+			var body struct {
+				Token                 string `json:"token"`
+				MFAEnrollmentRequired bool   `json:"mfa_enrollment_required"`
+				MFAToken              string `json:"mfa_token"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.MFAEnrollmentRequired != tt.wantEnroll {
+				t.Fatalf("mfa_enrollment_required = %v, want %v", body.MFAEnrollmentRequired, tt.wantEnroll)
+			}
+			if tt.wantEnroll && (body.MFAToken == "" || body.Token != "") {
+				t.Fatalf("want only an mfa token, got %s", w.Body)
+			}
+			if !tt.wantEnroll && body.Token == "" {
+				t.Fatalf("want an access token, got %s", w.Body)
+			}
+		})
+	}
+}
+
+func TestDisableMFAStaffByAssignment(t *testing.T) {
+	q := gen.New(fakeDB{
+		"ListRoleAssignmentsByUser": {clinicGrant},
+		"GetSecurityPolicy":         {{true, true}},
+	})
+	d := MFADeps{Q: q, Keys: auth.NewHMACKeySet("test-secret")}
+	h := WithGrants(q)(http.HandlerFunc(d.DisableHandler))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodDelete, "/v1/me/mfa", strings.NewReader(`{"code":"000000"}`))
+	ctx := context.WithValue(r.Context(), ctxUserID, int64(42))
+	ctx = context.WithValue(ctx, ctxRole, "patient")
+	h.ServeHTTP(w, r.WithContext(ctx))
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want 403: %s", w.Code, w.Body)
+	}
+}
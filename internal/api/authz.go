@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/justanamir/medappoint/internal/db/gen"
+	"github.com/justanamir/medappoint/internal/rbac"
+)
+
+const ctxGrants ctxKey = "grants"
+
+// WithGrants loads the caller's clinic role assignments once per request.
+// Must run after WithAuth.
+func WithGrants(q *gen.Queries) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			uid, ok := UserIDFromCtx(r)
+			if !ok || uid <= 0 {
+				ErrorJSON(w, http.StatusUnauthorized, "unauthorized", nil)
+				return
+			}
+			rows, err := q.ListRoleAssignmentsByUser(r.Context(), uid)
+			if err != nil {
+				ErrorJSON(w, http.StatusInternalServerError, "failed to load permissions", nil)
+				return
+			}
+			grants := make(rbac.Grants, 0, len(rows))
+			for _, ra := range rows {
+				grants = append(grants, rbac.Grant{
+					Role:       ra.Role,
+					ClinicID:   ra.ClinicID.Int64,
+					AllClinics: !ra.ClinicID.Valid,
+				})
+			}
+			ctx := context.WithValue(r.Context(), ctxGrants, grants)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequirePermission rejects callers that hold perm in no clinic at all.
+// Handlers still check the specific clinic via GrantsFromCtx.
+func RequirePermission(perm rbac.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !GrantsFromCtx(r).Any(perm) {
+				ErrorJSON(w, http.StatusForbidden, "forbidden", string(perm))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func GrantsFromCtx(r *http.Request) rbac.Grants {
+	g, _ := r.Context().Value(ctxGrants).(rbac.Grants)
+	return g
+}
+
+// isStaff reports whether the user holds any clinic role. Staff rules (the
+// MFA policy, SSO) go by role assignments rather than users.role, which
+// stays "patient" for someone who registered and was granted a role later.
+func isStaff(ctx context.Context, q *gen.Queries, uid int64) (bool, error) {
+	rows, err := q.ListRoleAssignmentsByUser(ctx, uid)
+	return len(rows) > 0, err
+}
+
+// ownsPatient reports whether user uid is the patient patientID or one of
+// their guardians.
+func ownsPatient(ctx context.Context, q *gen.Queries, uid, patientID int64) bool {
+	ok, err := q.CanActForPatient(ctx, gen.CanActForPatientParams{PatientID: patientID, UserID: uid})
+	return err == nil && ok
+}
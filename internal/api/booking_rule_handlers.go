@@ -0,0 +1,153 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/justanamir/medappoint/internal/db/gen"
+	"github.com/justanamir/medappoint/internal/rbac"
+	"github.com/justanamir/medappoint/internal/slots"
+)
+
+type BookingRuleDeps struct {
+	Q    *gen.Queries
+	Next *NextAvailable
+}
+
+// null leaves the limit off (clinic) or inherits the clinic's (service)
+type bookingRulesReq struct {
+	MinLeadMinutes *int32  `json:"min_lead_minutes"`
+	MaxAdvanceDays *int32  `json:"max_advance_days"`
+	SameDayCutoff  *string `json:"same_day_cutoff"` // "HH:MM" clinic time
+}
+
+type bookingRuleValues struct {
+	MinLead    pgtype.Int4
+	MaxAdvance pgtype.Int4
+	Cutoff     *string
+}
+
+func (req bookingRulesReq) validate() (bookingRuleValues, string) {
+	var v bookingRuleValues
+	if req.MinLeadMinutes != nil {
+		if *req.MinLeadMinutes < 0 || *req.MinLeadMinutes > 30*24*60 {
+			return v, "min_lead_minutes must be between 0 and 43200"
+		}
+		v.MinLead = pgtype.Int4{Int32: *req.MinLeadMinutes, Valid: true}
+	}
+	if req.MaxAdvanceDays != nil {
+		if *req.MaxAdvanceDays < 1 || *req.MaxAdvanceDays > 730 {
+			return v, "max_advance_days must be between 1 and 730"
+		}
+		v.MaxAdvance = pgtype.Int4{Int32: *req.MaxAdvanceDays, Valid: true}
+	}
+	if req.SameDayCutoff != nil {
+		if _, err := parseHHMM(*req.SameDayCutoff); err != nil {
+			return v, "same_day_cutoff must be HH:MM"
+		}
+		v.Cutoff = req.SameDayCutoff
+	}
+	return v, ""
+}
+
+// PUT /v1/admin/clinics/{id}/booking-rules
+// body: {"min_lead_minutes": 120, "max_advance_days": 90, "same_day_cutoff": "12:00"}
+func (d BookingRuleDeps) PutClinicRules(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		ErrorJSON(w, http.StatusBadRequest, "invalid clinic id", nil)
+		return
+	}
+	if !GrantsFromCtx(r).Can(rbac.CatalogWrite, id) {
+		ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
+		return
+	}
+	if _, err := d.Q.GetClinic(r.Context(), id); err != nil {
+		ErrorJSON(w, http.StatusNotFound, "clinic not found", nil)
+		return
+	}
+	var req bookingRulesReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		ErrorJSON(w, http.StatusBadRequest, "invalid json", nil)
+		return
+	}
+	v, msg := req.validate()
+	if msg != "" {
+		ErrorJSON(w, http.StatusUnprocessableEntity, msg, nil)
+		return
+	}
+	row, err := d.Q.UpsertClinicBookingRules(r.Context(), gen.UpsertClinicBookingRulesParams{
+		ClinicID:       id,
+		MinLeadMinutes: v.MinLead,
+		MaxAdvanceDays: v.MaxAdvance,
+		SameDayCutoff:  v.Cutoff,
+	})
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to save booking rules", nil)
+		return
+	}
+	d.Next.InvalidateAll()
+	JSON(w, http.StatusOK, row)
+}
+
+// PUT /v1/admin/services/{id}/booking-rules   body as for clinics
+// Set fields override the clinic's rules for this service.
+func (d BookingRuleDeps) PutServiceRules(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		ErrorJSON(w, http.StatusBadRequest, "invalid service id", nil)
+		return
+	}
+	svc, err := d.Q.GetService(r.Context(), id)
+	if err != nil {
+		ErrorJSON(w, http.StatusNotFound, "service not found", nil)
+		return
+	}
+	if !GrantsFromCtx(r).Can(rbac.CatalogWrite, svc.ClinicID) {
+		ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
+		return
+	}
+	var req bookingRulesReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		ErrorJSON(w, http.StatusBadRequest, "invalid json", nil)
+		return
+	}
+	v, msg := req.validate()
+	if msg != "" {
+		ErrorJSON(w, http.StatusUnprocessableEntity, msg, nil)
+		return
+	}
+	row, err := d.Q.UpsertServiceBookingRules(r.Context(), gen.UpsertServiceBookingRulesParams{
+		ServiceID:      svc.ID,
+		MinLeadMinutes: v.MinLead,
+		MaxAdvanceDays: v.MaxAdvance,
+		SameDayCutoff:  v.Cutoff,
+	})
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to save booking rules", nil)
+		return
+	}
+	d.Next.InvalidateAll()
+	JSON(w, http.StatusOK, row)
+}
+
+// bookingWindow loads the effective rules for a service.
+func bookingWindow(ctx context.Context, q *gen.Queries, serviceID int64) (slots.Window, error) {
+	row, err := q.GetBookingRules(ctx, serviceID)
+	if err != nil {
+		return slots.Window{}, err
+	}
+	win := slots.Window{
+		MinLead:        time.Duration(row.MinLeadMinutes) * time.Minute,
+		MaxAdvanceDays: int(row.MaxAdvanceDays.Int32),
+	}
+	if row.SameDayCutoff != nil {
+		win.SameDayCutoff = *row.SameDayCutoff
+	}
+	return win, nil
+}
@@ -2,7 +2,9 @@ package api
 
 import (
 	"net/http"
+	"strconv"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/justanamir/medappoint/internal/db/gen"
 )
 
@@ -10,11 +12,36 @@ type ClinicDeps struct {
 	Q *gen.Queries
 }
 
+// ListClinicsHandler handles GET /v1/clinics?q=&limit=&cursor=
 func (d ClinicDeps) ListClinicsHandler(w http.ResponseWriter, r *http.Request) {
-	clinics, err := d.Q.ListClinics(r.Context())
+	afterID, limit, err := idPageParams(r)
+	if err != nil {
+		ErrorJSON(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	clinics, err := d.Q.ListClinics(r.Context(), gen.ListClinicsParams{
+		Q:        queryText(r, "q"),
+		AfterID:  afterID,
+		RowLimit: limit + 1,
+	})
 	if err != nil {
 		ErrorJSON(w, http.StatusInternalServerError, "failed to list clinics", nil)
 		return
 	}
-	JSON(w, http.StatusOK, clinics)
+	JSON(w, http.StatusOK, idPage(clinics, limit, func(c gen.Clinic) int64 { return c.ID }))
+}
+
+// GET /v1/clinics/{id}
+func (d ClinicDeps) GetClinicHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		ErrorJSON(w, http.StatusBadRequest, "invalid clinic id", nil)
+		return
+	}
+	clinic, err := d.Q.GetClinic(r.Context(), id)
+	if err != nil {
+		ErrorJSON(w, http.StatusNotFound, "clinic not found", nil)
+		return
+	}
+	JSON(w, http.StatusOK, clinic)
 }
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// pageCursor is the position of the last row on a page. It is handed to
+// clients as an opaque token; only the fields the listing sorts on are set.
+type pageCursor struct {
+	Time time.Time `json:"t,omitempty"`
+	Key  string    `json:"k,omitempty"`
+	ID   int64     `json:"id"`
+}
+
+var errBadCursor = errors.New("invalid cursor")
+
+func (c pageCursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (pageCursor, error) {
+	var c pageCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, errBadCursor
+	}
+	if err := json.Unmarshal(b, &c); err != nil || c.ID <= 0 {
+		return c, errBadCursor
+	}
+	return c, nil
+}
+
+// page is the envelope for cursor-paginated lists. NextCursor is empty on
+// the last page.
+type page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// idPageParams reads ?limit= (default 20, max 100) and ?cursor= for
+// listings ordered by id alone.
+func idPageParams(r *http.Request) (afterID int64, limit int32, err error) {
+	limit = 20
+	if s := r.URL.Query().Get("limit"); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n > 0 && n <= 100 {
+			limit = int32(n)
+		}
+	}
+	if s := r.URL.Query().Get("cursor"); s != "" {
+		c, err := decodeCursor(s)
+		if err != nil {
+			return 0, 0, err
+		}
+		afterID = c.ID
+	}
+	return afterID, limit, nil
+}
+
+// idPage trims rows fetched with limit+1 down to a page and sets the cursor.
+func idPage[T any](rows []T, limit int32, id func(T) int64) page[T] {
+	p := page[T]{Items: rows}
+	if len(rows) > int(limit) {
+		p.Items = rows[:limit]
+		p.NextCursor = pageCursor{ID: id(p.Items[limit-1])}.encode()
+	}
+	if p.Items == nil {
+		p.Items = []T{}
+	}
+	return p
+}
+
+// queryID reads an optional positive id filter such as ?clinic_id=.
+func queryID(r *http.Request, name string) (pgtype.Int8, bool) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return pgtype.Int8{}, true
+	}
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || id <= 0 {
+		return pgtype.Int8{}, false
+	}
+	return pgtype.Int8{Int64: id, Valid: true}, true
+}
+
+// queryText reads an optional free-text filter; blank means unset.
+func queryText(r *http.Request, name string) *string {
+	s := strings.TrimSpace(r.URL.Query().Get(name))
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/justanamir/medappoint/internal/db/gen"
+)
+
+var validRelationships = map[string]bool{
+	"parent": true, "guardian": true, "carer": true, "spouse": true, "other": true,
+}
+
+// GET /v1/me/dependents
+func (d MeDeps) ListDependents(w http.ResponseWriter, r *http.Request) {
+	uid, ok := UserIDFromCtx(r)
+	if !ok || uid <= 0 {
+		ErrorJSON(w, http.StatusUnauthorized, "unauthorized", nil)
+		return
+	}
+	rows, err := d.Q.ListDependents(r.Context(), uid)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to list dependents", nil)
+		return
+	}
+	patientIDs := make([]int64, 0, len(rows))
+	for _, p := range rows {
+		patientIDs = append(patientIDs, p.ID)
+	}
+	auditPatients(r, d.Q, auditEvent{Action: AuditPatientRead, ResourceType: AuditResPatient}, patientIDs)
+
+	if rows == nil {
+		rows = []gen.ListDependentsRow{}
+	}
+	JSON(w, http.StatusOK, rows)
+}
+
+type createDependentReq struct {
+	FullName     string  `json:"full_name"`
+	Dob          string  `json:"dob"` // YYYY-MM-DD
+	Phone        *string `json:"phone"`
+	Relationship string  `json:"relationship"` // parent|guardian|carer|spouse|other
+}
+
+// POST /v1/me/dependents
+// Adds a patient profile without its own login, managed by the caller.
+func (d MeDeps) CreateDependent(w http.ResponseWriter, r *http.Request) {
+	uid, ok := UserIDFromCtx(r)
+	if !ok || uid <= 0 {
+		ErrorJSON(w, http.StatusUnauthorized, "unauthorized", nil)
+		return
+	}
+	if role, _ := RoleFromCtx(r); role != "patient" {
+		ErrorJSON(w, http.StatusForbidden, "only patient accounts can add dependents", nil)
+		return
+	}
+
+	var req createDependentReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		ErrorJSON(w, http.StatusBadRequest, "invalid json", nil)
+		return
+	}
+
+	p := gen.CreateDependentParams{GuardianUserID: uid}
+	fieldErrs := map[string]string{}
+	p.FullName = strings.TrimSpace(req.FullName)
+	if p.FullName == "" || len(p.FullName) > 200 {
+		fieldErrs["full_name"] = "must be 1-200 characters"
+	}
+	if dob, err := parseDOB(req.Dob, time.Now()); err != nil {
+		fieldErrs["dob"] = err.Error()
+	} else {
+		p.Dob = pgtype.Date{Time: dob, Valid: true}
+	}
+	if req.Phone != nil && *req.Phone != "" {
+		phone, err := normalizePhone(*req.Phone)
+		if err != nil {
+			fieldErrs["phone"] = err.Error()
+		}
+		p.Phone = &phone
+	}
+	p.Relationship = strings.ToLower(strings.TrimSpace(req.Relationship))
+	if !validRelationships[p.Relationship] {
+		fieldErrs["relationship"] = "must be one of parent, guardian, carer, spouse, other"
+	}
+	if len(fieldErrs) > 0 {
+		ErrorJSON(w, http.StatusUnprocessableEntity, "invalid dependent", fieldErrs)
+		return
+	}
+
+	row, err := d.Q.CreateDependent(r.Context(), p)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to create dependent", nil)
+		return
+	}
+	audit(r, d.Q, auditEvent{Action: AuditPatientCreate, ResourceType: AuditResPatient, ResourceID: row.ID, PatientID: row.ID})
+
+	JSON(w, http.StatusCreated, row)
+}
+
+// DELETE /v1/me/dependents/{id}
+// Unlinks the dependent; the patient record and its appointments remain.
+func (d MeDeps) RemoveDependent(w http.ResponseWriter, r *http.Request) {
+	uid, ok := UserIDFromCtx(r)
+	if !ok || uid <= 0 {
+		ErrorJSON(w, http.StatusUnauthorized, "unauthorized", nil)
+		return
+	}
+	pid, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || pid <= 0 {
+		ErrorJSON(w, http.StatusBadRequest, "invalid patient id", nil)
+		return
+	}
+	n, err := d.Q.DeleteDependentLink(r.Context(), gen.DeleteDependentLinkParams{GuardianUserID: uid, PatientID: pid})
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to remove dependent", nil)
+		return
+	}
+	if n == 0 {
+		ErrorJSON(w, http.StatusNotFound, "dependent not found", nil)
+		return
+	}
+	audit(r, d.Q, auditEvent{Action: AuditPatientUnlink, ResourceType: AuditResPatient, ResourceID: pid, PatientID: pid})
+	w.WriteHeader(http.StatusNoContent)
+}
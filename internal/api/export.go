@@ -0,0 +1,264 @@
+package api
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Response formats for list endpoints that can also be downloaded.
+const (
+	formatJSON = "json"
+	formatCSV  = "csv"
+	formatXLSX = "xlsx"
+)
+
+const xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// exportWriteTimeout is how long each flushed batch of a download may take
+// to reach the client; it pushes back the server's WriteTimeout so long
+// exports aren't cut off.
+const exportWriteTimeout = 30 * time.Second
+
+// exportFormat picks the format from ?format=json|csv|xlsx, else from the
+// Accept header, defaulting to JSON. An unknown ?format= gets a 400.
+func exportFormat(w http.ResponseWriter, r *http.Request) (string, bool) {
+	switch f := r.URL.Query().Get("format"); f {
+	case formatJSON, formatCSV, formatXLSX:
+		return f, true
+	case "":
+	default:
+		ErrorJSON(w, http.StatusBadRequest, "format must be json, csv or xlsx", nil)
+		return "", false
+	}
+	accept := r.Header.Get("Accept")
+	switch {
+	case strings.Contains(accept, xlsxContentType):
+		return formatXLSX, true
+	case strings.Contains(accept, "text/csv"):
+		return formatCSV, true
+	}
+	return formatJSON, true
+}
+
+// tableWriter streams a CSV or XLSX download row by row.
+type tableWriter interface {
+	Row(cells ...string) error
+	// Flush sends the rows so far to the client.
+	Flush() error
+	// Close finishes the file; the response is incomplete without it.
+	Close() error
+}
+
+// startExport sends the download headers for name.csv or name.xlsx and
+// writes the header row. format must be csv or xlsx.
+func startExport(w http.ResponseWriter, format, name string, header []string) (tableWriter, error) {
+	rc := http.NewResponseController(w)
+	var t tableWriter
+	switch format {
+	case formatCSV:
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		t = &csvTable{w: csv.NewWriter(w), rc: rc}
+	case formatXLSX:
+		w.Header().Set("Content-Type", xlsxContentType)
+		t = &xlsxTable{zw: zip.NewWriter(w), rc: rc}
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+"."+format+`"`)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if x, ok := t.(*xlsxTable); ok {
+		if err := x.begin(); err != nil {
+			return nil, err
+		}
+	}
+	return t, t.Row(header...)
+}
+
+// exportTable is a fully loaded CSV/XLSX download.
+type exportTable struct {
+	Header []string
+	Rows   [][]string
+}
+
+// writeTable sends t as a csv or xlsx download.
+func writeTable(w http.ResponseWriter, format, name string, t exportTable) {
+	tw, err := startExport(w, format, name, t.Header)
+	if err != nil {
+		slog.Error("export failed", "file", name, "err", err)
+		return
+	}
+	for _, row := range t.Rows {
+		if err := tw.Row(row...); err != nil {
+			slog.Error("export failed", "file", name, "err", err)
+			return
+		}
+	}
+	if err := tw.Close(); err != nil {
+		slog.Error("export failed", "file", name, "err", err)
+	}
+}
+
+// flushResponse pushes buffered bytes out and extends the write deadline.
+// Writers that can't do either (tests, some wrappers) are fine without.
+func flushResponse(rc *http.ResponseController) error {
+	if err := rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+var numericCell = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?$`)
+
+type csvTable struct {
+	w  *csv.Writer
+	rc *http.ResponseController
+}
+
+func (t *csvTable) Row(cells ...string) error {
+	out := make([]string, len(cells))
+	for i, c := range cells {
+		// keep spreadsheet apps from running names like "=HYPERLINK(...)"
+		if c != "" && strings.ContainsRune("=+-@\t\r", rune(c[0])) && !numericCell.MatchString(c) {
+			c = "'" + c
+		}
+		out[i] = c
+	}
+	return t.w.Write(out)
+}
+
+func (t *csvTable) Flush() error {
+	t.w.Flush()
+	if err := t.w.Error(); err != nil {
+		return err
+	}
+	return flushResponse(t.rc)
+}
+
+func (t *csvTable) Close() error { return t.Flush() }
+
+// xlsxTable writes a single-sheet workbook. Cells are inline strings, or
+// numbers when they look like one, so no shared-strings table has to be
+// held in memory and the sheet can be streamed.
+type xlsxTable struct {
+	zw    *zip.Writer
+	rc    *http.ResponseController
+	sheet io.Writer
+	rows  int
+}
+
+var xlsxParts = []struct{ name, body string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/></Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`},
+	{"xl/styles.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><fonts count="2"><font/><font><b/></font></fonts><fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills><borders count="1"><border/></borders><cellStyleXfs count="1"><xf/></cellStyleXfs><cellXfs count="2"><xf/><xf fontId="1" applyFont="1"/></cellXfs></styleSheet>`},
+}
+
+func (t *xlsxTable) begin() error {
+	for _, p := range xlsxParts {
+		f, err := t.zw.Create(p.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, p.body); err != nil {
+			return err
+		}
+	}
+	sheet, err := t.zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	t.sheet = sheet
+	_, err = io.WriteString(sheet, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	return err
+}
+
+func (t *xlsxTable) Row(cells ...string) error {
+	t.rows++
+	var b strings.Builder
+	fmt.Fprintf(&b, `<row r="%d">`, t.rows)
+	style := ""
+	if t.rows == 1 {
+		style = ` s="1"` // bold header
+	}
+	for _, c := range cells {
+		switch {
+		case c == "":
+			fmt.Fprintf(&b, `<c%s/>`, style)
+		case numericCell.MatchString(c) && t.rows > 1:
+			fmt.Fprintf(&b, `<c%s><v>%s</v></c>`, style, c)
+		default:
+			fmt.Fprintf(&b, `<c%s t="inlineStr"><is><t xml:space="preserve">`, style)
+			_ = xml.EscapeText(&b, []byte(c))
+			b.WriteString(`</t></is></c>`)
+		}
+	}
+	b.WriteString(`</row>`)
+	_, err := io.WriteString(t.sheet, b.String())
+	return err
+}
+
+func (t *xlsxTable) Flush() error {
+	if err := t.zw.Flush(); err != nil {
+		return err
+	}
+	return flushResponse(t.rc)
+}
+
+func (t *xlsxTable) Close() error {
+	if _, err := io.WriteString(t.sheet, `</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	if err := t.zw.Close(); err != nil {
+		return err
+	}
+	return flushResponse(t.rc)
+}
+
+// zoneCache loads clinic timezones once per export. Unknown names fall
+// back to the server's clinic time.
+type zoneCache map[string]*time.Location
+
+func (z zoneCache) get(name string) *time.Location {
+	if loc, ok := z[name]; ok {
+		return loc
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		loc, _ = time.LoadLocation("Asia/Kuala_Lumpur")
+	}
+	z[name] = loc
+	return loc
+}
+
+// localTimes renders a visit as date, start and end in loc.
+func localTimes(start, end time.Time, loc *time.Location) (string, string, string) {
+	start, end = start.In(loc), end.In(loc)
+	return start.Format("2006-01-02"), start.Format("15:04"), end.Format("15:04")
+}
+
+func strOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
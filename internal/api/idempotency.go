@@ -0,0 +1,151 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/justanamir/medappoint/internal/db/gen"
+)
+
+const maxIdempotentBody = 1 << 20
+
+// Idempotent makes a write safe to retry with an Idempotency-Key header:
+// the first response for a key is stored for 24h and replayed to repeats
+// of the same request by the same user. Reusing a key with a different
+// request is a 422; a repeat that arrives while the first is still running
+// gets a 409. Server errors aren't stored, so those can be retried. Requests
+// without the header pass straight through. Mount after WithAuth.
+//
+// Each claim carries a random token; only the request holding it may store
+// or release the response, so a claim taken over from a request presumed
+// dead keeps the response of the request that took it.
+func Idempotent(q *gen.Queries) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get("Idempotency-Key")
+			uid, ok := UserIDFromCtx(r)
+			if key == "" || !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > 255 {
+				ErrorJSON(w, http.StatusBadRequest, "Idempotency-Key must be at most 255 characters", nil)
+				return
+			}
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentBody+1))
+			if err != nil || len(body) > maxIdempotentBody {
+				ErrorJSON(w, http.StatusBadRequest, "invalid request body", nil)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			sum := sha256.Sum256([]byte(r.Method + " " + r.URL.Path + "\n" + string(body)))
+			hash := hex.EncodeToString(sum[:])
+
+			var tok [16]byte
+			if _, err := rand.Read(tok[:]); err != nil {
+				ErrorJSON(w, http.StatusInternalServerError, "failed to check Idempotency-Key", nil)
+				return
+			}
+			token := hex.EncodeToString(tok[:])
+
+			ctx := r.Context()
+			claimed, err := q.ClaimIdempotencyKey(ctx, gen.ClaimIdempotencyKeyParams{
+				UserID:      uid,
+				Key:         key,
+				RequestHash: hash,
+				ClaimToken:  token,
+			})
+			if err != nil {
+				ErrorJSON(w, http.StatusInternalServerError, "failed to check Idempotency-Key", nil)
+				return
+			}
+			if claimed == 0 {
+				replayIdempotent(w, r, q, uid, key, hash)
+				return
+			}
+
+			rec := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			// store even if the client has gone away; that's when it retries
+			ctx = context.WithoutCancel(ctx)
+			if rec.status >= 500 {
+				if err := q.ReleaseIdempotencyKey(ctx, gen.ReleaseIdempotencyKeyParams{UserID: uid, Key: key, ClaimToken: token}); err != nil {
+					slog.Error("idempotency: release failed", "err", err)
+				}
+				return
+			}
+			resp := rec.body.String()
+			n, err := q.SaveIdempotentResponse(ctx, gen.SaveIdempotentResponseParams{
+				StatusCode:   pgtype.Int4{Int32: int32(rec.status), Valid: true},
+				ResponseBody: &resp,
+				UserID:       uid,
+				Key:          key,
+				ClaimToken:   token,
+			})
+			if err != nil {
+				slog.Error("idempotency: save failed", "err", err)
+			} else if n == 0 {
+				slog.Warn("idempotency: claim was taken over before the response was stored", "user_id", uid)
+			}
+		})
+	}
+}
+
+func replayIdempotent(w http.ResponseWriter, r *http.Request, q *gen.Queries, uid int64, key, hash string) {
+	prev, err := q.GetIdempotencyKey(r.Context(), gen.GetIdempotencyKeyParams{UserID: uid, Key: key})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// released after a server error a moment ago
+		ErrorJSON(w, http.StatusConflict, "a request with this Idempotency-Key is in progress; retry shortly", nil)
+		return
+	}
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to check Idempotency-Key", nil)
+		return
+	}
+	if prev.RequestHash != hash {
+		ErrorJSON(w, http.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request", nil)
+		return
+	}
+	if !prev.StatusCode.Valid {
+		ErrorJSON(w, http.StatusConflict, "a request with this Idempotency-Key is in progress; retry shortly", nil)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(int(prev.StatusCode.Int32))
+	if prev.ResponseBody != nil {
+		_, _ = io.WriteString(w, *prev.ResponseBody)
+	}
+}
+
+// recordingWriter passes the response through and keeps a copy.
+type recordingWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rw *recordingWriter) WriteHeader(code int) {
+	if !rw.wroteHeader {
+		rw.status = code
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recordingWriter) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/justanamir/medappoint/internal/fieldcrypt"
+	"github.com/justanamir/medappoint/internal/importer"
+	"github.com/justanamir/medappoint/internal/rbac"
+)
+
+// ImportDeps needs the pool itself: an import runs in one transaction.
+type ImportDeps struct {
+	Pool *pgxpool.Pool
+	Keys *fieldcrypt.Keyring
+	Next *NextAvailable
+}
+
+const maxImportBody = 10 << 20
+
+// POST /v1/admin/import[?dry_run=true]
+// Body: an importer.Batch as JSON, or multipart/form-data with clinic_id
+// (or clinic_name, clinic_timezone and clinic_address to create one) and
+// CSV files in the parts services, providers and availability.
+// Needs catalog:write in the clinic (in every clinic to create one), and
+// staff:manage there as well to add providers. Answers 200 with the
+// report, or 422 with it when the batch has problems; nothing is written
+// then, nor on a dry run. A row that clashes with data written meanwhile
+// is a 409 naming its section and row.
+func (d ImportDeps) ImportHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBody)
+	var b importer.Batch
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mt == "multipart/form-data" {
+		if err := r.ParseMultipartForm(maxImportBody); err != nil {
+			ErrorJSON(w, http.StatusBadRequest, "invalid multipart body", nil)
+			return
+		}
+		if s := r.FormValue("clinic_id"); s != "" {
+			id, err := strconv.ParseInt(s, 10, 64)
+			if err != nil || id <= 0 {
+				ErrorJSON(w, http.StatusBadRequest, "invalid clinic_id", nil)
+				return
+			}
+			b.Clinic.ID = id
+		}
+		b.Clinic.Name = r.FormValue("clinic_name")
+		b.Clinic.Timezone = r.FormValue("clinic_timezone")
+		if a := r.FormValue("clinic_address"); a != "" {
+			b.Clinic.Address = &a
+		}
+		for _, section := range importer.Sections {
+			f, _, err := r.FormFile(section)
+			if err == http.ErrMissingFile {
+				continue
+			}
+			if err != nil {
+				ErrorJSON(w, http.StatusBadRequest, "invalid "+section+" file", nil)
+				return
+			}
+			err = importer.ReadCSV(&b, section, f)
+			f.Close()
+			if err != nil {
+				ErrorJSON(w, http.StatusBadRequest, err.Error(), nil)
+				return
+			}
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		ErrorJSON(w, http.StatusBadRequest, "invalid json", nil)
+		return
+	}
+
+	grants := GrantsFromCtx(r)
+	if b.Clinic.ID > 0 {
+		if !grants.Can(rbac.CatalogWrite, b.Clinic.ID) ||
+			(len(b.Providers) > 0 && !grants.Can(rbac.StaffManage, b.Clinic.ID)) {
+			ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
+			return
+		}
+	} else if !grants.Scope(rbac.CatalogWrite).All ||
+		(len(b.Providers) > 0 && !grants.Scope(rbac.StaffManage).All) {
+		ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+	rep, err := importer.Run(r.Context(), d.Pool, d.Keys, b, dryRun)
+	if isUniqueViolation(err) {
+		// the constraint error names our schema; the caller gets the row
+		slog.Warn("import conflict", "err", err)
+		var details interface{}
+		var we *importer.WriteError
+		if errors.As(err, &we) {
+			details = importer.Problem{Section: we.Section, Row: we.Row, Message: "conflicts with existing data"}
+		}
+		ErrorJSON(w, http.StatusConflict, "import conflicts with existing data; run a dry run for details", details)
+		return
+	}
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "import failed", nil)
+		return
+	}
+	if !rep.OK() {
+		JSON(w, http.StatusUnprocessableEntity, rep)
+		return
+	}
+	if rep.Committed {
+		d.Next.InvalidateAll()
+	}
+	JSON(w, http.StatusOK, rep)
+}
@@ -0,0 +1,240 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/justanamir/medappoint/internal/db/gen"
+	"github.com/justanamir/medappoint/internal/forms"
+	"github.com/justanamir/medappoint/internal/rbac"
+)
+
+type IntakeDeps struct {
+	Q *gen.Queries
+}
+
+// maxSchemaBytes bounds an uploaded form definition.
+const maxSchemaBytes = 256 << 10
+
+// maxAnswersBytes bounds a submitted questionnaire.
+const maxAnswersBytes = 1 << 20
+
+// GET /v1/services/{id}/intake-form
+// The live (latest) questionnaire for a service; 404 when it has none.
+func (d IntakeDeps) GetServiceFormHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		ErrorJSON(w, http.StatusBadRequest, "invalid service id", nil)
+		return
+	}
+	form, err := d.Q.GetLatestIntakeForm(r.Context(), id)
+	if err != nil {
+		ErrorJSON(w, http.StatusNotFound, "service has no intake form", nil)
+		return
+	}
+	JSON(w, http.StatusOK, form)
+}
+
+// GET /v1/intake-forms/{id}
+// A specific version, e.g. the one an older response was answered against.
+func (d IntakeDeps) GetFormHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		ErrorJSON(w, http.StatusBadRequest, "invalid form id", nil)
+		return
+	}
+	form, err := d.Q.GetIntakeForm(r.Context(), id)
+	if err != nil {
+		ErrorJSON(w, http.StatusNotFound, "intake form not found", nil)
+		return
+	}
+	JSON(w, http.StatusOK, form)
+}
+
+// PUT /v1/admin/services/{id}/intake-form   body: the schema (see internal/forms)
+// Publishes a new version; existing responses keep theirs.
+func (d IntakeDeps) PutServiceFormHandler(w http.ResponseWriter, r *http.Request) {
+	uid, _ := UserIDFromCtx(r)
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		ErrorJSON(w, http.StatusBadRequest, "invalid service id", nil)
+		return
+	}
+	ctx := r.Context()
+	svc, err := d.Q.GetService(ctx, id)
+	if err != nil {
+		ErrorJSON(w, http.StatusNotFound, "service not found", nil)
+		return
+	}
+	if !GrantsFromCtx(r).Can(rbac.CatalogWrite, svc.ClinicID) {
+		ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
+		return
+	}
+
+	raw, err := io.ReadAll(io.LimitReader(r.Body, maxSchemaBytes+1))
+	if err != nil || len(raw) > maxSchemaBytes {
+		ErrorJSON(w, http.StatusBadRequest, "schema too large", nil)
+		return
+	}
+	schema, err := forms.Parse(raw)
+	if err != nil {
+		ErrorJSON(w, http.StatusUnprocessableEntity, "invalid schema", err.Error())
+		return
+	}
+	canonical, _ := json.Marshal(schema)
+
+	form, err := d.Q.CreateIntakeForm(ctx, gen.CreateIntakeFormParams{
+		ServiceID: id,
+		Schema:    canonical,
+		CreatedBy: pgtype.Int8{Int64: uid, Valid: true},
+	})
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to save intake form", nil)
+		return
+	}
+	JSON(w, http.StatusCreated, form)
+}
+
+type intakeSubmitReq struct {
+	Answers map[string]json.RawMessage `json:"answers"`
+}
+
+// intakeView is a response as clients see it: answers decoded back to JSON.
+type intakeView struct {
+	AppointmentID int64           `json:"appointment_id"`
+	FormID        int64           `json:"form_id"`
+	FormVersion   int32           `json:"form_version"`
+	Answers       json.RawMessage `json:"answers"`
+	SubmittedAt   time.Time       `json:"submitted_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// PUT /v1/appointments/{id}/intake   body: {"answers": {...}}
+// The patient (or a guardian, or front desk) answers the service's form
+// before the visit. Re-submitting replaces the answers.
+func (d IntakeDeps) SubmitHandler(w http.ResponseWriter, r *http.Request) {
+	uid, _ := UserIDFromCtx(r)
+	ctx := r.Context()
+	appt, ok := loadAppointment(w, r, d.Q)
+	if !ok {
+		return
+	}
+	if !GrantsFromCtx(r).Can(rbac.AppointmentsWrite, appt.ClinicID) && !ownsPatient(ctx, d.Q, uid, appt.PatientID) {
+		ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
+		return
+	}
+	if appt.Status != "scheduled" || !appt.StartTime.After(time.Now()) {
+		ErrorJSON(w, http.StatusConflict, "intake can only be submitted before a scheduled visit", nil)
+		return
+	}
+
+	form, err := d.Q.GetLatestIntakeForm(ctx, appt.ServiceID)
+	if err != nil {
+		ErrorJSON(w, http.StatusNotFound, "service has no intake form", nil)
+		return
+	}
+	schema, err := forms.Parse(form.Schema)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "stored intake form is invalid", nil)
+		return
+	}
+
+	var req intakeSubmitReq
+	r.Body = http.MaxBytesReader(w, r.Body, maxAnswersBytes)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		ErrorJSON(w, http.StatusBadRequest, "invalid json", nil)
+		return
+	}
+	answers, fieldErrs := schema.Validate(req.Answers)
+	if fieldErrs != nil {
+		ErrorJSON(w, http.StatusUnprocessableEntity, "invalid answers", fieldErrs)
+		return
+	}
+
+	row, err := d.Q.UpsertIntakeResponse(ctx, gen.UpsertIntakeResponseParams{
+		AppointmentID: appt.ID,
+		FormID:        form.ID,
+		Answers:       string(answers),
+		SubmittedBy:   pgtype.Int8{Int64: uid, Valid: true},
+	})
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to save intake", nil)
+		return
+	}
+	audit(r, d.Q, auditEvent{
+		Action:       AuditIntakeSubmit,
+		ResourceType: AuditResIntake,
+		ResourceID:   row.ID,
+		PatientID:    appt.PatientID,
+		ClinicID:     appt.ClinicID,
+	})
+
+	JSON(w, http.StatusOK, intakeView{
+		AppointmentID: row.AppointmentID,
+		FormID:        row.FormID,
+		FormVersion:   form.Version,
+		Answers:       json.RawMessage(row.Answers),
+		SubmittedAt:   row.SubmittedAt,
+		UpdatedAt:     row.UpdatedAt,
+	})
+}
+
+// GET /v1/appointments/{id}/intake
+// Readable by the patient side, clinic staff with appointments:read and the
+// appointment's provider.
+func (d IntakeDeps) GetHandler(w http.ResponseWriter, r *http.Request) {
+	uid, _ := UserIDFromCtx(r)
+	ctx := r.Context()
+	appt, ok := loadAppointment(w, r, d.Q)
+	if !ok {
+		return
+	}
+	allowed := GrantsFromCtx(r).Can(rbac.AppointmentsRead, appt.ClinicID) || ownsPatient(ctx, d.Q, uid, appt.PatientID)
+	if !allowed {
+		if prov, err := d.Q.GetProvider(ctx, appt.ProviderID); err == nil {
+			allowed = canReadSchedule(r, uid, prov)
+		}
+	}
+	if !allowed {
+		ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
+		return
+	}
+
+	row, err := d.Q.GetIntakeResponseByAppointment(ctx, appt.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		ErrorJSON(w, http.StatusNotFound, "no intake submitted", nil)
+		return
+	}
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to load intake", nil)
+		return
+	}
+	form, err := d.Q.GetIntakeForm(ctx, row.FormID)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to load intake form", nil)
+		return
+	}
+	audit(r, d.Q, auditEvent{
+		Action:       AuditIntakeRead,
+		ResourceType: AuditResIntake,
+		ResourceID:   row.ID,
+		PatientID:    appt.PatientID,
+		ClinicID:     appt.ClinicID,
+	})
+
+	JSON(w, http.StatusOK, intakeView{
+		AppointmentID: row.AppointmentID,
+		FormID:        row.FormID,
+		FormVersion:   form.Version,
+		Answers:       json.RawMessage(row.Answers),
+		SubmittedAt:   row.SubmittedAt,
+		UpdatedAt:     row.UpdatedAt,
+	})
+}
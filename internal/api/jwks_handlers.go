@@ -0,0 +1,17 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/justanamir/medappoint/internal/auth"
+)
+
+type JWKSDeps struct {
+	Keys *auth.KeySet
+}
+
+// JWKSHandler handles GET /.well-known/jwks.json so other services can verify our tokens.
+func (d JWKSDeps) JWKSHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	JSON(w, http.StatusOK, d.Keys.JWKS())
+}
@@ -1,10 +1,15 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/justanamir/medappoint/internal/config"
 	"github.com/justanamir/medappoint/internal/db/gen"
 )
@@ -14,7 +19,14 @@ type MeDeps struct {
 	Q   *gen.Queries
 }
 
-// GET /v1/me/appointments?limit=20&offset=0
+var appointmentStatuses = map[string]bool{"scheduled": true, "completed": true, "cancelled": true}
+
+// GET /v1/me/appointments?scope=upcoming|past|all&status=&from=&to=&limit=20&cursor=[&patient_id=]
+// Covers the caller's own profile and all their dependents; patient_id
+// narrows it to one of them. upcoming is soonest first and hides cancelled
+// visits unless status asks for them; past and all are newest first.
+// status is a comma-separated list; from/to are RFC3339 or YYYY-MM-DD (to is
+// inclusive for dates). Page with cursor = next_cursor from the last page.
 func (d MeDeps) ListMyAppointments(w http.ResponseWriter, r *http.Request) {
 	uid, ok := UserIDFromCtx(r)
 	if !ok || uid <= 0 {
@@ -22,41 +34,235 @@ func (d MeDeps) ListMyAppointments(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// map user -> patient
-	patient, err := d.Q.GetPatientByUserID(r.Context(), uid)
+	// map user -> own patient profile + dependents
+	patientIDs, err := d.Q.ListManagedPatientIDs(r.Context(), uid)
 	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to load patient profiles", nil)
+		return
+	}
+	if len(patientIDs) == 0 {
 		ErrorJSON(w, http.StatusNotFound, "patient profile not found", nil)
 		return
 	}
 
-	// pagination
 	q := r.URL.Query()
-	limit := int32(20)
-	offset := int32(0)
+	if s := q.Get("patient_id"); s != "" {
+		pid, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || !containsID(patientIDs, pid) {
+			ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
+			return
+		}
+		patientIDs = []int64{pid}
+	}
+
+	loc, _ := time.LoadLocation("Asia/Kuala_Lumpur")
+	now := time.Now().In(loc)
+
+	p := gen.ListAppointmentsByPatientsParams{PatientIds: patientIDs, RowLimit: 20}
+	if s := q.Get("status"); s != "" {
+		for _, st := range strings.Split(s, ",") {
+			st = strings.TrimSpace(st)
+			if !appointmentStatuses[st] {
+				ErrorJSON(w, http.StatusBadRequest, "invalid status", st)
+				return
+			}
+			p.Statuses = append(p.Statuses, st)
+		}
+	}
+	var bad string
+	p.FromTime = historyTimeParam(q.Get("from"), loc, false, &bad, "from")
+	p.ToTime = historyTimeParam(q.Get("to"), loc, true, &bad, "to")
+	if bad != "" {
+		ErrorJSON(w, http.StatusBadRequest, "invalid "+bad, nil)
+		return
+	}
+
+	switch scope := q.Get("scope"); scope {
+	case "", "upcoming":
+		if !p.FromTime.Valid || p.FromTime.Time.Before(now) {
+			p.FromTime = pgtype.Timestamptz{Time: now, Valid: true}
+		}
+		if p.Statuses == nil {
+			p.Statuses = []string{"scheduled", "completed"}
+		}
+	case "past":
+		if !p.ToTime.Valid || p.ToTime.Time.After(now) {
+			p.ToTime = pgtype.Timestamptz{Time: now, Valid: true}
+		}
+		p.NewestFirst = true
+	case "all":
+		p.NewestFirst = true
+	default:
+		ErrorJSON(w, http.StatusBadRequest, "scope must be upcoming, past or all", nil)
+		return
+	}
+
 	if s := q.Get("limit"); s != "" {
 		if n, err := strconv.Atoi(s); err == nil && n > 0 && n <= 100 {
-			limit = int32(n)
+			p.RowLimit = int32(n)
 		}
 	}
-	if s := q.Get("offset"); s != "" {
-		if n, err := strconv.Atoi(s); err == nil && n >= 0 {
-			offset = int32(n)
+	if s := q.Get("cursor"); s != "" {
+		c, err := decodeCursor(s)
+		if err != nil {
+			ErrorJSON(w, http.StatusBadRequest, err.Error(), nil)
+			return
 		}
+		p.CursorTime = pgtype.Timestamptz{Time: c.Time, Valid: true}
+		p.CursorID = c.ID
 	}
 
-	loc, _ := time.LoadLocation("Asia/Kuala_Lumpur")
-	now := time.Now().In(loc)
-
-	rows, err := d.Q.ListUpcomingAppointmentsByPatient(r.Context(), gen.ListUpcomingAppointmentsByPatientParams{
-		PatientID: patient.ID,
-		StartTime: now,
-		Limit:     limit,
-		Offset:    offset,
-	})
+	// fetch one extra row to know whether there is a next page
+	limit := int(p.RowLimit)
+	p.RowLimit++
+	rows, err := d.Q.ListAppointmentsByPatients(r.Context(), p)
 	if err != nil {
 		ErrorJSON(w, http.StatusInternalServerError, "failed to list appointments", nil)
 		return
 	}
+	resp := page[gen.ListAppointmentsByPatientsRow]{Items: rows}
+	if len(rows) > limit {
+		resp.Items = rows[:limit]
+		last := resp.Items[limit-1]
+		resp.NextCursor = pageCursor{Time: last.StartTime, ID: last.ID}.encode()
+	}
+	if resp.Items == nil {
+		resp.Items = []gen.ListAppointmentsByPatientsRow{}
+	}
+
+	auditPatients(r, d.Q, auditEvent{
+		Action:       AuditAppointmentList,
+		ResourceType: AuditResAppointment,
+	}, patientIDs)
+
+	JSON(w, http.StatusOK, resp)
+}
+
+// historyTimeParam parses an RFC3339 timestamp or a YYYY-MM-DD date in loc.
+// With endOfDay a date means the start of the following day, so it can be
+// used as an exclusive upper bound. On error it records name in *bad.
+func historyTimeParam(s string, loc *time.Location, endOfDay bool, bad *string, name string) pgtype.Timestamptz {
+	if s == "" {
+		return pgtype.Timestamptz{}
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return pgtype.Timestamptz{Time: t, Valid: true}
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, loc)
+	if err != nil {
+		*bad = name
+		return pgtype.Timestamptz{}
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return pgtype.Timestamptz{Time: t, Valid: true}
+}
 
-	JSON(w, http.StatusOK, rows)
+// GET /v1/me/profile
+func (d MeDeps) GetProfile(w http.ResponseWriter, r *http.Request) {
+	uid, ok := UserIDFromCtx(r)
+	if !ok || uid <= 0 {
+		ErrorJSON(w, http.StatusUnauthorized, "unauthorized", nil)
+		return
+	}
+	patient, err := d.Q.GetPatientByUserID(r.Context(), uid)
+	if err != nil {
+		ErrorJSON(w, http.StatusNotFound, "patient profile not found", nil)
+		return
+	}
+	audit(r, d.Q, auditEvent{Action: AuditPatientRead, ResourceType: AuditResPatient, ResourceID: patient.ID, PatientID: patient.ID})
+
+	JSON(w, http.StatusOK, patient)
+}
+
+type updateProfileReq struct {
+	FullName *string `json:"full_name"`
+	Phone    *string `json:"phone"` // E.164, separators are stripped
+	Dob      *string `json:"dob"`   // YYYY-MM-DD
+}
+
+// PATCH /v1/me/profile
+// Omitted fields stay unchanged. The first PATCH creates the profile, so
+// full_name is required then.
+func (d MeDeps) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	uid, ok := UserIDFromCtx(r)
+	if !ok || uid <= 0 {
+		ErrorJSON(w, http.StatusUnauthorized, "unauthorized", nil)
+		return
+	}
+	if role, _ := RoleFromCtx(r); role != "patient" {
+		ErrorJSON(w, http.StatusForbidden, "only patient accounts have a profile", nil)
+		return
+	}
+
+	var req updateProfileReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		ErrorJSON(w, http.StatusBadRequest, "invalid json", nil)
+		return
+	}
+
+	var p gen.UpdatePatientProfileParams
+	fieldErrs := map[string]string{}
+	if req.FullName != nil {
+		name := strings.TrimSpace(*req.FullName)
+		if name == "" || len(name) > 200 {
+			fieldErrs["full_name"] = "must be 1-200 characters"
+		}
+		p.FullName = &name
+	}
+	if req.Phone != nil {
+		phone, err := normalizePhone(*req.Phone)
+		if err != nil {
+			fieldErrs["phone"] = err.Error()
+		}
+		p.Phone = &phone
+	}
+	if req.Dob != nil {
+		dob, err := parseDOB(*req.Dob, time.Now())
+		if err != nil {
+			fieldErrs["dob"] = err.Error()
+		}
+		p.Dob = pgtype.Date{Time: dob, Valid: err == nil}
+	}
+	if len(fieldErrs) > 0 {
+		ErrorJSON(w, http.StatusUnprocessableEntity, "invalid profile", fieldErrs)
+		return
+	}
+
+	ctx := r.Context()
+	var row gen.Patient
+	existing, err := d.Q.GetPatientByUserID(ctx, uid)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		if p.FullName == nil {
+			ErrorJSON(w, http.StatusUnprocessableEntity, "invalid profile", map[string]string{"full_name": "required"})
+			return
+		}
+		row, err = d.Q.CreatePatient(ctx, gen.CreatePatientParams{
+			UserID:   pgtype.Int8{Int64: uid, Valid: true},
+			FullName: *p.FullName,
+			Phone:    p.Phone,
+			Dob:      p.Dob,
+		})
+	case err == nil:
+		p.ID = existing.ID
+		row, err = d.Q.UpdatePatientProfile(ctx, p)
+	}
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to save profile", nil)
+		return
+	}
+	audit(r, d.Q, auditEvent{Action: AuditPatientUpdate, ResourceType: AuditResPatient, ResourceID: row.ID, PatientID: row.ID})
+
+	JSON(w, http.StatusOK, row)
+}
+
+func containsID(ids []int64, id int64) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
 }
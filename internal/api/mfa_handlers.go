@@ -0,0 +1,315 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/justanamir/medappoint/internal/auth"
+	"github.com/justanamir/medappoint/internal/config"
+	"github.com/justanamir/medappoint/internal/db/gen"
+	"github.com/justanamir/medappoint/internal/rbac"
+)
+
+type MFADeps struct {
+	Cfg  config.Config
+	Q    *gen.Queries
+	Keys *auth.KeySet
+}
+
+const (
+	recoveryCodeCount = 10
+	mfaMaxFailures    = 5
+	mfaLockout        = 15 * time.Minute // keep in sync with RecordMFAFailure
+)
+
+type mfaCodeRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// POST /v1/auth/mfa/verify   (Authorization: Bearer <mfa_token>)
+// Second login step: exchanges the challenge token plus a TOTP or recovery
+// code for an access token.
+func (d MFADeps) VerifyHandler(w http.ResponseWriter, r *http.Request) {
+	uid, _ := UserIDFromCtx(r)
+	role, _ := RoleFromCtx(r)
+	if PurposeFromCtx(r) != auth.PurposeMFA {
+		ErrorJSON(w, http.StatusUnauthorized, "mfa token required", nil)
+		return
+	}
+	var req mfaCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		ErrorJSON(w, http.StatusBadRequest, "invalid json", nil)
+		return
+	}
+
+	ctx := r.Context()
+	m, err := d.Q.GetUserMFA(ctx, uid)
+	if err != nil || !m.EnabledAt.Valid {
+		ErrorJSON(w, http.StatusUnauthorized, "mfa not enabled", nil)
+		return
+	}
+	if mfaLocked(m) {
+		ErrorJSON(w, http.StatusTooManyRequests, "too many failed attempts, try again later", nil)
+		return
+	}
+
+	ok := false
+	switch {
+	case req.Code != "":
+		ok = d.acceptTOTP(ctx, m, req.Code)
+	case req.RecoveryCode != "":
+		n, err := d.Q.UseRecoveryCode(ctx, gen.UseRecoveryCodeParams{
+			UserID:   uid,
+			CodeHash: auth.HashRecoveryCode(req.RecoveryCode),
+		})
+		ok = err == nil && n == 1
+	default:
+		ErrorJSON(w, http.StatusBadRequest, "code or recovery_code required", nil)
+		return
+	}
+	if !ok {
+		_, _ = d.Q.RecordMFAFailure(ctx, uid)
+		ErrorJSON(w, http.StatusUnauthorized, "invalid code", nil)
+		return
+	}
+
+	tok, err := d.Keys.SignJWT(d.Cfg.JWTIssuer, d.Cfg.JWTTTLMinutes, uid, role)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "token error", nil)
+		return
+	}
+	JSON(w, http.StatusOK, tokenResponse{Token: tok})
+}
+
+type mfaEnrollResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"` // render as QR code
+}
+
+// POST /v1/me/mfa/enroll   (access token or mfa_enroll token)
+// Creates a pending TOTP secret; it becomes active after /confirm.
+func (d MFADeps) EnrollHandler(w http.ResponseWriter, r *http.Request) {
+	uid, _ := UserIDFromCtx(r)
+	ctx := r.Context()
+
+	u, err := d.Q.GetUserByID(ctx, uid)
+	if err != nil {
+		ErrorJSON(w, http.StatusNotFound, "user not found", nil)
+		return
+	}
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to generate secret", nil)
+		return
+	}
+	m, err := d.Q.UpsertPendingUserMFA(ctx, gen.UpsertPendingUserMFAParams{UserID: uid, TotpSecret: secret})
+	if errors.Is(err, pgx.ErrNoRows) {
+		ErrorJSON(w, http.StatusConflict, "mfa already enabled", nil)
+		return
+	}
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to start enrollment", nil)
+		return
+	}
+
+	JSON(w, http.StatusOK, mfaEnrollResponse{
+		Secret:     m.TotpSecret,
+		OTPAuthURI: auth.TOTPURI(d.Cfg.JWTIssuer, u.Email, m.TotpSecret),
+	})
+}
+
+type mfaConfirmResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"` // shown once
+	Token         string   `json:"token,omitempty"`
+}
+
+// POST /v1/me/mfa/confirm {code}
+// Activates the pending secret and returns fresh recovery codes. When called
+// with an enrollment token it also completes the login.
+func (d MFADeps) ConfirmHandler(w http.ResponseWriter, r *http.Request) {
+	uid, _ := UserIDFromCtx(r)
+	role, _ := RoleFromCtx(r)
+	var req mfaCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		ErrorJSON(w, http.StatusBadRequest, "code required", nil)
+		return
+	}
+
+	ctx := r.Context()
+	m, err := d.Q.GetUserMFA(ctx, uid)
+	if err != nil {
+		ErrorJSON(w, http.StatusNotFound, "no pending enrollment", nil)
+		return
+	}
+	if m.EnabledAt.Valid {
+		ErrorJSON(w, http.StatusConflict, "mfa already enabled", nil)
+		return
+	}
+	step, ok := auth.ValidateTOTP(m.TotpSecret, req.Code, time.Now())
+	if !ok {
+		ErrorJSON(w, http.StatusUnauthorized, "invalid code", nil)
+		return
+	}
+	if err := d.Q.EnableUserMFA(ctx, gen.EnableUserMFAParams{UserID: uid, LastUsedStep: step}); err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to enable mfa", nil)
+		return
+	}
+	codes, err := d.newRecoveryCodes(ctx, uid)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to create recovery codes", nil)
+		return
+	}
+
+	resp := mfaConfirmResponse{RecoveryCodes: codes}
+	if PurposeFromCtx(r) == auth.PurposeMFAEnroll {
+		resp.Token, err = d.Keys.SignJWT(d.Cfg.JWTIssuer, d.Cfg.JWTTTLMinutes, uid, role)
+		if err != nil {
+			ErrorJSON(w, http.StatusInternalServerError, "token error", nil)
+			return
+		}
+	}
+	JSON(w, http.StatusOK, resp)
+}
+
+// POST /v1/me/mfa/recovery-codes {code}
+// Replaces all recovery codes; requires a current TOTP code.
+func (d MFADeps) RegenerateRecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	uid, _ := UserIDFromCtx(r)
+	m, ok := d.requireCode(w, r, uid)
+	if !ok {
+		return
+	}
+	codes, err := d.newRecoveryCodes(r.Context(), m.UserID)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to create recovery codes", nil)
+		return
+	}
+	JSON(w, http.StatusOK, mfaConfirmResponse{RecoveryCodes: codes})
+}
+
+// DELETE /v1/me/mfa {code}
+// Staff (anyone holding a clinic role) cannot disable MFA while the
+// security policy requires it.
+func (d MFADeps) DisableHandler(w http.ResponseWriter, r *http.Request) {
+	uid, _ := UserIDFromCtx(r)
+	ctx := r.Context()
+
+	if len(GrantsFromCtx(r)) > 0 {
+		pol, err := d.Q.GetSecurityPolicy(ctx)
+		if err != nil {
+			ErrorJSON(w, http.StatusInternalServerError, "failed to load security policy", nil)
+			return
+		}
+		if pol.RequireStaffMfa {
+			ErrorJSON(w, http.StatusForbidden, "mfa is required for staff accounts", nil)
+			return
+		}
+	}
+	if _, ok := d.requireCode(w, r, uid); !ok {
+		return
+	}
+	if err := d.Q.DeleteRecoveryCodes(ctx, uid); err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to disable mfa", nil)
+		return
+	}
+	if err := d.Q.DeleteUserMFA(ctx, uid); err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to disable mfa", nil)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type securityPolicyRequest struct {
+	RequireStaffMFA bool `json:"require_staff_mfa"`
+}
+
+// GET /v1/admin/security-policy
+func (d MFADeps) GetPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	pol, err := d.Q.GetSecurityPolicy(r.Context())
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to load security policy", nil)
+		return
+	}
+	JSON(w, http.StatusOK, pol)
+}
+
+// PUT /v1/admin/security-policy {require_staff_mfa}
+// Global setting, so it needs security:manage across all clinics.
+func (d MFADeps) UpdatePolicyHandler(w http.ResponseWriter, r *http.Request) {
+	if !GrantsFromCtx(r).Scope(rbac.SecurityManage).All {
+		ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
+		return
+	}
+	var req securityPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		ErrorJSON(w, http.StatusBadRequest, "invalid json", nil)
+		return
+	}
+	pol, err := d.Q.UpdateSecurityPolicy(r.Context(), req.RequireStaffMFA)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to update security policy", nil)
+		return
+	}
+	JSON(w, http.StatusOK, pol)
+}
+
+// ---- helpers ----
+
+// requireCode decodes {code} and checks it against the enabled factor,
+// writing the error response itself.
+func (d MFADeps) requireCode(w http.ResponseWriter, r *http.Request, uid int64) (gen.UserMfa, bool) {
+	var req mfaCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		ErrorJSON(w, http.StatusBadRequest, "code required", nil)
+		return gen.UserMfa{}, false
+	}
+	ctx := r.Context()
+	m, err := d.Q.GetUserMFA(ctx, uid)
+	if err != nil || !m.EnabledAt.Valid {
+		ErrorJSON(w, http.StatusNotFound, "mfa not enabled", nil)
+		return gen.UserMfa{}, false
+	}
+	if mfaLocked(m) {
+		ErrorJSON(w, http.StatusTooManyRequests, "too many failed attempts, try again later", nil)
+		return gen.UserMfa{}, false
+	}
+	if !d.acceptTOTP(ctx, m, req.Code) {
+		_, _ = d.Q.RecordMFAFailure(ctx, uid)
+		ErrorJSON(w, http.StatusUnauthorized, "invalid code", nil)
+		return gen.UserMfa{}, false
+	}
+	return m, true
+}
+
+// acceptTOTP validates code and burns its time step so it cannot be reused.
+func (d MFADeps) acceptTOTP(ctx context.Context, m gen.UserMfa, code string) bool {
+	step, ok := auth.ValidateTOTP(m.TotpSecret, code, time.Now())
+	if !ok {
+		return false
+	}
+	n, err := d.Q.AcceptMFAStep(ctx, gen.AcceptMFAStepParams{UserID: m.UserID, LastUsedStep: step})
+	return err == nil && n == 1
+}
+
+func (d MFADeps) newRecoveryCodes(ctx context.Context, uid int64) ([]string, error) {
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, c := range codes {
+		hashes[i] = auth.HashRecoveryCode(c)
+	}
+	err = d.Q.ReplaceRecoveryCodes(ctx, gen.ReplaceRecoveryCodesParams{UserID: uid, CodeHashes: hashes})
+	return codes, err
+}
+
+func mfaLocked(m gen.UserMfa) bool {
+	return m.FailedAttempts >= mfaMaxFailures &&
+		m.LastFailedAt.Valid && time.Since(m.LastFailedAt.Time) < mfaLockout
+}
@@ -6,18 +6,19 @@ import (
 	"strings"
 
 	"github.com/justanamir/medappoint/internal/auth"
-	"github.com/justanamir/medappoint/internal/config"
 )
 
 type ctxKey string
 
 const (
-	ctxUserID ctxKey = "uid"
-	ctxRole   ctxKey = "role"
+	ctxUserID  ctxKey = "uid"
+	ctxRole    ctxKey = "role"
+	ctxPurpose ctxKey = "purpose"
 )
 
 // WithAuth validates "Authorization: Bearer <jwt>" and attaches user to context.
-func WithAuth(cfg config.Config) func(http.Handler) http.Handler {
+// Only access tokens pass unless extra token purposes are listed in allow.
+func WithAuth(keys *auth.KeySet, allow ...string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			h := r.Header.Get("Authorization")
@@ -27,19 +28,32 @@ func WithAuth(cfg config.Config) func(http.Handler) http.Handler {
 			}
 			token := strings.TrimSpace(h[len("Bearer "):])
 
-			claims, err := auth.ParseJWT(cfg.JWTSecret, token)
-			if err != nil {
+			claims, err := keys.ParseJWT(token)
+			if err != nil || !purposeAllowed(claims.Purpose, allow) {
 				ErrorJSON(w, http.StatusUnauthorized, "invalid token", nil)
 				return
 			}
 
 			ctx := context.WithValue(r.Context(), ctxUserID, claims.UserID)
 			ctx = context.WithValue(ctx, ctxRole, claims.Role)
+			ctx = context.WithValue(ctx, ctxPurpose, claims.Purpose)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+func purposeAllowed(p string, allow []string) bool {
+	if p == "" {
+		return true
+	}
+	for _, a := range allow {
+		if a == p {
+			return true
+		}
+	}
+	return false
+}
+
 // Helpers
 func UserIDFromCtx(r *http.Request) (int64, bool) {
 	v := r.Context().Value(ctxUserID)
@@ -51,3 +65,9 @@ func RoleFromCtx(r *http.Request) (string, bool) {
 	s, ok := v.(string)
 	return s, ok
 }
+
+// PurposeFromCtx is "" for access tokens (see auth.Purpose*).
+func PurposeFromCtx(r *http.Request) string {
+	s, _ := r.Context().Value(ctxPurpose).(string)
+	return s
+}
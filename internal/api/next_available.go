@@ -0,0 +1,190 @@
+package api
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/justanamir/medappoint/internal/db/gen"
+	"github.com/justanamir/medappoint/internal/slots"
+	"golang.org/x/sync/singleflight"
+)
+
+// NextAvailable finds a provider's soonest bookable slot for a service and
+// caches it in memory. Writes that free or take time (bookings,
+// cancellations, resource and provider-service changes) must call
+// Invalidate or InvalidateAll; the TTL covers availability and blackouts
+// edited straight in the database and other instances' writes. Concurrent
+// misses for the same provider and service share one scan.
+type NextAvailable struct {
+	Horizon time.Duration
+	TTL     time.Duration
+
+	group   singleflight.Group
+	mu      sync.Mutex
+	version uint64 // bumped on every invalidation
+	entries map[nextKey]nextEntry
+}
+
+type nextKey struct{ providerID, serviceID int64 }
+
+type nextEntry struct {
+	at      *time.Time // nil: nothing within the horizon
+	expires time.Time
+}
+
+func NewNextAvailable(horizonDays, ttlSeconds int) *NextAvailable {
+	return &NextAvailable{
+		Horizon: time.Duration(horizonDays) * 24 * time.Hour,
+		TTL:     time.Duration(ttlSeconds) * time.Second,
+		entries: map[nextKey]nextEntry{},
+	}
+}
+
+// Invalidate drops every cached answer for the provider. Safe on nil.
+func (n *NextAvailable) Invalidate(providerID int64) {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.version++
+	for k := range n.entries {
+		if k.providerID == providerID {
+			delete(n.entries, k)
+		}
+	}
+}
+
+// InvalidateAll drops the whole cache, e.g. when shared rooms change.
+func (n *NextAvailable) InvalidateAll() {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.version++
+	clear(n.entries)
+}
+
+// Lookup returns the provider's next free start for the service, or nil if
+// there is none within the horizon.
+func (n *NextAvailable) Lookup(ctx context.Context, q *gen.Queries, providerID, clinicID, serviceID int64, durationMin int32, now time.Time) (*time.Time, error) {
+	key := nextKey{providerID, serviceID}
+	n.mu.Lock()
+	e, hit := n.entries[key]
+	version := n.version
+	n.mu.Unlock()
+	if hit && now.Before(e.expires) {
+		return e.at, nil
+	}
+
+	sfKey := strconv.FormatInt(providerID, 10) + "/" + strconv.FormatInt(serviceID, 10)
+	v, err, _ := n.group.Do(sfKey, func() (interface{}, error) {
+		// shared by every waiting caller, so one giving up mustn't cancel it
+		ctx := context.WithoutCancel(ctx)
+		win, err := bookingWindow(ctx, q, serviceID)
+		if err != nil {
+			return nil, err
+		}
+		at, err := n.scan(ctx, q, providerID, clinicID, serviceID, durationMin, win, now)
+		if err != nil {
+			return nil, err
+		}
+		expires := now.Add(n.TTL)
+		if at != nil {
+			// lead time or the same-day cutoff may close the slot before the TTL
+			if closes := win.ClosesAt(*at, now.Location()); closes.Before(expires) {
+				expires = closes
+			}
+		}
+		n.mu.Lock()
+		// skip the store if something was invalidated while we were scanning
+		if n.version == version {
+			n.entries[key] = nextEntry{at: at, expires: expires}
+		}
+		n.mu.Unlock()
+		return at, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*time.Time), nil
+}
+
+// scan loads the provider's weekly availability, closed days and bookings
+// for the whole horizon up front, then walks forward a day at a time until
+// it finds a slot or passes the horizon or the booking window. Only days
+// with a candidate start go back to the database, for the resource pool.
+func (n *NextAvailable) scan(ctx context.Context, q *gen.Queries, providerID, clinicID, serviceID int64, durationMin int32, win slots.Window, now time.Time) (*time.Time, error) {
+	kinds, err := q.ListServiceResourceKinds(ctx, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	loc := now.Location()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	end := now.Add(n.Horizon)
+	if win.MaxAdvanceDays > 0 {
+		if last := day.AddDate(0, 0, win.MaxAdvanceDays+1); last.Before(end) {
+			end = last
+		}
+	}
+
+	avails, err := q.ListAvailabilitiesByProvider(ctx, providerID)
+	if err != nil || len(avails) == 0 {
+		return nil, err
+	}
+	byWeekday := map[int][]slots.AvailWindow{}
+	for _, a := range avails {
+		wd := int(a.Weekday)
+		byWeekday[wd] = append(byWeekday[wd], slots.AvailWindow{StartHHMM: a.StartHhmm, EndHHMM: a.EndHhmm})
+	}
+	blackouts, err := q.ListBlackoutsForProvider(ctx, gen.ListBlackoutsForProviderParams{
+		ProviderID: pgtype.Int8{Int64: providerID, Valid: true},
+		ClinicID:   pgtype.Int8{Int64: clinicID, Valid: true},
+		FromDate:   pgtype.Date{Time: time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC), Valid: true},
+		ToDate:     pgtype.Date{Time: time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC), Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	closed := map[string]bool{}
+	for _, b := range blackouts {
+		closed[b.Date.Time.Format("2006-01-02")] = true
+	}
+	appts, err := q.ListProviderAppointmentsOnDate(ctx, gen.ListProviderAppointmentsOnDateParams{
+		ProviderID:  providerID,
+		StartTime:   day,
+		StartTime_2: end,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for ; day.Before(end); day = day.AddDate(0, 0, 1) {
+		next := day.AddDate(0, 0, 1)
+		// weekday mapping: our DB uses 1=Mon ... 7=Sun; Go uses 0=Sun ... 6=Sat
+		dbWD := ((int(day.Weekday()) + 6) % 7) + 1
+		av := byWeekday[dbWD]
+		if len(av) == 0 || closed[day.Format("2006-01-02")] {
+			continue
+		}
+		var booked []slots.BookedRange
+		for _, a := range appts {
+			if a.StartTime.Before(next) && a.EndTime.After(day) {
+				booked = append(booked, slots.BookedRange{Start: a.StartTime.In(loc), End: a.EndTime.In(loc)})
+			}
+		}
+		starts, err := freeStarts(ctx, q, clinicID, durationMin, kinds, win, day, loc, now, av, booked)
+		if err != nil {
+			return nil, err
+		}
+		// starts are in order
+		if len(starts) > 0 && starts[0].Before(end) {
+			return &starts[0], nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/justanamir/medappoint/internal/auth"
+	"github.com/justanamir/medappoint/internal/config"
+	"github.com/justanamir/medappoint/internal/db/gen"
+	"github.com/justanamir/medappoint/internal/oidc"
+)
+
+type OIDCDeps struct {
+	Cfg    config.Config
+	Q      *gen.Queries
+	Keys   *auth.KeySet
+	OIDC   *oidc.Client
+	States *oidc.StateStore
+}
+
+// GET /v1/auth/oidc/login
+// Redirects staff to the IdP (authorization code + PKCE).
+func (d OIDCDeps) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	authURL, pending, err := d.OIDC.AuthURL(r.Context())
+	if err != nil {
+		ErrorJSON(w, http.StatusBadGateway, "identity provider unavailable", nil)
+		return
+	}
+	d.States.Put(pending)
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// GET /v1/auth/oidc/callback?code=...&state=...
+// Maps the IdP identity to an existing staff user and issues our own token,
+// or an MFA challenge/enrollment token exactly as password login does.
+func (d OIDCDeps) CallbackHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		ErrorJSON(w, http.StatusUnauthorized, "login failed at identity provider", e)
+		return
+	}
+	pending, ok := d.States.Take(q.Get("state"))
+	if !ok {
+		ErrorJSON(w, http.StatusBadRequest, "invalid or expired login state", nil)
+		return
+	}
+	code := q.Get("code")
+	if code == "" {
+		ErrorJSON(w, http.StatusBadRequest, "missing code", nil)
+		return
+	}
+
+	ctx := r.Context()
+	claims, err := d.OIDC.Exchange(ctx, code, pending)
+	if err != nil {
+		ErrorJSON(w, http.StatusUnauthorized, "invalid identity token", nil)
+		return
+	}
+
+	// 1) already linked by (issuer, subject)
+	iss, sub := claims.Issuer, claims.Subject
+	u, err := d.Q.GetUserByOIDCSubject(ctx, gen.GetUserByOIDCSubjectParams{OidcIssuer: &iss, OidcSubject: &sub})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// 2) first login: link by verified email to a pre-created staff user
+		email := strings.TrimSpace(strings.ToLower(claims.Email))
+		if email == "" || !claims.EmailVerified {
+			ErrorJSON(w, http.StatusForbidden, "no staff account linked to this identity", nil)
+			return
+		}
+		u, err = d.Q.GetUserByEmail(ctx, email)
+		if err != nil {
+			ErrorJSON(w, http.StatusForbidden, "no staff account linked to this identity", nil)
+			return
+		}
+		if u.OidcSubject != nil {
+			// the account is bound to a different IdP identity
+			ErrorJSON(w, http.StatusForbidden, "no staff account linked to this identity", nil)
+			return
+		}
+		if staff, err := isStaff(ctx, d.Q, u.ID); err != nil || !staff {
+			ErrorJSON(w, http.StatusForbidden, "single sign-on is for staff accounts only", nil)
+			return
+		}
+		u, err = d.Q.LinkUserOIDC(ctx, gen.LinkUserOIDCParams{ID: u.ID, OidcIssuer: &iss, OidcSubject: &sub})
+	}
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to load user", nil)
+		return
+	}
+	staff, err := isStaff(ctx, d.Q, u.ID)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to load permissions", nil)
+		return
+	}
+	if !staff {
+		ErrorJSON(w, http.StatusForbidden, "single sign-on is for staff accounts only", nil)
+		return
+	}
+
+	// the IdP vouches for the first factor only; our own MFA still applies
+	issueSession(w, r, d.Q, d.Keys, d.Cfg, u, http.StatusOK)
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/justanamir/medappoint/internal/db/gen"
+	"github.com/justanamir/medappoint/internal/rbac"
+)
+
+type PatientDeps struct {
+	Q *gen.Queries
+}
+
+// GET /v1/admin/patients?name=&phone=&dob=YYYY-MM-DD&limit=20
+// Front-desk lookup; at least one filter is required. Staff with
+// patients:read in only some clinics see the patients with appointments
+// there; those with it everywhere (admins) search all patients.
+func (d PatientDeps) SearchPatientsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var p gen.SearchPatientsParams
+
+	if s := strings.TrimSpace(q.Get("name")); s != "" {
+		if len(s) < 2 {
+			ErrorJSON(w, http.StatusBadRequest, "name must be at least 2 characters", nil)
+			return
+		}
+		s = likeEscaper.Replace(s)
+		p.Name = &s
+	}
+	if s := q.Get("phone"); s != "" {
+		phone, err := normalizePhone(s)
+		if err != nil {
+			ErrorJSON(w, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		p.Phone = &phone
+	}
+	if s := q.Get("dob"); s != "" {
+		dob, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			ErrorJSON(w, http.StatusBadRequest, "dob must be YYYY-MM-DD", nil)
+			return
+		}
+		// canonical form, so it matches the stored blind index
+		ds := dob.Format("2006-01-02")
+		p.Dob = &ds
+	}
+	if p.Name == nil && p.Phone == nil && p.Dob == nil {
+		ErrorJSON(w, http.StatusBadRequest, "at least one of name, phone, dob is required", nil)
+		return
+	}
+	p.ClinicIds = GrantsFromCtx(r).Scope(rbac.PatientsRead).Filter()
+	p.RowLimit = 20
+	if s := q.Get("limit"); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n > 0 && n <= 100 {
+			p.RowLimit = int32(n)
+		}
+	}
+
+	rows, err := d.Q.SearchPatients(r.Context(), p)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to search patients", nil)
+		return
+	}
+	patientIDs := make([]int64, 0, len(rows))
+	for _, p := range rows {
+		patientIDs = append(patientIDs, p.ID)
+	}
+	auditPatients(r, d.Q, auditEvent{Action: AuditPatientSearch, ResourceType: AuditResPatient}, patientIDs)
+
+	if rows == nil {
+		rows = []gen.SearchPatientsRow{}
+	}
+	JSON(w, http.StatusOK, rows)
+}
+
+// ---- validation helpers ----
+
+var (
+	e164Re      = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+	likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+)
+
+// normalizePhone strips common separators and requires E.164, e.g. "+60 12-345 6789" => "+60123456789".
+func normalizePhone(s string) (string, error) {
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '(', ')', '.':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(s))
+	if !e164Re.MatchString(s) {
+		return "", errors.New("phone must be in E.164 format, e.g. +60123456789")
+	}
+	return s, nil
+}
+
+// parseDOB accepts YYYY-MM-DD that is not in the future and at most 130 years ago.
+func parseDOB(s string, now time.Time) (time.Time, error) {
+	dob, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, errors.New("dob must be YYYY-MM-DD")
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if dob.After(today) {
+		return time.Time{}, errors.New("dob cannot be in the future")
+	}
+	if dob.Before(today.AddDate(-130, 0, 0)) {
+		return time.Time{}, errors.New("dob is not plausible")
+	}
+	return dob, nil
+}
@@ -0,0 +1,452 @@
+package api
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/justanamir/medappoint/internal/db/gen"
+	"github.com/justanamir/medappoint/internal/rbac"
+	"github.com/justanamir/medappoint/internal/storage"
+)
+
+// PrivacyDeps serves data-subject rights: export (access/portability) and
+// erasure.
+type PrivacyDeps struct {
+	Q     *gen.Queries
+	Store storage.Store // attachment objects are removed on erasure
+}
+
+type exportUser struct {
+	ID        int64     `json:"id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	SSOLinked bool      `json:"sso_linked"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// exportIntake is an intake response with its answers as JSON rather than
+// an escaped string.
+type exportIntake struct {
+	AppointmentID int64           `json:"appointment_id"`
+	FormID        int64           `json:"form_id"`
+	FormVersion   int32           `json:"form_version"`
+	Answers       json.RawMessage `json:"answers"`
+	SubmittedAt   time.Time       `json:"submitted_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+type dataExport struct {
+	ExportedAt   time.Time                              `json:"exported_at"`
+	User         exportUser                             `json:"user"`
+	Patients     []gen.Patient                          `json:"patients"`
+	Dependents   []gen.ListDependentsRow                `json:"dependents"`
+	Appointments []gen.ListAppointmentsByPatientsRow    `json:"appointments"`
+	Intake       []exportIntake                         `json:"intake_responses"`
+	Attachments  []gen.ListAttachmentsByAppointmentsRow `json:"attachments"`
+}
+
+// exportPageSize is how many appointments are read per query while building
+// an export.
+const exportPageSize = 500
+
+// GET /v1/me/export?format=json|zip
+// Everything we hold about the caller and their dependents: account, patient
+// profiles, all appointments including notes, intake answers, and the
+// metadata of uploaded attachments (the files are downloaded one by one).
+// The password hash and MFA secrets are never exported.
+func (d PrivacyDeps) ExportHandler(w http.ResponseWriter, r *http.Request) {
+	uid, ok := UserIDFromCtx(r)
+	if !ok || uid <= 0 {
+		ErrorJSON(w, http.StatusUnauthorized, "unauthorized", nil)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "zip" {
+		ErrorJSON(w, http.StatusBadRequest, "format must be json or zip", nil)
+		return
+	}
+
+	ctx := r.Context()
+	u, err := d.Q.GetUserByID(ctx, uid)
+	if err != nil {
+		ErrorJSON(w, http.StatusNotFound, "user not found", nil)
+		return
+	}
+	exp := dataExport{
+		ExportedAt: time.Now().UTC(),
+		User: exportUser{
+			ID:        u.ID,
+			Email:     u.Email,
+			Role:      u.Role,
+			SSOLinked: u.OidcSubject != nil,
+			CreatedAt: u.CreatedAt,
+			UpdatedAt: u.UpdatedAt,
+		},
+		Patients:     []gen.Patient{},
+		Dependents:   []gen.ListDependentsRow{},
+		Appointments: []gen.ListAppointmentsByPatientsRow{},
+		Intake:       []exportIntake{},
+		Attachments:  []gen.ListAttachmentsByAppointmentsRow{},
+	}
+
+	patientIDs, err := d.Q.ListManagedPatientIDs(ctx, uid)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to load patient profiles", nil)
+		return
+	}
+	if len(patientIDs) > 0 {
+		if exp.Patients, err = d.Q.ListPatientsByIDs(ctx, patientIDs); err != nil {
+			ErrorJSON(w, http.StatusInternalServerError, "failed to load patient profiles", nil)
+			return
+		}
+		deps, err := d.Q.ListDependents(ctx, uid)
+		if err != nil {
+			ErrorJSON(w, http.StatusInternalServerError, "failed to load dependents", nil)
+			return
+		}
+		if deps != nil {
+			exp.Dependents = deps
+		}
+		if exp.Appointments, err = d.allAppointments(r, patientIDs); err != nil {
+			ErrorJSON(w, http.StatusInternalServerError, "failed to load appointments", nil)
+			return
+		}
+		apptIDs := make([]int64, 0, len(exp.Appointments))
+		for _, a := range exp.Appointments {
+			apptIDs = append(apptIDs, a.ID)
+		}
+		intake, err := d.Q.ListIntakeResponsesByAppointments(ctx, apptIDs)
+		if err != nil {
+			ErrorJSON(w, http.StatusInternalServerError, "failed to load intake responses", nil)
+			return
+		}
+		for _, ir := range intake {
+			exp.Intake = append(exp.Intake, exportIntake{
+				AppointmentID: ir.AppointmentID,
+				FormID:        ir.FormID,
+				FormVersion:   ir.FormVersion,
+				Answers:       json.RawMessage(ir.Answers),
+				SubmittedAt:   ir.SubmittedAt,
+				UpdatedAt:     ir.UpdatedAt,
+			})
+		}
+		files, err := d.Q.ListAttachmentsByAppointments(ctx, apptIDs)
+		if err != nil {
+			ErrorJSON(w, http.StatusInternalServerError, "failed to load attachments", nil)
+			return
+		}
+		if files != nil {
+			exp.Attachments = files
+		}
+	}
+	auditPatients(r, d.Q, auditEvent{Action: AuditPatientExport, ResourceType: AuditResPatient}, patientIDs)
+
+	name := fmt.Sprintf("medappoint-export-%d-%s", uid, exp.ExportedAt.Format("20060102"))
+	if format == "zip" {
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.zip"`)
+		_ = writeExportZip(w, exp)
+		return
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.json"`)
+	JSON(w, http.StatusOK, exp)
+}
+
+func (d PrivacyDeps) allAppointments(r *http.Request, patientIDs []int64) ([]gen.ListAppointmentsByPatientsRow, error) {
+	out := []gen.ListAppointmentsByPatientsRow{}
+	p := gen.ListAppointmentsByPatientsParams{PatientIds: patientIDs, NewestFirst: true, RowLimit: exportPageSize}
+	for {
+		rows, err := d.Q.ListAppointmentsByPatients(r.Context(), p)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rows...)
+		if len(rows) < exportPageSize {
+			return out, nil
+		}
+		last := rows[len(rows)-1]
+		p.CursorTime = pgtype.Timestamptz{Time: last.StartTime, Valid: true}
+		p.CursorID = last.ID
+	}
+}
+
+// writeExportZip writes one JSON file per section.
+func writeExportZip(w io.Writer, exp dataExport) error {
+	zw := zip.NewWriter(w)
+	files := []struct {
+		name string
+		v    interface{}
+	}{
+		{"account.json", struct {
+			ExportedAt time.Time  `json:"exported_at"`
+			User       exportUser `json:"user"`
+		}{exp.ExportedAt, exp.User}},
+		{"patients.json", exp.Patients},
+		{"dependents.json", exp.Dependents},
+		{"appointments.json", exp.Appointments},
+		{"intake_responses.json", exp.Intake},
+		{"attachments.json", exp.Attachments},
+	}
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: exp.ExportedAt})
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.v); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+type erasureReq struct {
+	PatientID *int64 `json:"patient_id"` // omit to erase your own account; or a dependent's id
+	Reason    string `json:"reason"`
+}
+
+// POST /v1/me/erasure-requests
+// Files a request that an admin must approve. Without patient_id it covers
+// the caller's account and own patient profile; with a dependent's
+// patient_id it covers only that dependent. An account that is the only
+// guardian of login-less dependents gets 409 until those are dealt with.
+func (d PrivacyDeps) RequestErasureHandler(w http.ResponseWriter, r *http.Request) {
+	uid, ok := UserIDFromCtx(r)
+	if !ok || uid <= 0 {
+		ErrorJSON(w, http.StatusUnauthorized, "unauthorized", nil)
+		return
+	}
+	var req erasureReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		ErrorJSON(w, http.StatusBadRequest, "invalid json", nil)
+		return
+	}
+
+	ctx := r.Context()
+	p := gen.CreateErasureRequestParams{UserID: uid}
+	own, err := d.Q.GetPatientByUserID(ctx, uid)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to load patient profile", nil)
+		return
+	}
+	hasOwn := err == nil
+
+	switch {
+	case req.PatientID == nil || (hasOwn && *req.PatientID == own.ID):
+		// staff accounts are closed by an administrator, not self-service
+		if role, _ := RoleFromCtx(r); role != "patient" {
+			ErrorJSON(w, http.StatusForbidden, "only patient accounts can request erasure", nil)
+			return
+		}
+		// dependents without a login would be left with nobody to manage,
+		// export or erase them
+		n, err := d.Q.CountSoleDependents(ctx, uid)
+		if err != nil {
+			ErrorJSON(w, http.StatusInternalServerError, "failed to load dependents", nil)
+			return
+		}
+		if n > 0 {
+			ErrorJSON(w, http.StatusConflict, "request erasure of your dependents, or have another guardian added, before closing your account", map[string]int64{"sole_dependents": n})
+			return
+		}
+		p.EraseAccount = true
+		if hasOwn {
+			p.PatientID = pgtype.Int8{Int64: own.ID, Valid: true}
+		}
+	default:
+		deps, err := d.Q.ListDependents(ctx, uid)
+		if err != nil {
+			ErrorJSON(w, http.StatusInternalServerError, "failed to load dependents", nil)
+			return
+		}
+		found := false
+		for _, dep := range deps {
+			// a dependent with their own login must ask themselves
+			if dep.ID == *req.PatientID && !dep.UserID.Valid {
+				found = true
+			}
+		}
+		if !found {
+			ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
+			return
+		}
+		p.PatientID = pgtype.Int8{Int64: *req.PatientID, Valid: true}
+	}
+	if s := strings.TrimSpace(req.Reason); s != "" {
+		if len(s) > 1000 {
+			ErrorJSON(w, http.StatusUnprocessableEntity, "reason must be at most 1000 characters", nil)
+			return
+		}
+		p.Reason = &s
+	}
+
+	row, err := d.Q.CreateErasureRequest(ctx, p)
+	if isUniqueViolation(err) {
+		ErrorJSON(w, http.StatusConflict, "an erasure request is already pending", nil)
+		return
+	}
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to create erasure request", nil)
+		return
+	}
+	audit(r, d.Q, auditEvent{
+		Action:       AuditErasureRequest,
+		ResourceType: AuditResErasureRequest,
+		ResourceID:   row.ID,
+		PatientID:    row.PatientID.Int64,
+	})
+
+	JSON(w, http.StatusAccepted, row)
+}
+
+// GET /v1/admin/erasure-requests?status=pending|rejected|completed&before_id=&limit=
+func (d PrivacyDeps) ListErasureRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	// erasure spans every clinic, so only global holders may review it
+	if !GrantsFromCtx(r).Scope(rbac.PatientsErase).All {
+		ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
+		return
+	}
+	q := r.URL.Query()
+	p := gen.ListErasureRequestsParams{RowLimit: 50}
+	if s := q.Get("status"); s != "" {
+		p.Status = &s
+	}
+	if s := q.Get("before_id"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n <= 0 {
+			ErrorJSON(w, http.StatusBadRequest, "invalid before_id", nil)
+			return
+		}
+		p.BeforeID = pgtype.Int8{Int64: n, Valid: true}
+	}
+	if s := q.Get("limit"); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n > 0 && n <= 200 {
+			p.RowLimit = int32(n)
+		}
+	}
+	rows, err := d.Q.ListErasureRequests(r.Context(), p)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to list erasure requests", nil)
+		return
+	}
+	if rows == nil {
+		rows = []gen.ListErasureRequestsRow{}
+	}
+	JSON(w, http.StatusOK, rows)
+}
+
+type erasureReviewReq struct {
+	Note string `json:"note"`
+}
+
+// POST /v1/admin/erasure-requests/{id}/approve   body (optional): {"note": "..."}
+// Anonymizes the subject immediately. This cannot be undone. Refused (409)
+// while the account is the only guardian of login-less dependents.
+func (d PrivacyDeps) ApproveErasureHandler(w http.ResponseWriter, r *http.Request) {
+	uid, id, note, ok := d.review(w, r)
+	if !ok {
+		return
+	}
+	row, err := d.Q.ExecuteErasureRequest(r.Context(), gen.ExecuteErasureRequestParams{
+		ReviewedBy: uid,
+		ReviewNote: note,
+		ID:         id,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// dependents may have been added since the request was filed
+		ErrorJSON(w, http.StatusConflict, "erasure request not found, not pending, or the account is still the only guardian of dependents", nil)
+		return
+	}
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to erase patient data", nil)
+		return
+	}
+	// the rows are gone; a failed object delete only leaves an orphan blob
+	for _, key := range row.AttachmentKeys {
+		if err := d.Store.Delete(r.Context(), key); err != nil {
+			slog.Error("erasure: attachment delete failed", "key", key, "err", err)
+		}
+	}
+	row.AttachmentKeys = nil
+	audit(r, d.Q, auditEvent{
+		Action:       AuditPatientErase,
+		ResourceType: AuditResErasureRequest,
+		ResourceID:   row.ID,
+		PatientID:    row.PatientID.Int64,
+	})
+
+	JSON(w, http.StatusOK, row)
+}
+
+// POST /v1/admin/erasure-requests/{id}/reject   body (optional): {"note": "..."}
+func (d PrivacyDeps) RejectErasureHandler(w http.ResponseWriter, r *http.Request) {
+	uid, id, note, ok := d.review(w, r)
+	if !ok {
+		return
+	}
+	row, err := d.Q.RejectErasureRequest(r.Context(), gen.RejectErasureRequestParams{
+		ReviewedBy: uid,
+		ReviewNote: note,
+		ID:         id,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		ErrorJSON(w, http.StatusConflict, "erasure request not found or not pending", nil)
+		return
+	}
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to reject erasure request", nil)
+		return
+	}
+	audit(r, d.Q, auditEvent{
+		Action:       AuditErasureReject,
+		ResourceType: AuditResErasureRequest,
+		ResourceID:   row.ID,
+		PatientID:    row.PatientID.Int64,
+	})
+
+	JSON(w, http.StatusOK, row)
+}
+
+// review does the checks shared by approve and reject, writing the error
+// response itself.
+func (d PrivacyDeps) review(w http.ResponseWriter, r *http.Request) (uid, id int64, note *string, ok bool) {
+	uid, _ = UserIDFromCtx(r)
+	if !GrantsFromCtx(r).Scope(rbac.PatientsErase).All {
+		ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
+		return 0, 0, nil, false
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		ErrorJSON(w, http.StatusBadRequest, "invalid erasure request id", nil)
+		return 0, 0, nil, false
+	}
+	var req erasureReviewReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		ErrorJSON(w, http.StatusBadRequest, "invalid json", nil)
+		return 0, 0, nil, false
+	}
+	if s := strings.TrimSpace(req.Note); s != "" {
+		note = &s
+	}
+	return uid, id, note, true
+}
+
+// isUniqueViolation reports whether err is a Postgres unique_violation.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
@@ -1,21 +1,235 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/justanamir/medappoint/internal/db/gen"
+	"github.com/justanamir/medappoint/internal/rbac"
 )
 
 type ProviderDeps struct {
-	Q *gen.Queries
+	Q    *gen.Queries
+	Next *NextAvailable
 }
 
-// ListProvidersHandler handles GET /v1/providers
+// ListProvidersHandler handles
+// GET /v1/providers?service_id=&clinic_id=&speciality=&q=&limit=&cursor=
+// With service_id only providers offering that service are returned, each
+// with their duration_min and next_available slot for it (null when nothing
+// is free within the horizon, or when it couldn't be worked out). q searches
+// the name, see gen.ListProviders.
 func (d ProviderDeps) ListProvidersHandler(w http.ResponseWriter, r *http.Request) {
-	providers, err := d.Q.ListProviders(r.Context())
+	serviceID, ok1 := queryID(r, "service_id")
+	clinicID, ok2 := queryID(r, "clinic_id")
+	if !ok1 || !ok2 {
+		ErrorJSON(w, http.StatusBadRequest, "invalid service_id or clinic_id", nil)
+		return
+	}
+	afterID, limit, err := idPageParams(r)
+	if err != nil {
+		ErrorJSON(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	rows, err := d.Q.ListProviders(r.Context(), gen.ListProvidersParams{
+		ServiceID:  serviceID,
+		ClinicID:   clinicID,
+		Speciality: queryText(r, "speciality"),
+		Q:          queryText(r, "q"),
+		AfterID:    afterID,
+		RowLimit:   limit + 1,
+	})
 	if err != nil {
 		ErrorJSON(w, http.StatusInternalServerError, "failed to list providers", nil)
 		return
 	}
-	JSON(w, http.StatusOK, providers)
+	p := idPage(rows, limit, func(p gen.ListProvidersRow) int64 { return p.ID })
+
+	resp := page[providerListItem]{Items: make([]providerListItem, len(p.Items)), NextCursor: p.NextCursor}
+	loc, _ := time.LoadLocation("Asia/Kuala_Lumpur")
+	now := time.Now().In(loc)
+	for i, row := range p.Items {
+		resp.Items[i].ListProvidersRow = row
+		if !serviceID.Valid || d.Next == nil {
+			continue
+		}
+		at, err := d.Next.Lookup(r.Context(), d.Q, row.ID, row.ClinicID, serviceID.Int64, row.DurationMin.Int32, now)
+		if err != nil {
+			// one provider's bad data shouldn't hide the rest of the list
+			slog.Warn("next available lookup failed", "provider_id", row.ID, "service_id", serviceID.Int64, "err", err)
+			continue
+		}
+		resp.Items[i].NextAvailable = at
+	}
+	JSON(w, http.StatusOK, resp)
+}
+
+type providerListItem struct {
+	gen.ListProvidersRow
+	NextAvailable *time.Time `json:"next_available"`
+}
+
+type providerView struct {
+	ID         int64      `json:"id"`
+	FullName   string     `json:"full_name"`
+	Speciality string     `json:"speciality"`
+	Clinic     gen.Clinic `json:"clinic"`
+}
+
+// GET /v1/providers/{id}
+func (d ProviderDeps) GetProviderHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		ErrorJSON(w, http.StatusBadRequest, "invalid provider id", nil)
+		return
+	}
+	prov, err := d.Q.GetProvider(r.Context(), id)
+	if err != nil {
+		ErrorJSON(w, http.StatusNotFound, "provider not found", nil)
+		return
+	}
+	clinic, err := d.Q.GetClinic(r.Context(), prov.ClinicID)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to load clinic", nil)
+		return
+	}
+	JSON(w, http.StatusOK, providerView{
+		ID:         prov.ID,
+		FullName:   prov.FullName,
+		Speciality: prov.Speciality,
+		Clinic:     clinic,
+	})
+}
+
+// GET /v1/providers/{id}/services
+func (d ProviderDeps) ListServicesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		ErrorJSON(w, http.StatusBadRequest, "invalid provider id", nil)
+		return
+	}
+	if _, err := d.Q.GetProvider(r.Context(), id); err != nil {
+		ErrorJSON(w, http.StatusNotFound, "provider not found", nil)
+		return
+	}
+	rows, err := d.Q.ListProviderServices(r.Context(), id)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to list provider services", nil)
+		return
+	}
+	if rows == nil {
+		rows = []gen.ListProviderServicesRow{}
+	}
+	JSON(w, http.StatusOK, rows)
+}
+
+type providerServiceReq struct {
+	DurationMin *int32 `json:"duration_min"` // null/omitted = use the service's default
+}
+
+// PUT /v1/admin/providers/{id}/services/{serviceID}   body (optional): {"duration_min": 45}
+// Lets the provider take bookings for the service.
+func (d ProviderDeps) PutServiceHandler(w http.ResponseWriter, r *http.Request) {
+	prov, svc, ok := d.loadPair(w, r)
+	if !ok {
+		return
+	}
+	if svc.ClinicID != prov.ClinicID {
+		ErrorJSON(w, http.StatusUnprocessableEntity, "service belongs to another clinic", nil)
+		return
+	}
+	var req providerServiceReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		ErrorJSON(w, http.StatusBadRequest, "invalid json", nil)
+		return
+	}
+	var dur pgtype.Int4
+	if req.DurationMin != nil {
+		if *req.DurationMin <= 0 || *req.DurationMin > 24*60 {
+			ErrorJSON(w, http.StatusUnprocessableEntity, "duration_min must be between 1 and 1440", nil)
+			return
+		}
+		dur = pgtype.Int4{Int32: *req.DurationMin, Valid: true}
+	}
+	row, err := d.Q.UpsertProviderService(r.Context(), gen.UpsertProviderServiceParams{
+		ProviderID:  prov.ID,
+		ServiceID:   svc.ID,
+		DurationMin: dur,
+	})
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to save provider service", nil)
+		return
+	}
+	d.Next.Invalidate(prov.ID)
+	JSON(w, http.StatusOK, row)
+}
+
+// DELETE /v1/admin/providers/{id}/services/{serviceID}
+// Existing appointments are kept; only new bookings are refused.
+func (d ProviderDeps) DeleteServiceHandler(w http.ResponseWriter, r *http.Request) {
+	prov, svc, ok := d.loadPair(w, r)
+	if !ok {
+		return
+	}
+	n, err := d.Q.DeleteProviderService(r.Context(), gen.DeleteProviderServiceParams{
+		ProviderID: prov.ID,
+		ServiceID:  svc.ID,
+	})
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to remove provider service", nil)
+		return
+	}
+	if n == 0 {
+		ErrorJSON(w, http.StatusNotFound, "provider does not offer this service", nil)
+		return
+	}
+	d.Next.Invalidate(prov.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadPair resolves {id} and {serviceID} and checks catalog:write in the
+// provider's clinic.
+func (d ProviderDeps) loadPair(w http.ResponseWriter, r *http.Request) (gen.Provider, gen.Service, bool) {
+	pid, err1 := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	sid, err2 := strconv.ParseInt(chi.URLParam(r, "serviceID"), 10, 64)
+	if err1 != nil || err2 != nil || pid <= 0 || sid <= 0 {
+		ErrorJSON(w, http.StatusBadRequest, "invalid provider or service id", nil)
+		return gen.Provider{}, gen.Service{}, false
+	}
+	prov, err := d.Q.GetProvider(r.Context(), pid)
+	if err != nil {
+		ErrorJSON(w, http.StatusNotFound, "provider not found", nil)
+		return gen.Provider{}, gen.Service{}, false
+	}
+	if !GrantsFromCtx(r).Can(rbac.CatalogWrite, prov.ClinicID) {
+		ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
+		return gen.Provider{}, gen.Service{}, false
+	}
+	svc, err := d.Q.GetService(r.Context(), sid)
+	if err != nil {
+		ErrorJSON(w, http.StatusNotFound, "service not found", nil)
+		return gen.Provider{}, gen.Service{}, false
+	}
+	return prov, svc, true
+}
+
+// serviceDuration is how long svc takes with providerID; ok is false when
+// the provider doesn't offer it.
+func serviceDuration(r *http.Request, q *gen.Queries, providerID, serviceID int64) (minutes int32, ok bool, err error) {
+	minutes, err = q.GetProviderServiceDuration(r.Context(), gen.GetProviderServiceDurationParams{
+		ProviderID: providerID,
+		ServiceID:  serviceID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	return minutes, err == nil, err
 }
@@ -1,13 +1,20 @@
 package api
 
 import (
+	"encoding/json"
+	"fmt"
+	"math"
 	"net/http"
+	"sort"
 	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/justanamir/medappoint/internal/config"
 	"github.com/justanamir/medappoint/internal/db/gen"
+	"github.com/justanamir/medappoint/internal/rbac"
+	"github.com/justanamir/medappoint/internal/slots"
 )
 
 type ProviderScheduleDeps struct {
@@ -15,14 +22,18 @@ type ProviderScheduleDeps struct {
 	Q   *gen.Queries
 }
 
-// GET /v1/providers/{id}/appointments?date=YYYY-MM-DD
+// GET /v1/providers/{id}/appointments?date=YYYY-MM-DD[&format=json|csv|xlsx]
+// CSV and XLSX leave out notes and intake answers.
 func (d ProviderScheduleDeps) ListProviderDayAppointments(w http.ResponseWriter, r *http.Request) {
 	uid, ok := UserIDFromCtx(r)
 	if !ok || uid <= 0 {
 		ErrorJSON(w, http.StatusUnauthorized, "unauthorized", nil)
 		return
 	}
-	role, _ := RoleFromCtx(r)
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
 
 	idStr := chi.URLParam(r, "id")
 	providerID, err := strconv.ParseInt(idStr, 10, 64)
@@ -31,12 +42,14 @@ func (d ProviderScheduleDeps) ListProviderDayAppointments(w http.ResponseWriter,
 		return
 	}
 
-	if role == "provider" {
-		myProv, err := d.Q.GetProviderByUserID(r.Context(), uid)
-		if err != nil || myProv.ID != providerID {
-			ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
-			return
-		}
+	prov, err := d.Q.GetProvider(r.Context(), providerID)
+	if err != nil {
+		ErrorJSON(w, http.StatusNotFound, "provider not found", nil)
+		return
+	}
+	if !canReadSchedule(r, uid, prov) {
+		ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
+		return
 	}
 
 	loc, _ := time.LoadLocation("Asia/Kuala_Lumpur")
@@ -63,6 +76,56 @@ func (d ProviderScheduleDeps) ListProviderDayAppointments(w http.ResponseWriter,
 		ErrorJSON(w, http.StatusInternalServerError, "failed to load provider schedule", nil)
 		return
 	}
+	patientIDs := make([]int64, 0, len(rows))
+	apptIDs := make([]int64, 0, len(rows))
+	for _, a := range rows {
+		patientIDs = append(patientIDs, a.PatientID)
+		apptIDs = append(apptIDs, a.ID)
+	}
+	if format != formatJSON {
+		auditPatients(r, d.Q, auditEvent{
+			Action:       AuditAppointmentExport,
+			ResourceType: AuditResProviderSchedule,
+			ResourceID:   providerID,
+			ClinicID:     prov.ClinicID,
+		}, patientIDs)
+		zone := d.clinicZone(r, prov.ClinicID)
+		t := exportTable{Header: []string{"date", "start", "end", "patient", "service", "status", "appointment_id"}}
+		for _, a := range rows {
+			date, start, end := localTimes(a.StartTime, a.EndTime, zone)
+			t.Rows = append(t.Rows, []string{date, start, end, strOrEmpty(a.PatientName), a.ServiceName, a.Status, itoa(a.ID)})
+		}
+		writeTable(w, format, fmt.Sprintf("provider_%d_%s", providerID, dayStart.Format("20060102")), t)
+		return
+	}
+
+	// attach intake answers so the provider can prepare
+	intake, err := d.Q.ListIntakeResponsesByAppointments(r.Context(), apptIDs)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to load intake answers", nil)
+		return
+	}
+	byAppt := make(map[int64]*intakeView, len(intake))
+	for _, in := range intake {
+		byAppt[in.AppointmentID] = &intakeView{
+			AppointmentID: in.AppointmentID,
+			FormID:        in.FormID,
+			FormVersion:   in.FormVersion,
+			Answers:       json.RawMessage(in.Answers),
+			SubmittedAt:   in.SubmittedAt,
+			UpdatedAt:     in.UpdatedAt,
+		}
+	}
+	items := make([]scheduleAppointment, 0, len(rows))
+	for _, a := range rows {
+		items = append(items, scheduleAppointment{ListAppointmentsByProviderOnDateRow: a, Intake: byAppt[a.ID]})
+	}
+	auditPatients(r, d.Q, auditEvent{
+		Action:       AuditAppointmentList,
+		ResourceType: AuditResProviderSchedule,
+		ResourceID:   providerID,
+		ClinicID:     prov.ClinicID,
+	}, patientIDs)
 
 	JSON(w, http.StatusOK, struct {
 		ProviderID   int64       `json:"provider_id"`
@@ -71,6 +134,249 @@ func (d ProviderScheduleDeps) ListProviderDayAppointments(w http.ResponseWriter,
 	}{
 		ProviderID:   providerID,
 		Date:         dayStart.Format("2006-01-02"),
-		Appointments: rows,
+		Appointments: items,
+	})
+}
+
+// maxScheduleDays bounds GET /v1/providers/{id}/schedule (a month view plus
+// the spill-over weeks around it).
+const maxScheduleDays = 42
+
+type scheduleSegment struct {
+	Start         time.Time `json:"start"`
+	End           time.Time `json:"end"`
+	Type          string    `json:"type"` // free | booked
+	AppointmentID int64     `json:"appointment_id,omitempty"`
+	Status        string    `json:"status,omitempty"`
+	PatientName   *string   `json:"patient_name,omitempty"`
+	ServiceName   string    `json:"service_name,omitempty"`
+}
+
+type scheduleDay struct {
+	Date             string            `json:"date"`
+	Blackout         bool              `json:"blackout"`
+	BlackoutReason   *string           `json:"blackout_reason,omitempty"`
+	AvailableMinutes int               `json:"available_minutes"`
+	BookedMinutes    int               `json:"booked_minutes"`
+	Utilization      *float64          `json:"utilization"` // percent; null with no availability
+	Segments         []scheduleSegment `json:"segments"`
+}
+
+// GET /v1/providers/{id}/schedule?from=YYYY-MM-DD&to=YYYY-MM-DD[&format=json|csv|xlsx]
+// Day-by-day timeline of free and booked segments with utilization (booked
+// share of the availability windows). to is inclusive; defaults to a week
+// from today. Blacked-out days have no availability, but bookings on them
+// are still listed. Cancelled visits are left out. CSV and XLSX have one
+// row per segment, plus one per blacked-out day.
+func (d ProviderScheduleDeps) ListProviderSchedule(w http.ResponseWriter, r *http.Request) {
+	uid, ok := UserIDFromCtx(r)
+	if !ok || uid <= 0 {
+		ErrorJSON(w, http.StatusUnauthorized, "unauthorized", nil)
+		return
+	}
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+	providerID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || providerID <= 0 {
+		ErrorJSON(w, http.StatusBadRequest, "invalid provider id", nil)
+		return
+	}
+	ctx := r.Context()
+	prov, err := d.Q.GetProvider(ctx, providerID)
+	if err != nil {
+		ErrorJSON(w, http.StatusNotFound, "provider not found", nil)
+		return
+	}
+	if !canReadSchedule(r, uid, prov) {
+		ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
+		return
+	}
+
+	loc, _ := time.LoadLocation("Asia/Kuala_Lumpur")
+	now := time.Now().In(loc)
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	if s := r.URL.Query().Get("from"); s != "" {
+		if from, err = time.ParseInLocation("2006-01-02", s, loc); err != nil {
+			ErrorJSON(w, http.StatusBadRequest, "from must be YYYY-MM-DD", nil)
+			return
+		}
+	}
+	to := from.AddDate(0, 0, 6)
+	if s := r.URL.Query().Get("to"); s != "" {
+		if to, err = time.ParseInLocation("2006-01-02", s, loc); err != nil {
+			ErrorJSON(w, http.StatusBadRequest, "to must be YYYY-MM-DD", nil)
+			return
+		}
+	}
+	if to.Before(from) || to.After(from.AddDate(0, 0, maxScheduleDays-1)) {
+		ErrorJSON(w, http.StatusBadRequest, "to must be on or after from and at most 42 days later", nil)
+		return
+	}
+	end := to.AddDate(0, 0, 1)
+
+	avails, err := d.Q.ListAvailabilitiesByProvider(ctx, providerID)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to load availability", nil)
+		return
+	}
+	byWeekday := map[int][]slots.AvailWindow{}
+	for _, a := range avails {
+		wd := int(a.Weekday)
+		byWeekday[wd] = append(byWeekday[wd], slots.AvailWindow{StartHHMM: a.StartHhmm, EndHHMM: a.EndHhmm})
+	}
+	blackouts, err := d.Q.ListBlackoutsForProvider(ctx, gen.ListBlackoutsForProviderParams{
+		ProviderID: pgtype.Int8{Int64: providerID, Valid: true},
+		ClinicID:   pgtype.Int8{Int64: prov.ClinicID, Valid: true},
+		FromDate:   pgtype.Date{Time: time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC), Valid: true},
+		ToDate:     pgtype.Date{Time: time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC), Valid: true},
+	})
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to load blackouts", nil)
+		return
+	}
+	closed := map[string]*string{}
+	for _, b := range blackouts {
+		date := b.Date.Time.Format("2006-01-02")
+		if _, seen := closed[date]; !seen || closed[date] == nil {
+			closed[date] = b.Reason
+		}
+	}
+	appts, err := d.Q.ListAppointmentsByProviderOnDate(ctx, gen.ListAppointmentsByProviderOnDateParams{
+		ProviderID:  providerID,
+		StartTime:   from,
+		StartTime_2: end,
 	})
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to load provider schedule", nil)
+		return
+	}
+
+	days := make([]scheduleDay, 0, maxScheduleDays)
+	var patientIDs []int64
+	var totalAvail, totalBooked int
+	for day := from; day.Before(end); day = day.AddDate(0, 0, 1) {
+		next := day.AddDate(0, 0, 1)
+		date := day.Format("2006-01-02")
+		sd := scheduleDay{Date: date, Segments: []scheduleSegment{}}
+		reason, blackout := closed[date]
+		sd.Blackout, sd.BlackoutReason = blackout, reason
+
+		var booked []slots.BookedRange
+		for _, a := range appts {
+			if a.Status == "cancelled" || a.StartTime.Before(day) || !a.StartTime.Before(next) {
+				continue
+			}
+			booked = append(booked, slots.BookedRange{Start: a.StartTime.In(loc), End: a.EndTime.In(loc)})
+			sd.Segments = append(sd.Segments, scheduleSegment{
+				Start:         a.StartTime.In(loc),
+				End:           a.EndTime.In(loc),
+				Type:          "booked",
+				AppointmentID: a.ID,
+				Status:        a.Status,
+				PatientName:   a.PatientName,
+				ServiceName:   a.ServiceName,
+			})
+			patientIDs = append(patientIDs, a.PatientID)
+		}
+		if !blackout {
+			// weekday mapping: our DB uses 1=Mon ... 7=Sun; Go uses 0=Sun ... 6=Sat
+			dbWD := ((int(day.Weekday()) + 6) % 7) + 1
+			free, availMin, bookedMin, err := slots.Utilization(day, loc, byWeekday[dbWD], booked)
+			if err != nil {
+				ErrorJSON(w, http.StatusInternalServerError, "invalid availability window", err.Error())
+				return
+			}
+			for _, f := range free {
+				sd.Segments = append(sd.Segments, scheduleSegment{Start: f.Start, End: f.End, Type: "free"})
+			}
+			sd.AvailableMinutes, sd.BookedMinutes = availMin, bookedMin
+			sd.Utilization = percent(bookedMin, availMin)
+		}
+		sort.Slice(sd.Segments, func(i, j int) bool { return sd.Segments[i].Start.Before(sd.Segments[j].Start) })
+		totalAvail += sd.AvailableMinutes
+		totalBooked += sd.BookedMinutes
+		days = append(days, sd)
+	}
+
+	auditPatients(r, d.Q, auditEvent{
+		Action:       AuditAppointmentList,
+		ResourceType: AuditResProviderSchedule,
+		ResourceID:   providerID,
+		ClinicID:     prov.ClinicID,
+	}, patientIDs)
+
+	if format != formatJSON {
+		zone := d.clinicZone(r, prov.ClinicID)
+		t := exportTable{Header: []string{"date", "start", "end", "type", "patient", "service", "status", "appointment_id", "note"}}
+		for _, sd := range days {
+			if sd.Blackout {
+				t.Rows = append(t.Rows, []string{sd.Date, "", "", "blackout", "", "", "", "", strOrEmpty(sd.BlackoutReason)})
+			}
+			for _, seg := range sd.Segments {
+				_, start, end := localTimes(seg.Start, seg.End, zone)
+				id := ""
+				if seg.AppointmentID > 0 {
+					id = itoa(seg.AppointmentID)
+				}
+				t.Rows = append(t.Rows, []string{sd.Date, start, end, seg.Type, strOrEmpty(seg.PatientName), seg.ServiceName, seg.Status, id, ""})
+			}
+		}
+		writeTable(w, format, fmt.Sprintf("provider_%d_%s_%s", providerID, from.Format("20060102"), to.Format("20060102")), t)
+		return
+	}
+
+	JSON(w, http.StatusOK, struct {
+		ProviderID       int64         `json:"provider_id"`
+		From             string        `json:"from"`
+		To               string        `json:"to"`
+		AvailableMinutes int           `json:"available_minutes"`
+		BookedMinutes    int           `json:"booked_minutes"`
+		Utilization      *float64      `json:"utilization"`
+		Days             []scheduleDay `json:"days"`
+	}{
+		ProviderID:       providerID,
+		From:             from.Format("2006-01-02"),
+		To:               to.Format("2006-01-02"),
+		AvailableMinutes: totalAvail,
+		BookedMinutes:    totalBooked,
+		Utilization:      percent(totalBooked, totalAvail),
+		Days:             days,
+	})
+}
+
+// clinicZone is the clinic's timezone for formatting exported times.
+func (d ProviderScheduleDeps) clinicZone(r *http.Request, clinicID int64) *time.Location {
+	name := ""
+	if c, err := d.Q.GetClinic(r.Context(), clinicID); err == nil {
+		name = c.Timezone
+	}
+	return zoneCache{}.get(name)
+}
+
+// percent is part/whole as a percentage to one decimal; nil when whole is 0.
+func percent(part, whole int) *float64 {
+	if whole <= 0 {
+		return nil
+	}
+	p := math.Round(float64(part)*1000/float64(whole)) / 10
+	return &p
+}
+
+// scheduleAppointment is one row of a provider's day plus the submitted
+// intake form, if any.
+type scheduleAppointment struct {
+	gen.ListAppointmentsByProviderOnDateRow
+	Intake *intakeView `json:"intake,omitempty"`
+}
+
+// canReadSchedule: schedule:read in the provider's clinic, or schedule:read:own
+// when the caller is that provider.
+func canReadSchedule(r *http.Request, uid int64, prov gen.Provider) bool {
+	grants := GrantsFromCtx(r)
+	if grants.Can(rbac.ScheduleRead, prov.ClinicID) {
+		return true
+	}
+	return grants.Can(rbac.ScheduleReadOwn, prov.ClinicID) && prov.UserID == uid
 }
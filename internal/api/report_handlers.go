@@ -0,0 +1,288 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/justanamir/medappoint/internal/db/gen"
+	"github.com/justanamir/medappoint/internal/rbac"
+)
+
+// ReportDeps serves the clinic operations reports. Every report takes
+// ?from=&to= (YYYY-MM-DD, inclusive, clinic time; default the last 30 days,
+// at most 366) and ?clinic_id=, is limited to the caller's reports:read
+// clinics, and can be downloaded as CSV or XLSX (see exportFormat).
+type ReportDeps struct {
+	Q *gen.Queries
+}
+
+const reportTZ = "Asia/Kuala_Lumpur"
+
+type reportParams struct {
+	From, To  time.Time
+	ClinicIDs []int64
+	Format    string
+}
+
+func (p reportParams) dates() (pgtype.Date, pgtype.Date) {
+	return pgtype.Date{Time: p.From, Valid: true}, pgtype.Date{Time: p.To, Valid: true}
+}
+
+// GET /v1/admin/reports/bookings
+// Visits per day with cancellation and no-show rates (percent of the day's
+// bookings). A no-show is a past visit that is still "scheduled".
+func (d ReportDeps) BookingsHandler(w http.ResponseWriter, r *http.Request) {
+	p, ok := parseReportParams(w, r)
+	if !ok {
+		return
+	}
+	from, to := p.dates()
+	rows, err := d.Q.ReportBookingsPerDay(r.Context(), gen.ReportBookingsPerDayParams{
+		Tz:        reportTZ,
+		FromDate:  from,
+		ToDate:    to,
+		ClinicIds: p.ClinicIDs,
+	})
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to build report", nil)
+		return
+	}
+	type counts struct {
+		Total            int64    `json:"total"`
+		Completed        int64    `json:"completed"`
+		Cancelled        int64    `json:"cancelled"`
+		NoShow           int64    `json:"no_show"`
+		CancellationRate *float64 `json:"cancellation_rate"`
+		NoShowRate       *float64 `json:"no_show_rate"`
+	}
+	type day struct {
+		Date string `json:"date"`
+		counts
+	}
+	days := make([]day, 0, len(rows))
+	var sum counts
+	t := exportTable{Header: []string{"date", "total", "completed", "cancelled", "no_show", "cancellation_rate", "no_show_rate"}}
+	for _, row := range rows {
+		c := counts{
+			Total:            row.Total,
+			Completed:        row.Completed,
+			Cancelled:        row.Cancelled,
+			NoShow:           row.NoShow,
+			CancellationRate: percent(int(row.Cancelled), int(row.Total)),
+			NoShowRate:       percent(int(row.NoShow), int(row.Total)),
+		}
+		date := row.Day.Time.Format("2006-01-02")
+		days = append(days, day{Date: date, counts: c})
+		sum.Total += row.Total
+		sum.Completed += row.Completed
+		sum.Cancelled += row.Cancelled
+		sum.NoShow += row.NoShow
+		t.Rows = append(t.Rows, []string{date, itoa(c.Total), itoa(c.Completed), itoa(c.Cancelled), itoa(c.NoShow), ftoa(c.CancellationRate), ftoa(c.NoShowRate)})
+	}
+	sum.CancellationRate = percent(int(sum.Cancelled), int(sum.Total))
+	sum.NoShowRate = percent(int(sum.NoShow), int(sum.Total))
+
+	writeReport(w, r, "bookings", p, t, map[string]any{
+		"from":    p.From.Format("2006-01-02"),
+		"to":      p.To.Format("2006-01-02"),
+		"summary": sum,
+		"days":    days,
+	})
+}
+
+// GET /v1/admin/reports/provider-utilization
+// Booked minutes against the weekly availability windows in the range,
+// blackout days excluded.
+func (d ReportDeps) ProviderUtilizationHandler(w http.ResponseWriter, r *http.Request) {
+	p, ok := parseReportParams(w, r)
+	if !ok {
+		return
+	}
+	from, to := p.dates()
+	rows, err := d.Q.ReportProviderUtilization(r.Context(), gen.ReportProviderUtilizationParams{
+		FromDate:  from,
+		ToDate:    to,
+		Tz:        reportTZ,
+		ClinicIds: p.ClinicIDs,
+	})
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to build report", nil)
+		return
+	}
+	type provider struct {
+		gen.ReportProviderUtilizationRow
+		Utilization *float64 `json:"utilization"`
+	}
+	items := make([]provider, 0, len(rows))
+	t := exportTable{Header: []string{"provider_id", "provider_name", "clinic_id", "appointments", "available_minutes", "booked_minutes", "utilization"}}
+	for _, row := range rows {
+		it := provider{ReportProviderUtilizationRow: row, Utilization: percent(int(row.BookedMinutes), int(row.AvailableMinutes))}
+		items = append(items, it)
+		t.Rows = append(t.Rows, []string{itoa(row.ProviderID), row.ProviderName, itoa(row.ClinicID), itoa(row.Appointments), itoa(row.AvailableMinutes), itoa(row.BookedMinutes), ftoa(it.Utilization)})
+	}
+	writeReport(w, r, "provider-utilization", p, t, map[string]any{
+		"from":      p.From.Format("2006-01-02"),
+		"to":        p.To.Format("2006-01-02"),
+		"providers": items,
+	})
+}
+
+// leadTimeBuckets matches the CASE in ReportLeadTimes.
+var leadTimeBuckets = []string{"<1h", "1h-1d", "1-3d", "3-7d", "7-30d", "30d+"}
+
+// GET /v1/admin/reports/lead-times
+// How far ahead visits were booked: counts per bucket plus median, p90
+// and mean in hours.
+func (d ReportDeps) LeadTimesHandler(w http.ResponseWriter, r *http.Request) {
+	p, ok := parseReportParams(w, r)
+	if !ok {
+		return
+	}
+	from, to := p.dates()
+	rows, err := d.Q.ReportLeadTimes(r.Context(), gen.ReportLeadTimesParams{
+		FromDate:  from,
+		Tz:        reportTZ,
+		ToDate:    to,
+		ClinicIds: p.ClinicIDs,
+	})
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to build report", nil)
+		return
+	}
+	stats, err := d.Q.ReportLeadTimeStats(r.Context(), gen.ReportLeadTimeStatsParams{
+		FromDate:  from,
+		Tz:        reportTZ,
+		ToDate:    to,
+		ClinicIds: p.ClinicIDs,
+	})
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to build report", nil)
+		return
+	}
+	counts := make([]int64, len(leadTimeBuckets))
+	for _, row := range rows {
+		if int(row.Bucket) < len(counts) {
+			counts[row.Bucket] = row.Appointments
+		}
+	}
+	type bucket struct {
+		Bucket       string `json:"bucket"`
+		Appointments int64  `json:"appointments"`
+	}
+	buckets := make([]bucket, len(leadTimeBuckets))
+	t := exportTable{Header: []string{"bucket", "appointments"}}
+	for i, name := range leadTimeBuckets {
+		buckets[i] = bucket{Bucket: name, Appointments: counts[i]}
+		t.Rows = append(t.Rows, []string{name, itoa(counts[i])})
+	}
+	writeReport(w, r, "lead-times", p, t, map[string]any{
+		"from":    p.From.Format("2006-01-02"),
+		"to":      p.To.Format("2006-01-02"),
+		"buckets": buckets,
+		"stats":   stats,
+	})
+}
+
+// GET /v1/admin/reports/busiest-hours
+// Non-cancelled visits per weekday (1=Mon ... 7=Sun) and starting hour,
+// busiest first.
+func (d ReportDeps) BusiestHoursHandler(w http.ResponseWriter, r *http.Request) {
+	p, ok := parseReportParams(w, r)
+	if !ok {
+		return
+	}
+	from, to := p.dates()
+	rows, err := d.Q.ReportBusiestHours(r.Context(), gen.ReportBusiestHoursParams{
+		Tz:        reportTZ,
+		FromDate:  from,
+		ToDate:    to,
+		ClinicIds: p.ClinicIDs,
+	})
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to build report", nil)
+		return
+	}
+	if rows == nil {
+		rows = []gen.ReportBusiestHoursRow{}
+	}
+	t := exportTable{Header: []string{"weekday", "hour", "appointments"}}
+	for _, row := range rows {
+		t.Rows = append(t.Rows, []string{itoa(int64(row.Weekday)), itoa(int64(row.Hour)), itoa(row.Appointments)})
+	}
+	writeReport(w, r, "busiest-hours", p, t, map[string]any{
+		"from":  p.From.Format("2006-01-02"),
+		"to":    p.To.Format("2006-01-02"),
+		"hours": rows,
+	})
+}
+
+func parseReportParams(w http.ResponseWriter, r *http.Request) (reportParams, bool) {
+	var p reportParams
+	var ok bool
+	if p.Format, ok = exportFormat(w, r); !ok {
+		return p, false
+	}
+	scope := GrantsFromCtx(r).Scope(rbac.ReportsRead)
+	p.ClinicIDs = scope.Filter()
+	if s := r.URL.Query().Get("clinic_id"); s != "" {
+		cid, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || cid <= 0 {
+			ErrorJSON(w, http.StatusBadRequest, "invalid clinic_id", nil)
+			return p, false
+		}
+		if !scope.Allows(cid) {
+			ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
+			return p, false
+		}
+		p.ClinicIDs = []int64{cid}
+	}
+
+	// plain dates; only the database applies the clinic timezone
+	loc, _ := time.LoadLocation(reportTZ)
+	now := time.Now().In(loc)
+	p.To = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if s := r.URL.Query().Get("to"); s != "" {
+		t, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			ErrorJSON(w, http.StatusBadRequest, "to must be YYYY-MM-DD", nil)
+			return p, false
+		}
+		p.To = t
+	}
+	p.From = p.To.AddDate(0, 0, -29)
+	if s := r.URL.Query().Get("from"); s != "" {
+		t, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			ErrorJSON(w, http.StatusBadRequest, "from must be YYYY-MM-DD", nil)
+			return p, false
+		}
+		p.From = t
+	}
+	if p.To.Before(p.From) || p.To.After(p.From.AddDate(0, 0, 365)) {
+		ErrorJSON(w, http.StatusBadRequest, "to must be on or after from and at most 366 days later", nil)
+		return p, false
+	}
+	return p, true
+}
+
+// writeReport sends body as JSON, or t as a download when one was asked for.
+func writeReport(w http.ResponseWriter, r *http.Request, name string, p reportParams, t exportTable, body any) {
+	if p.Format == formatJSON {
+		JSON(w, http.StatusOK, body)
+		return
+	}
+	writeTable(w, p.Format, fmt.Sprintf("%s_%s_%s", name, p.From.Format("20060102"), p.To.Format("20060102")), t)
+}
+
+func itoa(n int64) string { return strconv.FormatInt(n, 10) }
+
+// ftoa renders an optional percentage for CSV; empty when nil.
+func ftoa(f *float64) string {
+	if f == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*f, 'f', 1, 64)
+}
@@ -0,0 +1,305 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/justanamir/medappoint/internal/db/gen"
+	"github.com/justanamir/medappoint/internal/rbac"
+	"github.com/justanamir/medappoint/internal/slots"
+)
+
+// ResourceDeps manages rooms and equipment (catalog:write).
+type ResourceDeps struct {
+	Q    *gen.Queries
+	Next *NextAvailable
+}
+
+// GET /v1/admin/resources
+func (d ResourceDeps) ListResources(w http.ResponseWriter, r *http.Request) {
+	scope := GrantsFromCtx(r).Scope(rbac.CatalogWrite)
+	rows, err := d.Q.ListResources(r.Context(), scope.Filter())
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to list resources", nil)
+		return
+	}
+	if rows == nil {
+		rows = []gen.Resource{}
+	}
+	JSON(w, http.StatusOK, rows)
+}
+
+type createResourceReq struct {
+	ClinicID int64  `json:"clinic_id"`
+	Kind     string `json:"kind"` // e.g. "room", "ultrasound", "dental_chair"
+	Name     string `json:"name"`
+}
+
+// POST /v1/admin/resources
+func (d ResourceDeps) CreateResource(w http.ResponseWriter, r *http.Request) {
+	var req createResourceReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		ErrorJSON(w, http.StatusBadRequest, "invalid json", nil)
+		return
+	}
+	req.Kind = normalizeKind(req.Kind)
+	req.Name = strings.TrimSpace(req.Name)
+	if req.ClinicID <= 0 || req.Kind == "" || req.Name == "" {
+		ErrorJSON(w, http.StatusBadRequest, "clinic_id, kind and name are required", nil)
+		return
+	}
+	if !GrantsFromCtx(r).Can(rbac.CatalogWrite, req.ClinicID) {
+		ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
+		return
+	}
+	row, err := d.Q.CreateResource(r.Context(), gen.CreateResourceParams{
+		ClinicID: req.ClinicID,
+		Kind:     req.Kind,
+		Name:     req.Name,
+	})
+	if isUniqueViolation(err) {
+		ErrorJSON(w, http.StatusConflict, "a resource with that name already exists in the clinic", nil)
+		return
+	}
+	if err != nil {
+		ErrorJSON(w, http.StatusBadRequest, "failed to create resource", nil)
+		return
+	}
+	d.Next.InvalidateAll()
+	JSON(w, http.StatusCreated, row)
+}
+
+type updateResourceReq struct {
+	Name   *string `json:"name"`
+	Active *bool   `json:"active"` // inactive resources are never allocated
+}
+
+// PATCH /v1/admin/resources/{id}
+func (d ResourceDeps) UpdateResource(w http.ResponseWriter, r *http.Request) {
+	res, ok := d.load(w, r)
+	if !ok {
+		return
+	}
+	var req updateResourceReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		ErrorJSON(w, http.StatusBadRequest, "invalid json", nil)
+		return
+	}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			ErrorJSON(w, http.StatusBadRequest, "name cannot be empty", nil)
+			return
+		}
+		req.Name = &name
+	}
+	var active pgtype.Bool
+	if req.Active != nil {
+		active = pgtype.Bool{Bool: *req.Active, Valid: true}
+	}
+	row, err := d.Q.UpdateResource(r.Context(), gen.UpdateResourceParams{
+		Name:   req.Name,
+		Active: active,
+		ID:     res.ID,
+	})
+	if isUniqueViolation(err) {
+		ErrorJSON(w, http.StatusConflict, "a resource with that name already exists in the clinic", nil)
+		return
+	}
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to update resource", nil)
+		return
+	}
+	d.Next.InvalidateAll()
+	JSON(w, http.StatusOK, row)
+}
+
+// GET /v1/admin/resources/{id}/availability
+func (d ResourceDeps) GetAvailability(w http.ResponseWriter, r *http.Request) {
+	res, ok := d.load(w, r)
+	if !ok {
+		return
+	}
+	rows, err := d.Q.ListResourceAvailabilities(r.Context(), res.ID)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to load availability", nil)
+		return
+	}
+	if rows == nil {
+		rows = []gen.ResourceAvailability{}
+	}
+	JSON(w, http.StatusOK, rows)
+}
+
+type resourceWindow struct {
+	Weekday   int32  `json:"weekday"` // 1=Mon ... 7=Sun
+	StartHHMM string `json:"start_hhmm"`
+	EndHHMM   string `json:"end_hhmm"`
+}
+
+// PUT /v1/admin/resources/{id}/availability   body: [{"weekday":1,"start_hhmm":"09:00","end_hhmm":"13:00"}, ...]
+// Replaces the weekly schedule; an empty list means "open whenever the
+// provider is".
+func (d ResourceDeps) PutAvailability(w http.ResponseWriter, r *http.Request) {
+	res, ok := d.load(w, r)
+	if !ok {
+		return
+	}
+	var req []resourceWindow
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		ErrorJSON(w, http.StatusBadRequest, "invalid json", nil)
+		return
+	}
+	p := gen.ReplaceResourceAvailabilitiesParams{ResourceID: res.ID}
+	for i, win := range req {
+		sm, err1 := parseHHMM(win.StartHHMM)
+		em, err2 := parseHHMM(win.EndHHMM)
+		if win.Weekday < 1 || win.Weekday > 7 || err1 != nil || err2 != nil || em <= sm {
+			ErrorJSON(w, http.StatusUnprocessableEntity, "invalid window", map[string]int{"index": i})
+			return
+		}
+		p.Weekdays = append(p.Weekdays, win.Weekday)
+		p.StartHhmms = append(p.StartHhmms, win.StartHHMM)
+		p.EndHhmms = append(p.EndHhmms, win.EndHHMM)
+	}
+	rows, err := d.Q.ReplaceResourceAvailabilities(r.Context(), p)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to save availability", nil)
+		return
+	}
+	if rows == nil {
+		rows = []gen.ReplaceResourceAvailabilitiesRow{}
+	}
+	d.Next.InvalidateAll()
+	JSON(w, http.StatusOK, rows)
+}
+
+type serviceKindsReq struct {
+	Kinds []string `json:"kinds"`
+}
+
+// PUT /v1/admin/services/{id}/resource-kinds   body: {"kinds": ["room", "ultrasound"]}
+// Each listed kind needs one free resource for a slot to be bookable.
+func (d ResourceDeps) PutServiceKinds(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		ErrorJSON(w, http.StatusBadRequest, "invalid service id", nil)
+		return
+	}
+	ctx := r.Context()
+	svc, err := d.Q.GetService(ctx, id)
+	if err != nil {
+		ErrorJSON(w, http.StatusNotFound, "service not found", nil)
+		return
+	}
+	if !GrantsFromCtx(r).Can(rbac.CatalogWrite, svc.ClinicID) {
+		ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
+		return
+	}
+	var req serviceKindsReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		ErrorJSON(w, http.StatusBadRequest, "invalid json", nil)
+		return
+	}
+	kinds := make([]string, 0, len(req.Kinds))
+	for _, k := range req.Kinds {
+		if k = normalizeKind(k); k == "" {
+			ErrorJSON(w, http.StatusUnprocessableEntity, "kinds cannot be empty", nil)
+			return
+		}
+		kinds = append(kinds, k)
+	}
+	if err := d.Q.ReplaceServiceResourceKinds(ctx, gen.ReplaceServiceResourceKindsParams{
+		ServiceID: svc.ID,
+		Kinds:     kinds,
+	}); err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to save resource kinds", nil)
+		return
+	}
+	saved, err := d.Q.ListServiceResourceKinds(ctx, svc.ID)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to load resource kinds", nil)
+		return
+	}
+	if saved == nil {
+		saved = []string{}
+	}
+	d.Next.InvalidateAll()
+	JSON(w, http.StatusOK, map[string]any{"service_id": svc.ID, "kinds": saved})
+}
+
+func (d ResourceDeps) load(w http.ResponseWriter, r *http.Request) (gen.Resource, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		ErrorJSON(w, http.StatusBadRequest, "invalid resource id", nil)
+		return gen.Resource{}, false
+	}
+	res, err := d.Q.GetResource(r.Context(), id)
+	if err != nil {
+		ErrorJSON(w, http.StatusNotFound, "resource not found", nil)
+		return gen.Resource{}, false
+	}
+	if !GrantsFromCtx(r).Can(rbac.CatalogWrite, res.ClinicID) {
+		ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
+		return gen.Resource{}, false
+	}
+	return res, true
+}
+
+func normalizeKind(k string) string {
+	return strings.ToLower(strings.TrimSpace(k))
+}
+
+// loadResourcePool gathers the clinic's active resources of the given kinds
+// with their windows for weekday dbWD and their holds on [dayStart, dayEnd).
+func loadResourcePool(ctx context.Context, q *gen.Queries, clinicID int64, kinds []string, dbWD int, dayStart, dayEnd time.Time) ([]slots.Resource, error) {
+	if len(kinds) == 0 {
+		return nil, nil
+	}
+	res, err := q.ListActiveResourcesByKinds(ctx, gen.ListActiveResourcesByKindsParams{ClinicID: clinicID, Kinds: kinds})
+	if err != nil || len(res) == 0 {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(res))
+	for _, r := range res {
+		ids = append(ids, r.ID)
+	}
+	avails, err := q.ListResourceWeekdayAvailabilities(ctx, gen.ListResourceWeekdayAvailabilitiesParams{
+		ResourceIds: ids,
+		Weekday:     int32(dbWD),
+	})
+	if err != nil {
+		return nil, err
+	}
+	holds, err := q.ListResourceBookings(ctx, gen.ListResourceBookingsParams{
+		ResourceIds: ids,
+		RangeEnd:    dayEnd,
+		RangeStart:  dayStart,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	loc := dayStart.Location()
+	pool := make([]slots.Resource, len(res))
+	idx := make(map[int64]int, len(res))
+	for i, r := range res {
+		pool[i] = slots.Resource{ID: r.ID, Kind: r.Kind}
+		idx[r.ID] = i
+	}
+	for _, a := range avails {
+		p := &pool[idx[a.ResourceID]]
+		p.Avails = append(p.Avails, slots.AvailWindow{StartHHMM: a.StartHhmm, EndHHMM: a.EndHhmm})
+	}
+	for _, h := range holds {
+		p := &pool[idx[h.ResourceID]]
+		p.Booked = append(p.Booked, slots.BookedRange{Start: h.StartTime.In(loc), End: h.EndTime.In(loc)})
+	}
+	return pool, nil
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	dbconn "github.com/justanamir/medappoint/internal/db"
+	"github.com/justanamir/medappoint/internal/db/gen"
+	"github.com/justanamir/medappoint/internal/fieldcrypt"
+	"github.com/justanamir/medappoint/internal/rbac"
+)
+
+type RoleDeps struct {
+	Q    *gen.Queries
+	Pool *pgxpool.Pool       // a grant and the user's role change commit together
+	Keys *fieldcrypt.Keyring // for the patient lookup inside that transaction
+}
+
+// GET /v1/admin/role-assignments
+// Lists assignments in every clinic the caller may manage staff for.
+func (d RoleDeps) ListRoleAssignments(w http.ResponseWriter, r *http.Request) {
+	scope := GrantsFromCtx(r).Scope(rbac.StaffManage)
+	rows, err := d.Q.ListRoleAssignments(r.Context(), scope.Filter())
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to list role assignments", nil)
+		return
+	}
+	JSON(w, http.StatusOK, rows)
+}
+
+type createRoleAssignmentReq struct {
+	UserID   int64  `json:"user_id"`
+	ClinicID *int64 `json:"clinic_id"` // omit/null = all clinics (global admins only)
+	Role     string `json:"role"`
+}
+
+// POST /v1/admin/role-assignments
+// The target must be an existing user. Managers scoped to some clinics may
+// only grant to users who hold no roles elsewhere and are not patients with
+// their own record; global managers may grant to anyone. A patient account's
+// users.role moves to the granted role in the same transaction.
+func (d RoleDeps) CreateRoleAssignment(w http.ResponseWriter, r *http.Request) {
+	var req createRoleAssignmentReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		ErrorJSON(w, http.StatusBadRequest, "invalid json", nil)
+		return
+	}
+	if req.UserID <= 0 || !rbac.ValidRole(req.Role) {
+		ErrorJSON(w, http.StatusBadRequest, "user_id and a valid role are required", "admin, clinic_manager, receptionist, provider")
+		return
+	}
+
+	scope := GrantsFromCtx(r).Scope(rbac.StaffManage)
+	var clinicID pgtype.Int8
+	if req.ClinicID != nil {
+		clinicID = pgtype.Int8{Int64: *req.ClinicID, Valid: true}
+	}
+	if !canManageGrant(scope, clinicID, req.Role) {
+		ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := d.Pool.Begin(ctx)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to create role assignment", nil)
+		return
+	}
+	defer func() { _ = tx.Rollback(context.WithoutCancel(ctx)) }()
+	txq := gen.New(dbconn.Encrypted(tx, d.Keys))
+
+	u, err := txq.GetUserByID(ctx, req.UserID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		ErrorJSON(w, http.StatusNotFound, "user not found", nil)
+		return
+	}
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to load user", nil)
+		return
+	}
+	if !scope.All {
+		held, err := txq.ListRoleAssignmentsByUser(ctx, u.ID)
+		if err != nil {
+			ErrorJSON(w, http.StatusInternalServerError, "failed to load user", nil)
+			return
+		}
+		for _, ra := range held {
+			if !canManageGrant(scope, ra.ClinicID, ra.Role) {
+				ErrorJSON(w, http.StatusForbidden, "user holds roles outside your clinics", nil)
+				return
+			}
+		}
+		_, err = txq.GetPatientByUserID(ctx, u.ID)
+		if err == nil {
+			ErrorJSON(w, http.StatusConflict, "user is a patient account", "ask a global admin to grant roles to patients")
+			return
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			ErrorJSON(w, http.StatusInternalServerError, "failed to load user", nil)
+			return
+		}
+	}
+
+	row, err := txq.CreateRoleAssignment(ctx, gen.CreateRoleAssignmentParams{
+		UserID:   u.ID,
+		ClinicID: clinicID,
+		Role:     req.Role,
+	})
+	if err != nil {
+		ErrorJSON(w, http.StatusBadRequest, "failed to create role assignment", nil)
+		return
+	}
+	if err := txq.PromoteUserRole(ctx, gen.PromoteUserRoleParams{ID: u.ID, Role: req.Role}); err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to create role assignment", nil)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to create role assignment", nil)
+		return
+	}
+	JSON(w, http.StatusCreated, row)
+}
+
+// DELETE /v1/admin/role-assignments/{id}
+func (d RoleDeps) DeleteRoleAssignment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		ErrorJSON(w, http.StatusBadRequest, "invalid role assignment id", nil)
+		return
+	}
+
+	ra, err := d.Q.GetRoleAssignment(r.Context(), id)
+	if err != nil {
+		ErrorJSON(w, http.StatusNotFound, "role assignment not found", nil)
+		return
+	}
+	scope := GrantsFromCtx(r).Scope(rbac.StaffManage)
+	if !canManageGrant(scope, ra.ClinicID, ra.Role) {
+		ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
+		return
+	}
+
+	if err := d.Q.DeleteRoleAssignment(r.Context(), id); err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to delete role assignment", nil)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// canManageGrant: global and admin grants can only be handed out (or revoked)
+// by global managers; clinic grants need staff:manage in that clinic.
+func canManageGrant(scope rbac.Scope, clinicID pgtype.Int8, role string) bool {
+	if !clinicID.Valid || role == rbac.RoleAdmin {
+		return scope.All
+	}
+	return scope.Allows(clinicID.Int64)
+}
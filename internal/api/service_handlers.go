@@ -2,7 +2,9 @@ package api
 
 import (
 	"net/http"
+	"strconv"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/justanamir/medappoint/internal/db/gen"
 )
 
@@ -10,12 +12,70 @@ type ServiceDeps struct {
 	Q *gen.Queries
 }
 
-// ListServicesHandler handles GET /v1/services
+// ListServicesHandler handles GET /v1/services?clinic_id=&q=&limit=&cursor=
 func (d ServiceDeps) ListServicesHandler(w http.ResponseWriter, r *http.Request) {
-	services, err := d.Q.ListServices(r.Context())
+	clinicID, ok := queryID(r, "clinic_id")
+	if !ok {
+		ErrorJSON(w, http.StatusBadRequest, "invalid clinic_id", nil)
+		return
+	}
+	afterID, limit, err := idPageParams(r)
+	if err != nil {
+		ErrorJSON(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	rows, err := d.Q.ListServices(r.Context(), gen.ListServicesParams{
+		ClinicID: clinicID,
+		Q:        queryText(r, "q"),
+		AfterID:  afterID,
+		RowLimit: limit + 1,
+	})
 	if err != nil {
 		ErrorJSON(w, http.StatusInternalServerError, "failed to list services", nil)
 		return
 	}
-	JSON(w, http.StatusOK, services)
+	JSON(w, http.StatusOK, idPage(rows, limit, func(s gen.ListServicesRow) int64 { return s.ID }))
+}
+
+type serviceView struct {
+	ID          int64      `json:"id"`
+	Name        string     `json:"name"`
+	Description *string    `json:"description"`
+	DurationMin int32      `json:"duration_min"`
+	Clinic      gen.Clinic `json:"clinic"`
+
+	// effective lead time / advance window / same-day cutoff
+	BookingRules gen.GetBookingRulesRow `json:"booking_rules"`
+}
+
+// GET /v1/services/{id}
+func (d ServiceDeps) GetServiceHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		ErrorJSON(w, http.StatusBadRequest, "invalid service id", nil)
+		return
+	}
+	svc, err := d.Q.GetService(r.Context(), id)
+	if err != nil {
+		ErrorJSON(w, http.StatusNotFound, "service not found", nil)
+		return
+	}
+	clinic, err := d.Q.GetClinic(r.Context(), svc.ClinicID)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to load clinic", nil)
+		return
+	}
+	rules, err := d.Q.GetBookingRules(r.Context(), svc.ID)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to load booking rules", nil)
+		return
+	}
+	JSON(w, http.StatusOK, serviceView{
+		ID:           svc.ID,
+		Name:         svc.Name,
+		Description:  svc.Description,
+		DurationMin:  svc.DurationMin,
+		Clinic:       clinic,
+		BookingRules: rules,
+	})
 }
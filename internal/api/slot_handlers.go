@@ -1,10 +1,12 @@
 package api
 
 import (
+	"context"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/justanamir/medappoint/internal/db/gen"
 	"github.com/justanamir/medappoint/internal/slots"
 )
@@ -40,16 +42,25 @@ func (d SlotDeps) ListSlotsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Load provider/service + inputs we need
-	svc, err := d.Q.GetService(ctx, serviceID)
-	if err != nil {
+	if _, err := d.Q.GetService(ctx, serviceID); err != nil {
 		ErrorJSON(w, http.StatusNotFound, "service not found", nil)
 		return
 	}
 	// ensure provider exists (and to keep the door open for clinic checks later)
-	if _, err := d.Q.GetProvider(ctx, providerID); err != nil {
+	prov, err := d.Q.GetProvider(ctx, providerID)
+	if err != nil {
 		ErrorJSON(w, http.StatusNotFound, "provider not found", nil)
 		return
 	}
+	durationMin, offered, err := serviceDuration(r, d.Q, providerID, serviceID)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to load provider services", nil)
+		return
+	}
+	if !offered {
+		ErrorJSON(w, http.StatusUnprocessableEntity, "provider does not offer this service", nil)
+		return
+	}
 
 	// Use clinic/provider timezone. For now we assume Asia/Kuala_Lumpur.
 	loc, _ := time.LoadLocation("Asia/Kuala_Lumpur")
@@ -61,21 +72,63 @@ func (d SlotDeps) ListSlotsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	kinds, err := d.Q.ListServiceResourceKinds(ctx, serviceID)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to load service resources", nil)
+		return
+	}
+	win, err := bookingWindow(ctx, d.Q, serviceID)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to load booking rules", nil)
+		return
+	}
+	slotTimes, err := daySlots(ctx, d.Q, prov.ID, prov.ClinicID, durationMin, kinds, win, date, loc, time.Now().In(loc))
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to generate slots", nil)
+		return
+	}
+
+	// Return ISO8601 timestamps to be unambiguous
+	resp := struct {
+		Date       string   `json:"date"`
+		ProviderID int64    `json:"provider_id"`
+		ServiceID  int64    `json:"service_id"`
+		Slots      []string `json:"slots"`
+		Count      int      `json:"count"`
+	}{
+		Date:       dateStr,
+		ProviderID: providerID,
+		ServiceID:  serviceID,
+		Slots:      make([]string, 0, len(slotTimes)),
+		Count:      len(slotTimes),
+	}
+	for _, t := range slotTimes {
+		resp.Slots = append(resp.Slots, t.Format(time.RFC3339))
+	}
+
+	JSON(w, http.StatusOK, resp)
+
+}
+
+// daySlots lists the bookable starts on date for a provider: their weekly
+// availability unless the day is blacked out, minus existing bookings and
+// starts the booking window rules out, keeping only starts where every
+// room/equipment kind the service needs has a free resource too.
+func daySlots(ctx context.Context, q *gen.Queries, providerID, clinicID int64, durationMin int32, kinds []string, win slots.Window, date time.Time, loc *time.Location, now time.Time) ([]time.Time, error) {
 	// weekday mapping: our DB uses 1=Mon ... 7=Sun; Go uses 0=Sun ... 6=Sat
 	goWD := int(date.Weekday())  // 0..6 (Sun..Sat)
 	dbWD := ((goWD + 6) % 7) + 1 // 1..7 (Mon..Sun)
 
-	// Fetch availability rows for that weekday
-	avParams := gen.GetProviderWeekdayAvailabilityParams{
+	avRows, err := q.GetProviderWeekdayAvailability(ctx, gen.GetProviderWeekdayAvailabilityParams{
 		ProviderID: providerID,
 		Weekday:    int32(dbWD),
+	})
+	if err != nil || len(avRows) == 0 {
+		return nil, err
 	}
-	avRows, err := d.Q.GetProviderWeekdayAvailability(ctx, avParams)
-	if err != nil {
-		ErrorJSON(w, http.StatusInternalServerError, "failed to load availability", nil)
-		return
+	if blocked, err := isBlackout(ctx, q, providerID, clinicID, date); err != nil || blocked {
+		return nil, err
 	}
-
 	av := make([]slots.AvailWindow, 0, len(avRows))
 	for _, a := range avRows {
 		av = append(av, slots.AvailWindow{
@@ -87,17 +140,14 @@ func (d SlotDeps) ListSlotsHandler(w http.ResponseWriter, r *http.Request) {
 	// Fetch existing appointments for that date (to exclude overlaps)
 	dayStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
 	dayEnd := dayStart.Add(24 * time.Hour)
-	apParams := gen.ListProviderAppointmentsOnDateParams{
+	appts, err := q.ListProviderAppointmentsOnDate(ctx, gen.ListProviderAppointmentsOnDateParams{
 		ProviderID:  providerID,
 		StartTime:   dayStart,
 		StartTime_2: dayEnd, // sqlc uses _2 when the same name appears twice in the SQL
-	}
-	appts, err := d.Q.ListProviderAppointmentsOnDate(ctx, apParams)
+	})
 	if err != nil {
-		ErrorJSON(w, http.StatusInternalServerError, "failed to load appointments", nil)
-		return
+		return nil, err
 	}
-
 	booked := make([]slots.BookedRange, 0, len(appts))
 	for _, ap := range appts {
 		booked = append(booked, slots.BookedRange{
@@ -106,32 +156,35 @@ func (d SlotDeps) ListSlotsHandler(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
-	// Generate candidate start times
-	now := time.Now().In(loc)
-	slotTimes, err := slots.Generate(date, loc, int(svc.DurationMin), av, booked, now)
+	return freeStarts(ctx, q, clinicID, durationMin, kinds, win, date, loc, now, av, booked)
+}
+
+// freeStarts is daySlots once the day's availability and bookings are
+// loaded.
+func freeStarts(ctx context.Context, q *gen.Queries, clinicID int64, durationMin int32, kinds []string, win slots.Window, date time.Time, loc *time.Location, now time.Time, av []slots.AvailWindow, booked []slots.BookedRange) ([]time.Time, error) {
+	out, err := slots.Generate(date, loc, int(durationMin), av, booked, now)
 	if err != nil {
-		ErrorJSON(w, http.StatusBadRequest, "failed to generate slots", err.Error())
-		return
+		return nil, err
 	}
-
-	// Return ISO8601 timestamps to be unambiguous
-	resp := struct {
-		Date       string   `json:"date"`
-		ProviderID int64    `json:"provider_id"`
-		ServiceID  int64    `json:"service_id"`
-		Slots      []string `json:"slots"`
-		Count      int      `json:"count"`
-	}{
-		Date:       dateStr,
-		ProviderID: providerID,
-		ServiceID:  serviceID,
-		Slots:      make([]string, 0, len(slotTimes)),
-		Count:      len(slotTimes),
+	out = win.Filter(out, now, loc)
+	if len(out) == 0 || len(kinds) == 0 {
+		return out, nil
 	}
-	for _, t := range slotTimes {
-		resp.Slots = append(resp.Slots, t.Format(time.RFC3339))
+	dayStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+	dbWD := ((int(dayStart.Weekday()) + 6) % 7) + 1
+	pool, err := loadResourcePool(ctx, q, clinicID, kinds, dbWD, dayStart, dayStart.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
 	}
+	return slots.FilterByResources(out, int(durationMin), loc, kinds, pool), nil
+}
 
-	JSON(w, http.StatusOK, resp)
-
+// isBlackout reports whether the provider or their whole clinic is closed on
+// date.
+func isBlackout(ctx context.Context, q *gen.Queries, providerID, clinicID int64, date time.Time) (bool, error) {
+	return q.IsDateBlackoutedForProvider(ctx, gen.IsDateBlackoutedForProviderParams{
+		ProviderID: pgtype.Int8{Int64: providerID, Valid: true},
+		ClinicID:   pgtype.Int8{Int64: clinicID, Valid: true},
+		Date:       pgtype.Date{Time: time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC), Valid: true},
+	})
 }
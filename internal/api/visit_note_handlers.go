@@ -0,0 +1,183 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/justanamir/medappoint/internal/db/gen"
+	"github.com/justanamir/medappoint/internal/rbac"
+)
+
+// VisitNoteDeps serves the provider's clinical notes. Unlike appointment
+// notes (written at booking, visible to the patient) these are only for
+// the treating provider and clinic staff holding notes:read.
+type VisitNoteDeps struct {
+	Q *gen.Queries
+}
+
+const maxVisitNoteLen = 20000
+
+type visitNotesView struct {
+	AppointmentID int64           `json:"appointment_id"`
+	Signed        bool            `json:"signed"`
+	Versions      []gen.VisitNote `json:"versions"` // newest first
+}
+
+// GET /v1/appointments/{id}/visit-notes
+func (d VisitNoteDeps) ListHandler(w http.ResponseWriter, r *http.Request) {
+	uid, _ := UserIDFromCtx(r)
+	appt, ok := loadAppointment(w, r, d.Q)
+	if !ok {
+		return
+	}
+	if canRead, _ := d.access(r, uid, appt); !canRead {
+		ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
+		return
+	}
+	notes, err := d.Q.ListVisitNotes(r.Context(), appt.ID)
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to load visit notes", nil)
+		return
+	}
+	if notes == nil {
+		notes = []gen.VisitNote{}
+	}
+	audit(r, d.Q, auditEvent{
+		Action:       AuditVisitNoteRead,
+		ResourceType: AuditResAppointment,
+		ResourceID:   appt.ID,
+		PatientID:    appt.PatientID,
+		ClinicID:     appt.ClinicID,
+	})
+	JSON(w, http.StatusOK, visitNotesView{
+		AppointmentID: appt.ID,
+		Signed:        len(notes) > 0 && notes[0].SignedAt.Valid,
+		Versions:      notes,
+	})
+}
+
+type visitNoteReq struct {
+	Body        string `json:"body"`
+	BaseVersion int32  `json:"base_version"` // latest version the edit started from; 0 for the first
+}
+
+// POST /v1/appointments/{id}/visit-notes   body: {"body": "...", "base_version": 2}
+// Saves a new version. 409 if someone saved in between or the note is signed.
+func (d VisitNoteDeps) WriteHandler(w http.ResponseWriter, r *http.Request) {
+	uid, _ := UserIDFromCtx(r)
+	appt, ok := loadAppointment(w, r, d.Q)
+	if !ok {
+		return
+	}
+	if _, canWrite := d.access(r, uid, appt); !canWrite {
+		ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
+		return
+	}
+	if appt.Status == "cancelled" {
+		ErrorJSON(w, http.StatusConflict, "appointment is cancelled", nil)
+		return
+	}
+
+	var req visitNoteReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		ErrorJSON(w, http.StatusBadRequest, "invalid json", nil)
+		return
+	}
+	body := strings.TrimSpace(req.Body)
+	if body == "" || len(body) > maxVisitNoteLen {
+		ErrorJSON(w, http.StatusUnprocessableEntity, "body is required (max 20000 characters)", nil)
+		return
+	}
+	if req.BaseVersion < 0 {
+		ErrorJSON(w, http.StatusUnprocessableEntity, "base_version must be >= 0", nil)
+		return
+	}
+
+	note, err := d.Q.CreateVisitNote(r.Context(), gen.CreateVisitNoteParams{
+		AppointmentID: appt.ID,
+		Body:          body,
+		AuthorID:      pgtype.Int8{Int64: uid, Valid: true},
+		BaseVersion:   req.BaseVersion,
+	})
+	if errors.Is(err, pgx.ErrNoRows) || isUniqueViolation(err) {
+		ErrorJSON(w, http.StatusConflict, "note is signed or was changed since base_version", nil)
+		return
+	}
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to save visit note", nil)
+		return
+	}
+	audit(r, d.Q, auditEvent{
+		Action:       AuditVisitNoteWrite,
+		ResourceType: AuditResVisitNote,
+		ResourceID:   note.ID,
+		PatientID:    appt.PatientID,
+		ClinicID:     appt.ClinicID,
+	})
+	JSON(w, http.StatusCreated, note)
+}
+
+type signVisitNoteReq struct {
+	Version int32 `json:"version"`
+}
+
+// POST /v1/appointments/{id}/visit-notes/sign   body: {"version": 3}
+// Signs the given (latest) version once the appointment is completed.
+// A signed note can no longer be edited.
+func (d VisitNoteDeps) SignHandler(w http.ResponseWriter, r *http.Request) {
+	uid, _ := UserIDFromCtx(r)
+	appt, ok := loadAppointment(w, r, d.Q)
+	if !ok {
+		return
+	}
+	if _, canWrite := d.access(r, uid, appt); !canWrite {
+		ErrorJSON(w, http.StatusForbidden, "forbidden", nil)
+		return
+	}
+	if appt.Status != "completed" {
+		ErrorJSON(w, http.StatusConflict, "appointment must be completed before signing", nil)
+		return
+	}
+
+	var req signVisitNoteReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Version <= 0 {
+		ErrorJSON(w, http.StatusBadRequest, "version is required", nil)
+		return
+	}
+
+	note, err := d.Q.SignVisitNote(r.Context(), gen.SignVisitNoteParams{
+		SignedBy:      pgtype.Int8{Int64: uid, Valid: true},
+		AppointmentID: appt.ID,
+		Version:       req.Version,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		ErrorJSON(w, http.StatusConflict, "version is not the latest or is already signed", nil)
+		return
+	}
+	if err != nil {
+		ErrorJSON(w, http.StatusInternalServerError, "failed to sign visit note", nil)
+		return
+	}
+	audit(r, d.Q, auditEvent{
+		Action:       AuditVisitNoteSign,
+		ResourceType: AuditResVisitNote,
+		ResourceID:   note.ID,
+		PatientID:    appt.PatientID,
+		ClinicID:     appt.ClinicID,
+	})
+	JSON(w, http.StatusOK, note)
+}
+
+// access reports whether uid may read and write notes on appt. Only the
+// appointment's own provider writes; notes:read adds read-only access.
+func (d VisitNoteDeps) access(r *http.Request, uid int64, appt gen.Appointment) (read, write bool) {
+	grants := GrantsFromCtx(r)
+	if prov, err := d.Q.GetProvider(r.Context(), appt.ProviderID); err == nil {
+		write = prov.UserID == uid && grants.Can(rbac.NotesWriteOwn, appt.ClinicID)
+	}
+	return write || grants.Can(rbac.NotesRead, appt.ClinicID), write
+}
@@ -7,35 +7,54 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// Token purposes. Access tokens have none; the others are short-lived
+// step-up tokens only accepted by the MFA endpoints.
+const (
+	PurposeMFA       = "mfa"        // password ok, TOTP/recovery code pending
+	PurposeMFAEnroll = "mfa_enroll" // password ok, policy requires enrolling first
+)
+
 type Claims struct {
-	UserID int64  `json:"uid"`
-	Role   string `json:"role"`
+	UserID  int64  `json:"uid"`
+	Role    string `json:"role"`
+	Purpose string `json:"pur,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func SignJWT(secret, issuer string, ttlMinutes int, uid int64, role string) (string, error) {
+// SignJWT issues a token with the current signing key; asymmetric tokens carry
+// its kid header so verifiers can pick the matching key from the JWKS.
+func (ks *KeySet) SignJWT(issuer string, ttlMinutes int, uid int64, role string) (string, error) {
+	return ks.sign(issuer, time.Duration(ttlMinutes)*time.Minute, uid, role, "")
+}
+
+// SignPurposeJWT issues a step-up token (see Purpose*) valid for ttl.
+func (ks *KeySet) SignPurposeJWT(issuer string, ttl time.Duration, uid int64, role, purpose string) (string, error) {
+	return ks.sign(issuer, ttl, uid, role, purpose)
+}
+
+func (ks *KeySet) sign(issuer string, ttl time.Duration, uid int64, role, purpose string) (string, error) {
 	now := time.Now()
 	claims := Claims{
-		UserID: uid,
-		Role:   role,
+		UserID:  uid,
+		Role:    role,
+		Purpose: purpose,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    issuer,
 			IssuedAt:  jwt.NewNumericDate(now),
-			ExpiresAt: jwt.NewNumericDate(now.Add(time.Duration(ttlMinutes) * time.Minute)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
 		},
 	}
-	tok := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return tok.SignedString([]byte(secret))
+	tok := jwt.NewWithClaims(jwt.GetSigningMethod(ks.alg), claims)
+	if ks.signKID != "" {
+		tok.Header["kid"] = ks.signKID
+	}
+	return tok.SignedString(ks.signKey)
 }
 
-// ParseJWT verifies signature using secret and returns our custom Claims.
-func ParseJWT(secret, tokenStr string) (*Claims, error) {
-	tok, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(t *jwt.Token) (interface{}, error) {
-		if t.Method != jwt.SigningMethodHS256 {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(secret), nil
-	})
+// ParseJWT verifies the signature against the key named by the token's kid
+// and returns our custom Claims.
+func (ks *KeySet) ParseJWT(tokenStr string) (*Claims, error) {
+	tok, err := jwt.ParseWithClaims(tokenStr, &Claims{}, ks.keyFunc, jwt.WithValidMethods(ks.validMethods()))
 	if err != nil {
 		return nil, err
 	}
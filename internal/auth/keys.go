@@ -0,0 +1,271 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported signing algorithms (JWT "alg" header values).
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// verifyKey is a public (or, for HS256, shared) key tokens may be checked against.
+type verifyKey struct {
+	alg string
+	key interface{}
+}
+
+// KeySet holds the key used to sign new tokens plus every key still accepted
+// for verification, indexed by "kid". Rotating means: sign with a new key,
+// keep the previous public key in the verify set until old tokens expire.
+type KeySet struct {
+	alg        string
+	signKID    string
+	signKey    interface{}
+	verifyKeys map[string]verifyKey
+	order      []string // kids in load order, for a stable JWKS
+}
+
+// KeySetOptions describes where keys come from (usually straight from config).
+type KeySetOptions struct {
+	Alg            string   // HS256 | RS256 | EdDSA
+	Secret         string   // HS256 only
+	SigningKeyFile string   // PEM private key (RS256/EdDSA)
+	VerifyKeyFiles []string // extra PEM public/private keys still accepted
+}
+
+// NewHMACKeySet returns a single-secret HS256 key set (the legacy/dev mode).
+func NewHMACKeySet(secret string) *KeySet {
+	ks := &KeySet{
+		alg:        AlgHS256,
+		signKey:    []byte(secret),
+		verifyKeys: map[string]verifyKey{},
+	}
+	ks.verifyKeys[""] = verifyKey{alg: AlgHS256, key: []byte(secret)}
+	return ks
+}
+
+// LoadKeySet builds a KeySet from PEM files. The kid of each key is its file
+// name without extension, e.g. keys/2025-09.pem => "2025-09".
+func LoadKeySet(opts KeySetOptions) (*KeySet, error) {
+	alg := opts.Alg
+	if alg == "" {
+		alg = AlgHS256
+	}
+	switch alg {
+	case AlgHS256:
+		if opts.Secret == "" {
+			return nil, errors.New("HS256 requires a secret")
+		}
+		return NewHMACKeySet(opts.Secret), nil
+	case AlgRS256, AlgEdDSA:
+	default:
+		return nil, fmt.Errorf("unsupported jwt alg %q", alg)
+	}
+
+	if opts.SigningKeyFile == "" {
+		return nil, fmt.Errorf("%s requires a signing key file", alg)
+	}
+	ks := &KeySet{alg: alg, verifyKeys: map[string]verifyKey{}}
+
+	priv, err := readPrivateKey(opts.SigningKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("signing key: %w", err)
+	}
+	pub, kalg, err := publicOf(priv)
+	if err != nil {
+		return nil, fmt.Errorf("signing key: %w", err)
+	}
+	if kalg != alg {
+		return nil, fmt.Errorf("signing key is %s but JWT alg is %s", kalg, alg)
+	}
+	ks.signKID = kidFromPath(opts.SigningKeyFile)
+	ks.signKey = priv
+	ks.add(ks.signKID, kalg, pub)
+
+	for _, f := range opts.VerifyKeyFiles {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		pub, kalg, err := readPublicKey(f)
+		if err != nil {
+			return nil, fmt.Errorf("verify key %s: %w", f, err)
+		}
+		ks.add(kidFromPath(f), kalg, pub)
+	}
+	return ks, nil
+}
+
+func (ks *KeySet) add(kid, alg string, key interface{}) {
+	if _, dup := ks.verifyKeys[kid]; !dup {
+		ks.order = append(ks.order, kid)
+	}
+	ks.verifyKeys[kid] = verifyKey{alg: alg, key: key}
+}
+
+// Alg returns the algorithm used for signing.
+func (ks *KeySet) Alg() string { return ks.alg }
+
+// keyFunc resolves the verification key from the token's kid header.
+func (ks *KeySet) keyFunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	vk, ok := ks.verifyKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	if t.Method.Alg() != vk.alg {
+		return nil, errors.New("unexpected signing method")
+	}
+	return vk.key, nil
+}
+
+// validMethods lists every alg present in the verify set.
+func (ks *KeySet) validMethods() []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, vk := range ks.verifyKeys {
+		if !seen[vk.alg] {
+			seen[vk.alg] = true
+			out = append(out, vk.alg)
+		}
+	}
+	return out
+}
+
+// JWK is a single public key in RFC 7517 form.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// OKP (Ed25519)
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is the document served at /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns all public verification keys. Shared HS256 secrets are never
+// published, so an HMAC key set yields an empty list.
+func (ks *KeySet) JWKS() JWKS {
+	out := JWKS{Keys: []JWK{}}
+	for _, kid := range ks.order {
+		vk := ks.verifyKeys[kid]
+		switch k := vk.key.(type) {
+		case *rsa.PublicKey:
+			out.Keys = append(out.Keys, JWK{
+				Kty: "RSA", Kid: kid, Use: "sig", Alg: vk.alg,
+				N: b64url(k.N.Bytes()),
+				E: b64url(big.NewInt(int64(k.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			out.Keys = append(out.Keys, JWK{
+				Kty: "OKP", Kid: kid, Use: "sig", Alg: vk.alg,
+				Crv: "Ed25519", X: b64url(k),
+			})
+		}
+	}
+	return out
+}
+
+// ---- PEM helpers ----
+
+func readPEM(path string) (*pem.Block, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, jwt.ErrKeyMustBePEMEncoded
+	}
+	return block, nil
+}
+
+func readPrivateKey(path string) (crypto.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	if k, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		return k, nil
+	}
+	if k, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return k, nil
+	}
+	return nil, errors.New("not a PKCS#8 or PKCS#1 private key")
+}
+
+// readPublicKey accepts either a public key or a private key (whose public
+// half is then used), so operators can point at whichever file they have.
+func readPublicKey(path string) (crypto.PublicKey, string, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, "", err
+	}
+	if strings.Contains(block.Type, "PRIVATE") {
+		priv, err := readPrivateKey(path)
+		if err != nil {
+			return nil, "", err
+		}
+		return publicOf(priv)
+	}
+	if k, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return classify(k)
+	}
+	if k, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return k, AlgRS256, nil
+	}
+	return nil, "", errors.New("not a PKIX or PKCS#1 public key")
+}
+
+func publicOf(priv crypto.PrivateKey) (crypto.PublicKey, string, error) {
+	switch k := priv.(type) {
+	case *rsa.PrivateKey:
+		return &k.PublicKey, AlgRS256, nil
+	case ed25519.PrivateKey:
+		return k.Public(), AlgEdDSA, nil
+	}
+	return nil, "", errors.New("unsupported key type (want RSA or Ed25519)")
+}
+
+func classify(pub crypto.PublicKey) (crypto.PublicKey, string, error) {
+	switch pub.(type) {
+	case *rsa.PublicKey:
+		return pub, AlgRS256, nil
+	case ed25519.PublicKey:
+		return pub, AlgEdDSA, nil
+	}
+	return nil, "", errors.New("unsupported key type (want RSA or Ed25519)")
+}
+
+func kidFromPath(path string) string {
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+func b64url(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,281 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func newEd25519Key(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, k, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+// writePrivate stores key as a PKCS#8 PEM file named <kid>.pem in dir.
+func writePrivate(t *testing.T, dir, kid string, key crypto.PrivateKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writePEM(t, dir, kid, "PRIVATE KEY", der)
+}
+
+// writePublic stores the public half of key as a PKIX PEM file.
+func writePublic(t *testing.T, dir, kid string, key crypto.Signer) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writePEM(t, dir, kid, "PUBLIC KEY", der)
+}
+
+func writePEM(t *testing.T, dir, kid, typ string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, kid+".pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSignAndVerify(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		alg string
+		key crypto.PrivateKey
+	}{
+		{AlgRS256, newRSAKey(t)},
+		{AlgEdDSA, newEd25519Key(t)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.alg, func(t *testing.T) {
+			ks, err := LoadKeySet(KeySetOptions{Alg: tt.alg, SigningKeyFile: writePrivate(t, dir, "sign-"+tt.alg, tt.key)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			tok, err := ks.SignJWT("medappoint", 5, 42, "receptionist")
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed, _, err := jwt.NewParser().ParseUnverified(tok, &Claims{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parsed.Header["kid"] != "sign-"+tt.alg || parsed.Header["alg"] != tt.alg {
+				t.Fatalf("header = %v", parsed.Header)
+			}
+			c, err := ks.ParseJWT(tok)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.UserID != 42 || c.Role != "receptionist" || c.Issuer != "medappoint" || c.Purpose != "" {
+				t.Fatalf("claims = %+v", c)
+			}
+		})
+	}
+}
+
+func TestLoadKeySetErrors(t *testing.T) {
+	dir := t.TempDir()
+	ed := writePrivate(t, dir, "ed", newEd25519Key(t))
+	garbage := filepath.Join(dir, "garbage.pem")
+	if err := os.WriteFile(garbage, []byte("not a key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts KeySetOptions
+		want string
+	}{
+		{"hs256 without secret", KeySetOptions{Alg: AlgHS256}, "requires a secret"},
+		{"unsupported alg", KeySetOptions{Alg: "ES256", SigningKeyFile: ed}, "unsupported jwt alg"},
+		{"no signing key", KeySetOptions{Alg: AlgEdDSA}, "requires a signing key file"},
+		{"key does not match alg", KeySetOptions{Alg: AlgRS256, SigningKeyFile: ed}, "signing key is EdDSA but JWT alg is RS256"},
+		{"missing file", KeySetOptions{Alg: AlgEdDSA, SigningKeyFile: filepath.Join(dir, "nope.pem")}, "signing key"},
+		{"not pem", KeySetOptions{Alg: AlgEdDSA, SigningKeyFile: garbage}, "signing key"},
+		{"bad verify key", KeySetOptions{Alg: AlgEdDSA, SigningKeyFile: ed, VerifyKeyFiles: []string{garbage}}, "verify key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadKeySet(tt.opts); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyAcrossRotation(t *testing.T) {
+	dir := t.TempDir()
+	oldKey := newRSAKey(t)
+	oldSet, err := LoadKeySet(KeySetOptions{Alg: AlgRS256, SigningKeyFile: writePrivate(t, dir, "2025-01", oldKey)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldTok, err := oldSet.SignJWT("medappoint", 5, 1, "patient")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the new key signs; the old one is only kept for verification, from its
+	// public half in a separate directory
+	pubDir := t.TempDir()
+	newSet, err := LoadKeySet(KeySetOptions{
+		Alg:            AlgEdDSA,
+		SigningKeyFile: writePrivate(t, dir, "2025-06", newEd25519Key(t)),
+		VerifyKeyFiles: []string{" ", writePublic(t, pubDir, "2025-01", oldKey)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newTok, err := newSet.SignJWT("medappoint", 5, 2, "patient")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c, err := newSet.ParseJWT(oldTok); err != nil || c.UserID != 1 {
+		t.Fatalf("token from the previous key: %+v, %v", c, err)
+	}
+	if c, err := newSet.ParseJWT(newTok); err != nil || c.UserID != 2 {
+		t.Fatalf("token from the new key: %+v, %v", c, err)
+	}
+	if _, err := oldSet.ParseJWT(newTok); err == nil {
+		t.Fatal("old key set accepted a token from a key it does not know")
+	}
+}
+
+func TestRejectsUnknownKid(t *testing.T) {
+	dir := t.TempDir()
+	ks, err := LoadKeySet(KeySetOptions{Alg: AlgEdDSA, SigningKeyFile: writePrivate(t, dir, "current", newEd25519Key(t))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := newEd25519Key(t)
+	for _, kid := range []interface{}{"retired", "", nil} {
+		tok := jwt.NewWithClaims(jwt.SigningMethodEdDSA, Claims{UserID: 1, Role: "admin"})
+		if kid != nil {
+			tok.Header["kid"] = kid
+		}
+		s, err := tok.SignedString(other)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ks.ParseJWT(s); err == nil {
+			t.Errorf("kid %v: token accepted", kid)
+		}
+	}
+}
+
+func TestRejectsAlgorithmMismatch(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey := newRSAKey(t)
+	ks, err := LoadKeySet(KeySetOptions{
+		Alg:            AlgRS256,
+		SigningKeyFile: writePrivate(t, dir, "rsa", rsaKey),
+		VerifyKeyFiles: []string{writePublic(t, dir, "ed", newEd25519Key(t))},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubPEM, err := os.ReadFile(writePublic(t, t.TempDir(), "rsa", rsaKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKIXPublicKey(rsaKey.Public())
+
+	claims := Claims{UserID: 1, Role: "admin", RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))}}
+	tests := []struct {
+		name   string
+		method jwt.SigningMethod
+		kid    string
+		key    interface{}
+	}{
+		// the classic confusion attack: HMAC keyed with the published public key
+		{"HS256 with the public key PEM", jwt.SigningMethodHS256, "rsa", pubPEM},
+		{"HS256 with the public key DER", jwt.SigningMethodHS256, "rsa", der},
+		{"EdDSA under an RSA kid", jwt.SigningMethodEdDSA, "rsa", newEd25519Key(t)},
+		{"RS256 under an EdDSA kid", jwt.SigningMethodRS256, "ed", rsaKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tok := jwt.NewWithClaims(tt.method, claims)
+			tok.Header["kid"] = tt.kid
+			s, err := tok.SignedString(tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ks.ParseJWT(s); err == nil {
+				t.Fatal("token accepted")
+			}
+		})
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey := newRSAKey(t)
+	edKey := newEd25519Key(t)
+	ks, err := LoadKeySet(KeySetOptions{
+		Alg:            AlgRS256,
+		SigningKeyFile: writePrivate(t, dir, "2025-06", rsaKey),
+		// a private key file is accepted too; only its public half is used
+		VerifyKeyFiles: []string{writePrivate(t, dir, "2025-01", edKey)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b64 := base64.RawURLEncoding.EncodeToString
+	want := []JWK{
+		{Kty: "RSA", Kid: "2025-06", Use: "sig", Alg: AlgRS256, N: b64(rsaKey.N.Bytes()), E: "AQAB"},
+		{Kty: "OKP", Kid: "2025-01", Use: "sig", Alg: AlgEdDSA, Crv: "Ed25519", X: b64(edKey.Public().(ed25519.PublicKey))},
+	}
+	got := ks.JWKS().Keys
+	if len(got) != len(want) {
+		t.Fatalf("JWKS has %d keys, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("key %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// nothing private leaks into the document
+	doc, _ := json.Marshal(ks.JWKS())
+	for _, field := range []string{`"d"`, `"p"`, `"q"`} {
+		if strings.Contains(string(doc), field) {
+			t.Errorf("JWKS contains %s: %s", field, doc)
+		}
+	}
+	// shared secrets are never published
+	if doc, _ := json.Marshal(NewHMACKeySet("secret").JWKS()); string(doc) != `{"keys":[]}` {
+		t.Errorf("HMAC JWKS = %s", doc)
+	}
+}
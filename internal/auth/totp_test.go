@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed of RFC 4226 appendix D and RFC 6238 appendix B.
+var rfcSecret = b32.EncodeToString([]byte("12345678901234567890"))
+
+func TestHOTPVectors(t *testing.T) {
+	// RFC 4226 appendix D, counters 0-9
+	want := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	for c, w := range want {
+		if got := hotp([]byte("12345678901234567890"), int64(c)); got != w {
+			t.Errorf("hotp(%d) = %s, want %s", c, got, w)
+		}
+	}
+}
+
+func TestValidateTOTPVectors(t *testing.T) {
+	// RFC 6238 appendix B (SHA-1); we use six digits, the last six of the table's eight
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		now := time.Unix(tt.unix, 0)
+		step, ok := ValidateTOTP(rfcSecret, tt.code, now)
+		if !ok {
+			t.Errorf("ValidateTOTP(%s at %d) rejected", tt.code, tt.unix)
+			continue
+		}
+		if want := tt.unix / totpPeriod; step != want {
+			t.Errorf("ValidateTOTP(%s at %d) step = %d, want %d", tt.code, tt.unix, step, want)
+		}
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	at := time.Unix(1111111109, 0) // step 37037036, code 081804
+	for _, d := range []time.Duration{-totpPeriod * time.Second, totpPeriod * time.Second} {
+		if _, ok := ValidateTOTP(rfcSecret, "081804", at.Add(d)); !ok {
+			t.Errorf("code rejected %v away from its step", d)
+		}
+	}
+	for _, d := range []time.Duration{-2 * totpPeriod * time.Second, 2 * totpPeriod * time.Second} {
+		if _, ok := ValidateTOTP(rfcSecret, "081804", at.Add(d)); ok {
+			t.Errorf("code accepted %v away from its step", d)
+		}
+	}
+}
+
+func TestValidateTOTPRejects(t *testing.T) {
+	now := time.Unix(59, 0)
+	for _, code := range []string{"", "28708", "2870820", "287083", "abcdef"} {
+		if _, ok := ValidateTOTP(rfcSecret, code, now); ok {
+			t.Errorf("ValidateTOTP(%q) accepted", code)
+		}
+	}
+	if _, ok := ValidateTOTP("not base32!", "287082", now); ok {
+		t.Error("ValidateTOTP accepted a malformed secret")
+	}
+	// lower-case secrets, as some apps display them, still work
+	if _, ok := ValidateTOTP(strings.ToLower(rfcSecret), "287082", now); !ok {
+		t.Error("ValidateTOTP rejected a lower-case secret")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	for _, c := range codes {
+		if len(c) != 11 || c[5] != '-' {
+			t.Errorf("malformed code %q", c)
+		}
+		seen[c] = true
+	}
+	if len(seen) != len(codes) {
+		t.Error("duplicate recovery codes")
+	}
+	if HashRecoveryCode(" ABCDE-FGHJK ") != HashRecoveryCode("abcdefghjk") {
+		t.Error("HashRecoveryCode does not normalize case, dashes and spaces")
+	}
+}
@@ -1,11 +1,19 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 )
 
+// DefaultJWTSecret is the dev-only fallback; Validate rejects it outside dev.
+const DefaultJWTSecret = "dev-secret"
+
+// DefaultAttachmentURLSecret signs download links in dev only.
+const DefaultAttachmentURLSecret = "dev-attachment-secret"
+
 type Config struct {
 	Env           string
 	Port          int
@@ -18,6 +26,44 @@ type Config struct {
 	JWTSecret     string
 	JWTIssuer     string
 	JWTTTLMinutes int
+
+	// Asymmetric signing (RS256/EdDSA). Key IDs are the PEM file names.
+	JWTAlg            string
+	JWTSigningKeyFile string
+	JWTVerifyKeyFiles []string
+
+	// Staff SSO via OpenID Connect; disabled when OIDCIssuer is empty.
+	OIDCIssuer       string
+	OIDCClientID     string
+	OIDCClientSecret string
+	OIDCRedirectURL  string
+
+	// Key-encryption key for field encryption at rest; the dev KEK is used
+	// when empty.
+	FieldKEKFile string
+
+	// Appointment attachments: StorageBackend is "local" (files under
+	// StorageDir) or "s3" (any S3-compatible endpoint, e.g. MinIO).
+	StorageBackend          string
+	StorageDir              string
+	S3Endpoint              string
+	S3Region                string
+	S3Bucket                string
+	S3AccessKey             string
+	S3SecretKey             string
+	AttachmentMaxBytes      int
+	AttachmentURLSecret     string
+	AttachmentURLTTLMinutes int
+
+	// How far ahead GET /v1/providers looks for next_available, and how
+	// long an answer is cached.
+	NextAvailableHorizonDays int
+	NextAvailableTTLSeconds  int
+
+	// Per-patient booking limits; 0 / false turns a check off. Overlapping
+	// visits are always refused (a database constraint since 0021).
+	PatientMaxUpcoming         int
+	PatientOnePerServicePerDay bool
 }
 
 func FromEnv() Config {
@@ -30,10 +76,75 @@ func FromEnv() Config {
 		DBUser:        getenv("DB_USER", "medapp"),
 		DBPassword:    getenv("DB_PASSWORD", "medpass"),
 		DBSSLMode:     getenv("DB_SSLMODE", "disable"),
-		JWTSecret:     getenv("JWT_SECRET", "dev-secret"),
+		JWTSecret:     getenv("JWT_SECRET", DefaultJWTSecret),
 		JWTIssuer:     getenv("JWT_ISSUER", "medappoint"),
 		JWTTTLMinutes: getenvInt("JWT_TTL_MINUTES", 60),
+
+		JWTAlg:            getenv("JWT_ALG", "HS256"),
+		JWTSigningKeyFile: getenv("JWT_SIGNING_KEY_FILE", ""),
+		JWTVerifyKeyFiles: getenvList("JWT_VERIFY_KEY_FILES"),
+
+		OIDCIssuer:       getenv("OIDC_ISSUER", ""),
+		OIDCClientID:     getenv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret: getenv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:  getenv("OIDC_REDIRECT_URL", "http://localhost:8080/v1/auth/oidc/callback"),
+
+		FieldKEKFile: getenv("FIELD_KEK_FILE", ""),
+
+		StorageBackend:          getenv("STORAGE_BACKEND", "local"),
+		StorageDir:              getenv("STORAGE_DIR", "data/attachments"),
+		S3Endpoint:              getenv("S3_ENDPOINT", ""),
+		S3Region:                getenv("S3_REGION", "us-east-1"),
+		S3Bucket:                getenv("S3_BUCKET", ""),
+		S3AccessKey:             getenv("S3_ACCESS_KEY", ""),
+		S3SecretKey:             getenv("S3_SECRET_KEY", ""),
+		AttachmentMaxBytes:      getenvInt("ATTACHMENT_MAX_BYTES", 10<<20),
+		AttachmentURLSecret:     getenv("ATTACHMENT_URL_SECRET", DefaultAttachmentURLSecret),
+		AttachmentURLTTLMinutes: getenvInt("ATTACHMENT_URL_TTL_MINUTES", 15),
+
+		NextAvailableHorizonDays: getenvInt("NEXT_AVAILABLE_HORIZON_DAYS", 30),
+		NextAvailableTTLSeconds:  getenvInt("NEXT_AVAILABLE_TTL_SECONDS", 300),
+
+		PatientMaxUpcoming:         getenvInt("PATIENT_MAX_UPCOMING_APPOINTMENTS", 5),
+		PatientOnePerServicePerDay: getenvBool("PATIENT_ONE_PER_SERVICE_PER_DAY", true),
+	}
+}
+
+// IsDev reports whether we run with developer defaults allowed.
+func (c Config) IsDev() bool {
+	return c.Env == "" || c.Env == "dev"
+}
+
+// Validate refuses configurations that are only safe on a laptop.
+func (c Config) Validate() error {
+	if c.OIDCIssuer != "" && c.OIDCClientID == "" {
+		return errors.New("OIDC_CLIENT_ID is required when OIDC_ISSUER is set")
+	}
+	switch c.StorageBackend {
+	case "local":
+	case "s3":
+		if c.S3Endpoint == "" || c.S3Bucket == "" {
+			return errors.New("S3_ENDPOINT and S3_BUCKET are required when STORAGE_BACKEND=s3")
+		}
+	default:
+		return fmt.Errorf("STORAGE_BACKEND must be local or s3, got %q", c.StorageBackend)
+	}
+	if c.NextAvailableHorizonDays < 1 || c.NextAvailableHorizonDays > 365 {
+		return errors.New("NEXT_AVAILABLE_HORIZON_DAYS must be between 1 and 365")
+	}
+	if c.IsDev() {
+		return nil
+	}
+	if c.JWTAlg == "HS256" && (c.JWTSecret == "" || c.JWTSecret == DefaultJWTSecret) {
+		return errors.New("JWT_SECRET must be set to a non-default value when APP_ENV is not dev")
+	}
+	if c.FieldKEKFile == "" {
+		return errors.New("FIELD_KEK_FILE is required when APP_ENV is not dev")
+	}
+	if c.AttachmentURLSecret == "" || c.AttachmentURLSecret == DefaultAttachmentURLSecret {
+		return errors.New("ATTACHMENT_URL_SECRET must be set to a non-default value when APP_ENV is not dev")
 	}
+	return nil
 }
 
 func (c Config) PGConnString(hostOverride string) string {
@@ -53,6 +164,23 @@ func getenv(key, def string) string {
 	}
 	return def
 }
+func getenvList(key string) []string {
+	var out []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+func getenvBool(key string, def bool) bool {
+	if v := os.Getenv(key); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return def
+}
 func getenvInt(key string, def int) int {
 	if v := os.Getenv(key); v != "" {
 		if n, err := strconv.Atoi(v); err == nil {
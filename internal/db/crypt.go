@@ -0,0 +1,346 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/justanamir/medappoint/internal/db/gen"
+	"github.com/justanamir/medappoint/internal/fieldcrypt"
+)
+
+// Encrypted fields are named "table.column"; the name is the associated
+// data each value is sealed with (see fieldcrypt). searchableFields carry a
+// blind index for exact-match search.
+var searchableFields = map[string]bool{
+	"patients.phone": true,
+	"patients.dob":   true,
+}
+
+var (
+	patientFields     = map[string]string{"phone": "patients.phone", "dob": "patients.dob"}
+	appointmentFields = map[string]string{"notes": "appointments.notes"}
+	intakeFields      = map[string]string{"answers": "intake_responses.answers"}
+	visitNoteFields   = map[string]string{"body": "visit_notes.body"}
+	attachmentFields  = map[string]string{"filename": "attachments.filename"}
+)
+
+// encryptedResults lists, per sqlc query name, the result columns decrypted
+// on the way out and the field each was sealed as. A new query returning an
+// encrypted column must be added here, or its callers get ciphertext.
+var encryptedResults = map[string]map[string]string{
+	"CreatePatient":        patientFields,
+	"GetPatientByUserID":   patientFields,
+	"ListPatientsByIDs":    patientFields,
+	"SearchPatients":       patientFields,
+	"UpdatePatientProfile": patientFields,
+	"CreateDependent":      patientFields,
+	"ListDependents":       patientFields,
+
+	"CreateAppointment":                appointmentFields,
+	"GetAppointment":                   appointmentFields,
+	"CancelAppointment":                appointmentFields,
+	"CompleteAppointment":              appointmentFields,
+	"RescheduleAppointment":            appointmentFields,
+	"ListAllAppointmentsOnDate":        appointmentFields,
+	"ListAppointmentsByPatients":       appointmentFields,
+	"ListAppointmentsByProviderOnDate": appointmentFields,
+
+	"UpsertIntakeResponse":              intakeFields,
+	"GetIntakeResponseByAppointment":    intakeFields,
+	"ListIntakeResponsesByAppointments": intakeFields,
+
+	"CreateVisitNote": visitNoteFields,
+	"SignVisitNote":   visitNoteFields,
+	"ListVisitNotes":  visitNoteFields,
+
+	"CreateAttachment":              attachmentFields,
+	"GetAttachment":                 attachmentFields,
+	"ListAttachmentsByAppointment":  attachmentFields,
+	"ListAttachmentsByAppointments": attachmentFields,
+
+	// 24h, not re-sealed by cmd/reencrypt
+	"GetIdempotencyKey": {"response_body": "idempotency_keys.response_body"},
+}
+
+type paramRule struct {
+	field string
+	index bool // replace with the blind index instead of sealing
+}
+
+// encryptedParams lists, per sqlc query name, the 1-based arguments that
+// write or search an encrypted column. A new query touching one of those
+// columns must be added here: sealArgs refuses to run a query that binds an
+// argument into an encrypted column (see boundWrites) without a matching
+// rule, rather than store it in plaintext.
+var encryptedParams = map[string]map[int]paramRule{
+	"CreatePatient":          {3: {field: "patients.phone"}, 4: {field: "patients.dob"}},
+	"UpdatePatientProfile":   {2: {field: "patients.phone"}, 3: {field: "patients.dob"}},
+	"CreateDependent":        {2: {field: "patients.phone"}, 3: {field: "patients.dob"}},
+	"SearchPatients":         {2: {field: "patients.phone", index: true}, 3: {field: "patients.dob", index: true}},
+	"CreateAppointment":      {7: {field: "appointments.notes"}},
+	"UpsertIntakeResponse":   {3: {field: "intake_responses.answers"}},
+	"CreateVisitNote":        {2: {field: "visit_notes.body"}},
+	"CreateAttachment":       {3: {field: "attachments.filename"}},
+	"SaveIdempotentResponse": {2: {field: "idempotency_keys.response_body"}},
+}
+
+// Encrypted wraps a pool or transaction so sqlc queries read and write the
+// encrypted columns as plaintext. Use it for everything except the
+// re-encryption command, which handles ciphertext itself.
+func Encrypted(db gen.DBTX, kr *fieldcrypt.Keyring) gen.DBTX {
+	return &cryptDB{db: db, kr: kr}
+}
+
+type cryptDB struct {
+	db gen.DBTX
+	kr *fieldcrypt.Keyring
+}
+
+func (c *cryptDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	args, err := c.sealArgs(sql, args)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return c.db.Exec(ctx, sql, args...)
+}
+
+func (c *cryptDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	args, err := c.sealArgs(sql, args)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := c.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &cryptRows{Rows: rows, kr: c.kr, fields: encryptedResults[queryName(sql)]}, nil
+}
+
+func (c *cryptDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	rows, err := c.Query(ctx, sql, args...)
+	return &cryptRow{rows: rows, err: err}
+}
+
+// sealArgs encrypts (or blind-indexes) the arguments registered for the
+// query named in sqlc's leading "-- name: X :kind" comment.
+func (c *cryptDB) sealArgs(sql string, args []interface{}) ([]interface{}, error) {
+	name := queryName(sql)
+	rules := encryptedParams[name]
+	if err := checkRules(name, sql, rules); err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return args, nil
+	}
+	out := make([]interface{}, len(args))
+	copy(out, args)
+	for pos, rule := range rules {
+		if pos < 1 || pos > len(out) {
+			continue
+		}
+		pt, ok, err := plaintextArg(out[pos-1])
+		if err != nil {
+			return nil, fmt.Errorf("%s arg %d: %w", rule.field, pos, err)
+		}
+		if !ok {
+			out[pos-1] = nil
+			continue
+		}
+		if rule.index {
+			out[pos-1] = c.kr.BlindIndex(rule.field, pt)
+			continue
+		}
+		ct, err := c.kr.Encrypt(rule.field, pt, searchableFields[rule.field])
+		if err != nil {
+			return nil, err
+		}
+		out[pos-1] = ct
+	}
+	return out, nil
+}
+
+// checkRules fails closed when the query writes an argument into an
+// encrypted column that its rules would not seal: a query missing from
+// encryptedParams, or one whose parameters moved.
+func checkRules(name, sql string, rules map[int]paramRule) error {
+	if ciphertextQueries[name] {
+		return nil
+	}
+	for pos, col := range boundWrites(sql) {
+		if r, ok := rules[pos]; !ok || r.field != col || r.index {
+			if name == "" {
+				name = "unnamed query"
+			}
+			return fmt.Errorf("db: %s writes $%d into encrypted column %s without a matching encryptedParams rule", name, pos, col)
+		}
+	}
+	return nil
+}
+
+func queryName(sql string) string {
+	const marker = "-- name: "
+	if !strings.HasPrefix(sql, marker) {
+		return ""
+	}
+	rest := sql[len(marker):]
+	if i := strings.IndexAny(rest, " \n"); i >= 0 {
+		return rest[:i]
+	}
+	return rest
+}
+
+// plaintextArg turns the Go types sqlc uses for these columns into the
+// string that gets sealed; ok is false for SQL NULL.
+func plaintextArg(v interface{}) (string, bool, error) {
+	switch x := v.(type) {
+	case nil:
+		return "", false, nil
+	case string:
+		return x, true, nil
+	case *string:
+		if x == nil {
+			return "", false, nil
+		}
+		return *x, true, nil
+	case pgtype.Text:
+		return x.String, x.Valid, nil
+	case pgtype.Date:
+		return x.Time.Format("2006-01-02"), x.Valid, nil
+	default:
+		return "", false, fmt.Errorf("unsupported type %T", v)
+	}
+}
+
+type cryptRows struct {
+	pgx.Rows
+	kr     *fieldcrypt.Keyring
+	fields map[string]string // result column -> field, from encryptedResults
+}
+
+func (r *cryptRows) encryptedIndexes() []int {
+	if len(r.fields) == 0 {
+		return nil
+	}
+	var idx []int
+	for i, fd := range r.FieldDescriptions() {
+		if _, ok := r.fields[fd.Name]; ok {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+func (r *cryptRows) Scan(dest ...interface{}) error {
+	idx := r.encryptedIndexes()
+	if len(idx) == 0 {
+		return r.Rows.Scan(dest...)
+	}
+	raw := make([]pgtype.Text, len(idx))
+	swapped := make([]interface{}, len(dest))
+	copy(swapped, dest)
+	for j, i := range idx {
+		if i < len(swapped) {
+			swapped[i] = &raw[j]
+		}
+	}
+	if err := r.Rows.Scan(swapped...); err != nil {
+		return err
+	}
+	fds := r.FieldDescriptions()
+	for j, i := range idx {
+		if i >= len(dest) {
+			continue
+		}
+		field := r.fields[fds[i].Name]
+		var pt string
+		if raw[j].Valid {
+			var err error
+			if pt, err = r.kr.Decrypt(field, raw[j].String); err != nil {
+				return err
+			}
+		}
+		if err := assignPlaintext(dest[i], pt, raw[j].Valid); err != nil {
+			return fmt.Errorf("%s: %w", field, err)
+		}
+	}
+	return nil
+}
+
+func (r *cryptRows) Values() ([]interface{}, error) {
+	vals, err := r.Rows.Values()
+	if err != nil {
+		return nil, err
+	}
+	fds := r.FieldDescriptions()
+	for _, i := range r.encryptedIndexes() {
+		if s, ok := vals[i].(string); ok {
+			if vals[i], err = r.kr.Decrypt(r.fields[fds[i].Name], s); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return vals, nil
+}
+
+func assignPlaintext(dest interface{}, pt string, valid bool) error {
+	switch d := dest.(type) {
+	case *string:
+		*d = pt
+	case **string:
+		if !valid {
+			*d = nil
+			return nil
+		}
+		*d = &pt
+	case *pgtype.Text:
+		*d = pgtype.Text{String: pt, Valid: valid}
+	case *pgtype.Date:
+		if !valid {
+			*d = pgtype.Date{}
+			return nil
+		}
+		t, err := time.Parse("2006-01-02", pt)
+		if err != nil {
+			return err
+		}
+		*d = pgtype.Date{Time: t, Valid: true}
+	default:
+		return fmt.Errorf("unsupported scan target %T", dest)
+	}
+	return nil
+}
+
+// cryptRow mirrors pgx's QueryRow semantics on top of cryptRows.
+type cryptRow struct {
+	rows pgx.Rows
+	err  error
+}
+
+func (r *cryptRow) Scan(dest ...interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	defer r.rows.Close()
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return pgx.ErrNoRows
+	}
+	if err := r.rows.Scan(dest...); err != nil {
+		return err
+	}
+	r.rows.Close()
+	return r.rows.Err()
+}
+
+// Searchable reports whether the encrypted field ("table.column") carries a
+// blind index.
+func Searchable(field string) bool {
+	return searchableFields[field]
+}
@@ -0,0 +1,395 @@
+package db
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/justanamir/medappoint/internal/db/gen"
+	"github.com/justanamir/medappoint/internal/fieldcrypt"
+)
+
+// fakeDB records what reaches the database and returns no rows.
+type fakeDB struct {
+	sql  string
+	args []interface{}
+}
+
+func (f *fakeDB) Exec(_ context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	f.sql, f.args = sql, args
+	return pgconn.CommandTag{}, nil
+}
+
+func (f *fakeDB) Query(_ context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	f.sql, f.args = sql, args
+	return emptyRows{}, nil
+}
+
+func (f *fakeDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	rows, _ := f.Query(ctx, sql, args...)
+	return &cryptRow{rows: rows}
+}
+
+type emptyRows struct{}
+
+func (emptyRows) Close()                                       {}
+func (emptyRows) Err() error                                   { return nil }
+func (emptyRows) CommandTag() pgconn.CommandTag                { return pgconn.CommandTag{} }
+func (emptyRows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+func (emptyRows) Next() bool                                   { return false }
+func (emptyRows) Scan(...interface{}) error                    { return pgx.ErrNoRows }
+func (emptyRows) Values() ([]interface{}, error)               { return nil, nil }
+func (emptyRows) RawValues() [][]byte                          { return nil }
+func (emptyRows) Conn() *pgx.Conn                              { return nil }
+
+// recorder sits above cryptDB and keeps the arguments as sqlc passed them.
+type recorder struct {
+	next gen.DBTX
+	args []interface{}
+}
+
+func (r *recorder) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	r.args = args
+	return r.next.Exec(ctx, sql, args...)
+}
+
+func (r *recorder) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	r.args = args
+	return r.next.Query(ctx, sql, args...)
+}
+
+func (r *recorder) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	r.args = args
+	return r.next.QueryRow(ctx, sql, args...)
+}
+
+func testKeyring(t *testing.T) *fieldcrypt.Keyring {
+	t.Helper()
+	dk, _ := fieldcrypt.NewKey()
+	ik, _ := fieldcrypt.NewKey()
+	kr, err := fieldcrypt.NewKeyring(map[int32][]byte{1: dk}, ik)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kr
+}
+
+// fill gives every argument a non-NULL value, so nothing slips through as
+// NULL (which is never sealed).
+func fill(v reflect.Value, name string) {
+	switch v.Interface().(type) {
+	case time.Time:
+		v.Set(reflect.ValueOf(time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)))
+		return
+	case pgtype.Date:
+		v.Set(reflect.ValueOf(pgtype.Date{Time: time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC), Valid: true}))
+		return
+	case pgtype.Text:
+		v.Set(reflect.ValueOf(pgtype.Text{String: "plain " + name, Valid: true}))
+		return
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString("plain " + name)
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(7)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(7)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes([]byte(`{"plain":"` + name + `"}`))
+			return
+		}
+		s := reflect.MakeSlice(v.Type(), 1, 1)
+		fill(s.Index(0), name)
+		v.Set(s)
+	case reflect.Ptr:
+		p := reflect.New(v.Type().Elem())
+		fill(p.Elem(), name)
+		v.Set(p)
+	case reflect.Struct:
+		if f := v.FieldByName("Valid"); f.IsValid() && f.Kind() == reflect.Bool {
+			// pgtype scalars: set the value field too where there is one
+			for i := 0; i < v.NumField(); i++ {
+				if v.Type().Field(i).IsExported() {
+					fill(v.Field(i), name)
+				}
+			}
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				fill(v.Field(i), v.Type().Field(i).Name)
+			}
+		}
+	}
+}
+
+// TestEveryQuerySealsEncryptedWrites calls every generated query through
+// Encrypted and checks that each argument bound into an encrypted column
+// reaches the database sealed, and that the rules in encryptedParams agree
+// with where sqlc actually put the parameters.
+func TestEveryQuerySealsEncryptedWrites(t *testing.T) {
+	kr := testKeyring(t)
+	ctxType := reflect.TypeOf((*context.Context)(nil)).Elem()
+
+	writers := map[string]bool{}
+	q := reflect.ValueOf(gen.New(nil))
+	for i := 0; i < q.NumMethod(); i++ {
+		m := q.Type().Method(i)
+		mt := q.Method(i).Type()
+		if mt.NumIn() == 0 || mt.In(0) != ctxType {
+			continue
+		}
+		t.Run(m.Name, func(t *testing.T) {
+			fake := &fakeDB{}
+			rec := &recorder{next: Encrypted(fake, kr)}
+			in := []reflect.Value{reflect.ValueOf(context.Background())}
+			for j := 1; j < mt.NumIn(); j++ {
+				v := reflect.New(mt.In(j)).Elem()
+				fill(v, m.Name)
+				in = append(in, v)
+			}
+			out := reflect.ValueOf(gen.New(rec)).MethodByName(m.Name).Call(in)
+			if err, _ := out[len(out)-1].Interface().(error); err != nil && err != pgx.ErrNoRows {
+				t.Fatalf("call failed: %v", err)
+			}
+			if name := queryName(fake.sql); name != m.Name {
+				t.Fatalf("query is named %q in its SQL", name)
+			}
+			if ciphertextQueries[m.Name] {
+				return
+			}
+
+			bound := boundWrites(fake.sql)
+			if len(bound) > 0 {
+				writers[m.Name] = true
+			}
+			for pos, field := range bound {
+				orig, ok, err := plaintextArg(rec.args[pos-1])
+				if err != nil || !ok {
+					t.Fatalf("$%d (%s): unexpected argument %#v", pos, field, rec.args[pos-1])
+				}
+				got, isString := fake.args[pos-1].(string)
+				if !isString || !fieldcrypt.IsEncrypted(got) {
+					t.Fatalf("$%d (%s) reached the database unsealed: %#v", pos, field, fake.args[pos-1])
+				}
+				pt, err := kr.Decrypt(field, got)
+				if err != nil || pt != orig {
+					t.Fatalf("$%d (%s) does not decrypt to the original: %q, %v", pos, field, pt, err)
+				}
+			}
+			// search rules must point at arguments that became blind indexes
+			for pos, rule := range encryptedParams[m.Name] {
+				if !rule.index {
+					continue
+				}
+				orig, _, _ := plaintextArg(rec.args[pos-1])
+				if fake.args[pos-1] != kr.BlindIndex(rule.field, orig) {
+					t.Fatalf("$%d (%s) is not the blind index", pos, rule.field)
+				}
+			}
+		})
+	}
+
+	// the analysis must keep seeing the writes we know about
+	for name, rules := range encryptedParams {
+		for _, rule := range rules {
+			if !rule.index && !writers[name] {
+				t.Errorf("%s has sealing rules but no encrypted write was found in its SQL", name)
+			}
+		}
+	}
+}
+
+func TestUnregisteredEncryptedWriteFails(t *testing.T) {
+	kr := testKeyring(t)
+	fake := &fakeDB{}
+	db := Encrypted(fake, kr)
+	ctx := context.Background()
+
+	tests := []struct{ name, sql string }{
+		{"missing rule", "-- name: UpdateAppointmentNotes :exec\nUPDATE appointments SET notes = $1, updated_at = NOW() WHERE id = $2"},
+		{"moved parameter", "-- name: CreateVisitNote :one\nINSERT INTO visit_notes (appointment_id, body, version) VALUES ($1, $3, $2)"},
+		{"upsert", "-- name: SetPatientPhone :exec\nINSERT INTO patients (id, full_name) VALUES ($1, $2)\nON CONFLICT (id) DO UPDATE SET phone = $3"},
+		{"unnamed", "UPDATE patients p SET dob = $1 WHERE id = $2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake.sql = ""
+			_, err := db.Exec(ctx, tt.sql, "a", "b", "c")
+			if err == nil || !strings.Contains(err.Error(), "encryptedParams") {
+				t.Fatalf("got %v, want a refusal", err)
+			}
+			if fake.sql != "" {
+				t.Fatal("query reached the database")
+			}
+		})
+	}
+
+	// literals and other tables are fine without rules
+	for _, sql := range []string{
+		"-- name: X :exec\nUPDATE patients SET full_name = 'Erased', phone = NULL, dob = NULL WHERE id = $1",
+		"-- name: Y :exec\nUPDATE appointments SET status = 'cancelled', cancellation_reason = $1 WHERE id = $2",
+	} {
+		if _, err := db.Exec(ctx, sql, "a", "b"); err != nil {
+			t.Errorf("%s: %v", sql, err)
+		}
+	}
+}
+
+func TestBoundWrites(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want map[int]string
+	}{
+		{"INSERT INTO patients (user_id, full_name, phone, dob) VALUES ($1, $2, $3, $4)", map[int]string{3: "patients.phone", 4: "patients.dob"}},
+		{"INSERT INTO visit_notes (appointment_id, version, body) SELECT $1::bigint, COALESCE(MAX(version), 0) + 1, $2::text FROM visit_notes", map[int]string{2: "visit_notes.body"}},
+		{"WITH a AS (\n  UPDATE patients\n  SET phone = COALESCE($2, phone), full_name = 'x, y' -- phone = $9\n  WHERE id = $1\n) SELECT 1", map[int]string{2: "patients.phone"}},
+		{"INSERT INTO intake_responses (appointment_id, answers) VALUES ($1, $2) ON CONFLICT (appointment_id) DO UPDATE SET answers = EXCLUDED.answers WHERE intake_responses.id = $3", map[int]string{2: "intake_responses.answers"}},
+		{"UPDATE idempotency_keys SET status_code = $1, response_body = $2 WHERE user_id = $3", map[int]string{2: "idempotency_keys.response_body"}},
+		{"UPDATE users SET email = $1 WHERE id = $2", map[int]string{}},
+	}
+	for _, tt := range tests {
+		if got := boundWrites(tt.sql); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("boundWrites(%q) = %v, want %v", tt.sql, got, tt.want)
+		}
+	}
+}
+
+// TestEncryptedResultsCoverEveryQuery checks encryptedResults against the
+// generated result types: every query that scans an encrypted column must
+// decrypt it as that table's field, and every entry must match a column the
+// query returns.
+func TestEncryptedResultsCoverEveryQuery(t *testing.T) {
+	fields := map[string]bool{}
+	for table, cols := range encryptedTables {
+		for _, col := range cols {
+			fields[table+"."+col] = true
+		}
+	}
+
+	q := reflect.TypeOf(gen.New(nil))
+	for i := 0; i < q.NumMethod(); i++ {
+		m := q.Method(i)
+		if m.Type.NumOut() != 2 {
+			continue
+		}
+		rt := m.Type.Out(0)
+		if rt.Kind() == reflect.Slice {
+			rt = rt.Elem()
+		}
+		returned := map[string]bool{}
+		if rt.Kind() == reflect.Struct {
+			for j := 0; j < rt.NumField(); j++ {
+				returned[rt.Field(j).Tag.Get("json")] = true
+			}
+		}
+		want := encryptedResults[m.Name]
+		for _, cols := range encryptedTables {
+			for _, col := range cols {
+				if returned[col] && want[col] == "" {
+					t.Errorf("%s returns %s but has no encryptedResults entry for it", m.Name, col)
+				}
+			}
+		}
+		for col, field := range want {
+			if !returned[col] {
+				t.Errorf("%s: encryptedResults lists %s, which the query does not return", m.Name, col)
+			}
+			if !fields[field] || !strings.HasSuffix(field, "."+col) {
+				t.Errorf("%s: %s is decrypted as %q, not an encrypted field for that column", m.Name, col, field)
+			}
+		}
+	}
+	for name := range encryptedResults {
+		if _, ok := q.MethodByName(name); !ok {
+			t.Errorf("encryptedResults lists unknown query %s", name)
+		}
+	}
+}
+
+// textRows returns one row of text values under the given column names.
+type textRows struct {
+	emptyRows
+	cols []string
+	vals []string
+	done bool
+}
+
+func (r *textRows) FieldDescriptions() []pgconn.FieldDescription {
+	fds := make([]pgconn.FieldDescription, len(r.cols))
+	for i, c := range r.cols {
+		fds[i].Name = c
+	}
+	return fds
+}
+
+func (r *textRows) Next() bool {
+	next := !r.done
+	r.done = true
+	return next
+}
+
+func (r *textRows) Scan(dest ...interface{}) error {
+	for i, d := range dest {
+		switch d := d.(type) {
+		case *pgtype.Text:
+			*d = pgtype.Text{String: r.vals[i], Valid: true}
+		case *string:
+			*d = r.vals[i]
+		}
+	}
+	return nil
+}
+
+type rowsDB struct {
+	fakeDB
+	rows *textRows
+}
+
+func (f *rowsDB) Query(context.Context, string, ...interface{}) (pgx.Rows, error) {
+	return f.rows, nil
+}
+
+func TestDecryptsResultsByQuery(t *testing.T) {
+	kr := testKeyring(t)
+	notes, _ := kr.Encrypt("appointments.notes", "allergic to penicillin", false)
+	body, _ := kr.Encrypt("visit_notes.body", "allergic to penicillin", false)
+
+	tests := []struct {
+		name    string
+		sql     string
+		col     string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"mapped column", "-- name: GetAppointment :one\nSELECT notes FROM appointments", "notes", notes, "allergic to penicillin", false},
+		{"column of another query", "-- name: ListProviderNotes :many\nSELECT notes FROM providers", "notes", notes, notes, false},
+		{"value copied from another table", "-- name: GetAppointment :one\nSELECT notes FROM appointments", "notes", body, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := Encrypted(&rowsDB{rows: &textRows{cols: []string{tt.col}, vals: []string{tt.value}}}, kr)
+			var got string
+			err := db.QueryRow(context.Background(), tt.sql).Scan(&got)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %q, want an error", got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("got %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
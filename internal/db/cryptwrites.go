@@ -0,0 +1,220 @@
+package db
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// encryptedTables are the tables holding encrypted columns, and which. This
+// is what sealArgs checks a query's rules against.
+var encryptedTables = map[string][]string{
+	"patients":         {"phone", "dob"},
+	"appointments":     {"notes"},
+	"intake_responses": {"answers"},
+	"visit_notes":      {"body"},
+	"attachments":      {"filename"},
+	"idempotency_keys": {"response_body"},
+}
+
+// ciphertextQueries write values that are sealed already (the
+// re-encryption command reads and writes ciphertext itself).
+var ciphertextQueries = map[string]bool{
+	"UpdatePatientCiphertexts":           true,
+	"UpdateAppointmentNoteCiphertext":    true,
+	"UpdateIntakeAnswerCiphertext":       true,
+	"UpdateVisitNoteCiphertext":          true,
+	"UpdateAttachmentFilenameCiphertext": true,
+}
+
+var (
+	sqlComment  = regexp.MustCompile(`--[^\n]*`)
+	insertInto  = regexp.MustCompile(`(?i)\bINSERT\s+INTO\s+([a-z_]+)\s*\(`)
+	updateSet   = regexp.MustCompile(`(?i)\bUPDATE\s+([a-z_]+)(?:\s+(?:AS\s+)?[a-z_]+)?\s+SET\b`)
+	conflictSet = regexp.MustCompile(`(?i)\bDO\s+UPDATE\s+SET\b`)
+	placeholder = regexp.MustCompile(`\$(\d+)`)
+)
+
+// boundWrites maps each $n placeholder that sqlc binds into an encrypted
+// column, in an INSERT column list or an UPDATE ... SET, to that column's
+// field ("table.column").
+// It understands the shapes our queries use (VALUES, INSERT ... SELECT,
+// UPDATE and ON CONFLICT DO UPDATE, also inside CTEs); it is not a general
+// SQL parser.
+func boundWrites(sql string) map[int]string {
+	if v, ok := boundWritesCache.Load(sql); ok {
+		return v.(map[int]string)
+	}
+	out := map[int]string{}
+	s := sqlComment.ReplaceAllString(sql, "")
+	bind := func(table, col, expr string) {
+		if !isEncrypted(table, col) {
+			return
+		}
+		for _, m := range placeholder.FindAllStringSubmatch(expr, -1) {
+			n, _ := strconv.Atoi(m[1])
+			out[n] = table + "." + col
+		}
+	}
+
+	for _, m := range insertInto.FindAllStringSubmatchIndex(s, -1) {
+		table := strings.ToLower(s[m[2]:m[3]])
+		colsEnd := matchParen(s, m[1]-1)
+		if colsEnd < 0 {
+			continue
+		}
+		cols := splitTop(s[m[1]:colsEnd])
+		rest := strings.TrimLeft(s[colsEnd+1:], " \t\r\n")
+		var exprs []string
+		switch {
+		case hasKeyword(rest, "VALUES"):
+			open := strings.IndexByte(rest, '(')
+			if end := matchParen(rest, open); open >= 0 && end > 0 {
+				exprs = splitTop(rest[open+1 : end])
+			}
+		case hasKeyword(rest, "SELECT"):
+			body := rest[len("SELECT"):]
+			exprs = splitTop(body[:clauseEnd(body, "FROM", "WHERE", "RETURNING", "ON")])
+		}
+		for i, col := range cols {
+			if i < len(exprs) {
+				bind(table, strings.ToLower(strings.TrimSpace(col)), exprs[i])
+			}
+		}
+		// ON CONFLICT ... DO UPDATE SET belongs to this insert's table
+		tail := s[colsEnd+1:]
+		if next := insertInto.FindStringIndex(tail); next != nil {
+			tail = tail[:next[0]]
+		}
+		if c := conflictSet.FindStringIndex(tail); c != nil {
+			bindSet(tail[c[1]:], table, bind)
+		}
+	}
+	for _, m := range updateSet.FindAllStringSubmatchIndex(s, -1) {
+		bindSet(s[m[1]:], strings.ToLower(s[m[2]:m[3]]), bind)
+	}
+	boundWritesCache.Store(sql, out)
+	return out
+}
+
+var boundWritesCache sync.Map // query text -> map[int]string
+
+func bindSet(s, table string, bind func(table, col, expr string)) {
+	for _, a := range splitTop(s[:clauseEnd(s, "FROM", "WHERE", "RETURNING")]) {
+		col, expr, ok := strings.Cut(a, "=")
+		if !ok {
+			continue
+		}
+		col = strings.TrimSpace(col)
+		if i := strings.LastIndexByte(col, '.'); i >= 0 {
+			col = col[i+1:]
+		}
+		bind(table, strings.ToLower(col), expr)
+	}
+}
+
+func isEncrypted(table, col string) bool {
+	for _, c := range encryptedTables[table] {
+		if c == col {
+			return true
+		}
+	}
+	return false
+}
+
+// matchParen returns the index of the parenthesis closing the one at open.
+func matchParen(s string, open int) int {
+	if open < 0 || open >= len(s) || s[open] != '(' {
+		return -1
+	}
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '\'':
+			i = skipString(s, i)
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitTop splits s on commas outside parentheses and string literals.
+func splitTop(s string) []string {
+	var out []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\'':
+			i = skipString(s, i)
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				out = append(out, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(out, s[start:])
+}
+
+// clauseEnd is where the clause starting s stops: at one of the keywords,
+// a semicolon, or the parenthesis closing an enclosing CTE, all at depth 0.
+func clauseEnd(s string, keywords ...string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\'':
+			i = skipString(s, i)
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		case c == ';' && depth == 0:
+			return i
+		case depth == 0 && (i == 0 || !isWordByte(s[i-1])):
+			for _, kw := range keywords {
+				if hasKeyword(s[i:], kw) {
+					return i
+				}
+			}
+		}
+	}
+	return len(s)
+}
+
+// hasKeyword reports whether s starts with the keyword as a whole word.
+func hasKeyword(s, kw string) bool {
+	return len(s) >= len(kw) && strings.EqualFold(s[:len(kw)], kw) &&
+		(len(s) == len(kw) || !isWordByte(s[len(kw)]))
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// skipString returns the index of the quote closing the literal at i.
+func skipString(s string, i int) int {
+	for j := i + 1; j < len(s); j++ {
+		if s[j] == '\'' {
+			if j+1 < len(s) && s[j+1] == '\'' {
+				j++
+				continue
+			}
+			return j
+		}
+	}
+	return len(s)
+}
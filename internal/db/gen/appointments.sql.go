@@ -8,19 +8,93 @@ package gen
 import (
 	"context"
 	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const cancelAppointment = `-- name: CancelAppointment :one
+WITH appt AS (
+  UPDATE appointments
+  SET status = 'cancelled',
+      cancellation_reason = $1,
+      cancelled_at = NOW(),
+      cancelled_by = $2,
+      updated_at = NOW()
+  WHERE id = $3 AND status = 'scheduled'
+  RETURNING
+    id, clinic_id, provider_id, patient_id, service_id,
+    start_time, end_time, status, notes, created_at, updated_at,
+    cancellation_reason, cancelled_at, cancelled_by
+), released AS (
+  DELETE FROM appointment_resources WHERE appointment_id IN (SELECT id FROM appt)
+)
+SELECT
+  id, clinic_id, provider_id, patient_id, service_id,
+  start_time, end_time, status, notes, created_at, updated_at,
+  cancellation_reason, cancelled_at, cancelled_by
+FROM appt
+`
+
+type CancelAppointmentParams struct {
+	Reason      *string     `json:"reason"`
+	CancelledBy pgtype.Int8 `json:"cancelled_by"`
+	ID          int64       `json:"id"`
+}
+
+type CancelAppointmentRow struct {
+	ID                 int64              `json:"id"`
+	ClinicID           int64              `json:"clinic_id"`
+	ProviderID         int64              `json:"provider_id"`
+	PatientID          int64              `json:"patient_id"`
+	ServiceID          int64              `json:"service_id"`
+	StartTime          time.Time          `json:"start_time"`
+	EndTime            time.Time          `json:"end_time"`
+	Status             string             `json:"status"`
+	Notes              *string            `json:"notes"`
+	CreatedAt          time.Time          `json:"created_at"`
+	UpdatedAt          time.Time          `json:"updated_at"`
+	CancellationReason *string            `json:"cancellation_reason"`
+	CancelledAt        pgtype.Timestamptz `json:"cancelled_at"`
+	CancelledBy        pgtype.Int8        `json:"cancelled_by"`
+}
+
+// Also releases any rooms/equipment the appointment held.
+func (q *Queries) CancelAppointment(ctx context.Context, arg CancelAppointmentParams) (CancelAppointmentRow, error) {
+	row := q.db.QueryRow(ctx, cancelAppointment, arg.Reason, arg.CancelledBy, arg.ID)
+	var i CancelAppointmentRow
+	err := row.Scan(
+		&i.ID,
+		&i.ClinicID,
+		&i.ProviderID,
+		&i.PatientID,
+		&i.ServiceID,
+		&i.StartTime,
+		&i.EndTime,
+		&i.Status,
+		&i.Notes,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CancellationReason,
+		&i.CancelledAt,
+		&i.CancelledBy,
+	)
+	return i, err
+}
+
+const completeAppointment = `-- name: CompleteAppointment :one
 UPDATE appointments
-SET status = 'cancelled', updated_at = NOW()
-WHERE id = $1 AND status = 'scheduled'
+SET status = 'completed',
+    updated_at = NOW()
+WHERE id = $1 AND status = 'scheduled' AND start_time <= NOW()
 RETURNING
   id, clinic_id, provider_id, patient_id, service_id,
-  start_time, end_time, status, notes, created_at, updated_at
+  start_time, end_time, status, notes, created_at, updated_at,
+  cancellation_reason, cancelled_at, cancelled_by
 `
 
-func (q *Queries) CancelAppointment(ctx context.Context, id int64) (Appointment, error) {
-	row := q.db.QueryRow(ctx, cancelAppointment, id)
+// Only once the visit has started; cancelled/completed rows don't match.
+func (q *Queries) CompleteAppointment(ctx context.Context, id int64) (Appointment, error) {
+	row := q.db.QueryRow(ctx, completeAppointment, id)
 	var i Appointment
 	err := row.Scan(
 		&i.ID,
@@ -34,29 +108,117 @@ func (q *Queries) CancelAppointment(ctx context.Context, id int64) (Appointment,
 		&i.Notes,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.CancellationReason,
+		&i.CancelledAt,
+		&i.CancelledBy,
 	)
 	return i, err
 }
 
-const createAppointment = `-- name: CreateAppointment :one
+const countPatientBookingConflicts = `-- name: CountPatientBookingConflicts :one
+SELECT
+  COUNT(*) FILTER (WHERE start_time > now()) AS upcoming,
+  COUNT(*) FILTER (
+    WHERE service_id = $1
+      AND start_time >= $2 AND start_time < $3
+  ) AS same_service_day,
+  COUNT(*) FILTER (
+    WHERE start_time < $4 AND end_time > $5
+  ) AS overlapping
+FROM appointments
+WHERE patient_id = $6
+  AND status = 'scheduled'
+  AND id <> $7::bigint
+`
+
+type CountPatientBookingConflictsParams struct {
+	ServiceID int64     `json:"service_id"`
+	DayStart  time.Time `json:"day_start"`
+	DayEnd    time.Time `json:"day_end"`
+	EndTime   time.Time `json:"end_time"`
+	StartTime time.Time `json:"start_time"`
+	PatientID int64     `json:"patient_id"`
+	ExcludeID int64     `json:"exclude_id"`
+}
+
+type CountPatientBookingConflictsRow struct {
+	Upcoming       int64 `json:"upcoming"`
+	SameServiceDay int64 `json:"same_service_day"`
+	Overlapping    int64 `json:"overlapping"`
+}
 
-INSERT INTO appointments (clinic_id, provider_id, patient_id, service_id, start_time, end_time, status, notes)
-VALUES ($1,$2,$3,$4,$5,$6,'scheduled',$7)
-RETURNING id, clinic_id, provider_id, patient_id, service_id, start_time, end_time, status, notes, created_at, updated_at
+// What a new booking for the patient would collide with among their
+// scheduled visits other than exclude_id (the one being moved, or 0).
+func (q *Queries) CountPatientBookingConflicts(ctx context.Context, arg CountPatientBookingConflictsParams) (CountPatientBookingConflictsRow, error) {
+	row := q.db.QueryRow(ctx, countPatientBookingConflicts,
+		arg.ServiceID,
+		arg.DayStart,
+		arg.DayEnd,
+		arg.EndTime,
+		arg.StartTime,
+		arg.PatientID,
+		arg.ExcludeID,
+	)
+	var i CountPatientBookingConflictsRow
+	err := row.Scan(
+		&i.Upcoming,
+		&i.SameServiceDay,
+		&i.Overlapping,
+	)
+	return i, err
+}
+
+const createAppointment = `-- name: CreateAppointment :one
+WITH appt AS (
+  INSERT INTO appointments (clinic_id, provider_id, patient_id, service_id, start_time, end_time, status, notes)
+  VALUES ($1, $2, $3, $4,
+          $5, $6, 'scheduled', $7)
+  RETURNING id, clinic_id, provider_id, patient_id, service_id, start_time, end_time, status, notes, created_at, updated_at,
+    cancellation_reason, cancelled_at, cancelled_by
+), held AS (
+  INSERT INTO appointment_resources (appointment_id, resource_id, start_time, end_time)
+  SELECT appt.id, r.id, appt.start_time, appt.end_time
+  FROM appt, unnest($8::bigint[]) AS r(id)
+  RETURNING resource_id
+)
+SELECT id, clinic_id, provider_id, patient_id, service_id, start_time, end_time, status, notes, created_at, updated_at,
+  cancellation_reason, cancelled_at, cancelled_by,
+  (SELECT COALESCE(array_agg(resource_id ORDER BY resource_id), '{}') FROM held)::bigint[] AS resource_ids
+FROM appt
 `
 
 type CreateAppointmentParams struct {
-	ClinicID   int64     `json:"clinic_id"`
-	ProviderID int64     `json:"provider_id"`
-	PatientID  int64     `json:"patient_id"`
-	ServiceID  int64     `json:"service_id"`
-	StartTime  time.Time `json:"start_time"`
-	EndTime    time.Time `json:"end_time"`
-	Notes      *string   `json:"notes"`
+	ClinicID    int64     `json:"clinic_id"`
+	ProviderID  int64     `json:"provider_id"`
+	PatientID   int64     `json:"patient_id"`
+	ServiceID   int64     `json:"service_id"`
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time"`
+	Notes       *string   `json:"notes"`
+	ResourceIds []int64   `json:"resource_ids"`
+}
+
+type CreateAppointmentRow struct {
+	ID                 int64              `json:"id"`
+	ClinicID           int64              `json:"clinic_id"`
+	ProviderID         int64              `json:"provider_id"`
+	PatientID          int64              `json:"patient_id"`
+	ServiceID          int64              `json:"service_id"`
+	StartTime          time.Time          `json:"start_time"`
+	EndTime            time.Time          `json:"end_time"`
+	Status             string             `json:"status"`
+	Notes              *string            `json:"notes"`
+	CreatedAt          time.Time          `json:"created_at"`
+	UpdatedAt          time.Time          `json:"updated_at"`
+	CancellationReason *string            `json:"cancellation_reason"`
+	CancelledAt        pgtype.Timestamptz `json:"cancelled_at"`
+	CancelledBy        pgtype.Int8        `json:"cancelled_by"`
+	ResourceIds        []int64            `json:"resource_ids"`
 }
 
-// cancelled doesn't block
-func (q *Queries) CreateAppointment(ctx context.Context, arg CreateAppointmentParams) (Appointment, error) {
+// Books the provider and the given resources in one statement; a resource
+// that is already held fails the whole insert with an exclusion violation.
+func (q *Queries) CreateAppointment(ctx context.Context, arg CreateAppointmentParams) (CreateAppointmentRow, error) {
 	row := q.db.QueryRow(ctx, createAppointment,
 		arg.ClinicID,
 		arg.ProviderID,
@@ -65,8 +227,9 @@ func (q *Queries) CreateAppointment(ctx context.Context, arg CreateAppointmentPa
 		arg.StartTime,
 		arg.EndTime,
 		arg.Notes,
+		arg.ResourceIds,
 	)
-	var i Appointment
+	var i CreateAppointmentRow
 	err := row.Scan(
 		&i.ID,
 		&i.ClinicID,
@@ -79,6 +242,10 @@ func (q *Queries) CreateAppointment(ctx context.Context, arg CreateAppointmentPa
 		&i.Notes,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.CancellationReason,
+		&i.CancelledAt,
+		&i.CancelledBy,
+		&i.ResourceIds,
 	)
 	return i, err
 }
@@ -86,7 +253,8 @@ func (q *Queries) CreateAppointment(ctx context.Context, arg CreateAppointmentPa
 const getAppointment = `-- name: GetAppointment :one
 SELECT
   id, clinic_id, provider_id, patient_id, service_id,
-  start_time, end_time, status, notes, created_at, updated_at
+  start_time, end_time, status, notes, created_at, updated_at,
+  cancellation_reason, cancelled_at, cancelled_by
 FROM appointments
 WHERE id = $1
 `
@@ -106,6 +274,9 @@ func (q *Queries) GetAppointment(ctx context.Context, id int64) (Appointment, er
 		&i.Notes,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.CancellationReason,
+		&i.CancelledAt,
+		&i.CancelledBy,
 	)
 	return i, err
 }
@@ -125,12 +296,14 @@ JOIN services  s  ON s.id = a.service_id
 JOIN clinics   c  ON c.id = a.clinic_id
 WHERE a.start_time >= $1
   AND a.start_time <  $2
+  AND ($3::bigint[] IS NULL OR a.clinic_id = ANY($3::bigint[]))
 ORDER BY pr.id, a.start_time
 `
 
 type ListAllAppointmentsOnDateParams struct {
-	StartTime   time.Time `json:"start_time"`
-	StartTime_2 time.Time `json:"start_time_2"`
+	DayStart  time.Time `json:"day_start"`
+	DayEnd    time.Time `json:"day_end"`
+	ClinicIds []int64   `json:"clinic_ids"`
 }
 
 type ListAllAppointmentsOnDateRow struct {
@@ -152,7 +325,7 @@ type ListAllAppointmentsOnDateRow struct {
 }
 
 func (q *Queries) ListAllAppointmentsOnDate(ctx context.Context, arg ListAllAppointmentsOnDateParams) ([]ListAllAppointmentsOnDateRow, error) {
-	rows, err := q.db.Query(ctx, listAllAppointmentsOnDate, arg.StartTime, arg.StartTime_2)
+	rows, err := q.db.Query(ctx, listAllAppointmentsOnDate, arg.DayStart, arg.DayEnd, arg.ClinicIds)
 	if err != nil {
 		return nil, err
 	}
@@ -187,6 +360,155 @@ func (q *Queries) ListAllAppointmentsOnDate(ctx context.Context, arg ListAllAppo
 	return items, nil
 }
 
+const listAppointmentNoteCiphertexts = `-- name: ListAppointmentNoteCiphertexts :many
+SELECT id, notes::text AS notes_ct
+FROM appointments
+WHERE id > $1::bigint AND notes IS NOT NULL
+ORDER BY id
+LIMIT $2
+`
+
+type ListAppointmentNoteCiphertextsParams struct {
+	AfterID  int64 `json:"after_id"`
+	RowLimit int32 `json:"row_limit"`
+}
+
+type ListAppointmentNoteCiphertextsRow struct {
+	ID      int64   `json:"id"`
+	NotesCt *string `json:"notes_ct"`
+}
+
+// Raw stored values for the re-encryption command; not decrypted.
+func (q *Queries) ListAppointmentNoteCiphertexts(ctx context.Context, arg ListAppointmentNoteCiphertextsParams) ([]ListAppointmentNoteCiphertextsRow, error) {
+	rows, err := q.db.Query(ctx, listAppointmentNoteCiphertexts, arg.AfterID, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListAppointmentNoteCiphertextsRow
+	for rows.Next() {
+		var i ListAppointmentNoteCiphertextsRow
+		if err := rows.Scan(&i.ID, &i.NotesCt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAppointmentsByPatients = `-- name: ListAppointmentsByPatients :many
+SELECT
+  a.id, a.clinic_id, a.provider_id, a.patient_id, a.service_id,
+  a.start_time, a.end_time, a.status, a.notes, a.created_at, a.updated_at,
+  a.cancellation_reason, a.cancelled_at,
+  p.full_name   AS provider_name,
+  s.name        AS service_name,
+  c.name        AS clinic_name,
+  pa.full_name  AS patient_name
+FROM appointments a
+JOIN providers p  ON p.id = a.provider_id
+JOIN services  s  ON s.id = a.service_id
+JOIN clinics   c  ON c.id = a.clinic_id
+JOIN patients  pa ON pa.id = a.patient_id
+WHERE a.patient_id = ANY($1::bigint[])
+  AND ($2::text[] IS NULL OR a.status = ANY($2::text[]))
+  AND ($3::timestamptz IS NULL OR a.start_time >= $3)
+  AND ($4::timestamptz IS NULL OR a.start_time < $4)
+  AND (
+    $5::timestamptz IS NULL
+    OR ($6::bool AND (a.start_time, a.id) < ($5, $7::bigint))
+    OR (NOT $6::bool AND (a.start_time, a.id) > ($5, $7::bigint))
+  )
+ORDER BY
+  CASE WHEN $6::bool THEN a.start_time END DESC,
+  CASE WHEN $6::bool THEN a.id END DESC,
+  a.start_time ASC, a.id ASC
+LIMIT $8
+`
+
+type ListAppointmentsByPatientsParams struct {
+	PatientIds  []int64            `json:"patient_ids"`
+	Statuses    []string           `json:"statuses"`
+	FromTime    pgtype.Timestamptz `json:"from_time"`
+	ToTime      pgtype.Timestamptz `json:"to_time"`
+	CursorTime  pgtype.Timestamptz `json:"cursor_time"`
+	NewestFirst bool               `json:"newest_first"`
+	CursorID    int64              `json:"cursor_id"`
+	RowLimit    int32              `json:"row_limit"`
+}
+
+type ListAppointmentsByPatientsRow struct {
+	ID                 int64              `json:"id"`
+	ClinicID           int64              `json:"clinic_id"`
+	ProviderID         int64              `json:"provider_id"`
+	PatientID          int64              `json:"patient_id"`
+	ServiceID          int64              `json:"service_id"`
+	StartTime          time.Time          `json:"start_time"`
+	EndTime            time.Time          `json:"end_time"`
+	Status             string             `json:"status"`
+	Notes              *string            `json:"notes"`
+	CreatedAt          time.Time          `json:"created_at"`
+	UpdatedAt          time.Time          `json:"updated_at"`
+	CancellationReason *string            `json:"cancellation_reason"`
+	CancelledAt        pgtype.Timestamptz `json:"cancelled_at"`
+	ProviderName       string             `json:"provider_name"`
+	ServiceName        string             `json:"service_name"`
+	ClinicName         string             `json:"clinic_name"`
+	PatientName        string             `json:"patient_name"`
+}
+
+// Keyset-paged history; newest_first flips both the order and the cursor
+// comparison. statuses NULL means any status.
+func (q *Queries) ListAppointmentsByPatients(ctx context.Context, arg ListAppointmentsByPatientsParams) ([]ListAppointmentsByPatientsRow, error) {
+	rows, err := q.db.Query(ctx, listAppointmentsByPatients,
+		arg.PatientIds,
+		arg.Statuses,
+		arg.FromTime,
+		arg.ToTime,
+		arg.CursorTime,
+		arg.NewestFirst,
+		arg.CursorID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListAppointmentsByPatientsRow
+	for rows.Next() {
+		var i ListAppointmentsByPatientsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ClinicID,
+			&i.ProviderID,
+			&i.PatientID,
+			&i.ServiceID,
+			&i.StartTime,
+			&i.EndTime,
+			&i.Status,
+			&i.Notes,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.CancellationReason,
+			&i.CancelledAt,
+			&i.ProviderName,
+			&i.ServiceName,
+			&i.ClinicName,
+			&i.PatientName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listAppointmentsByProviderOnDate = `-- name: ListAppointmentsByProviderOnDate :many
 SELECT
   a.id, a.clinic_id, a.provider_id, a.patient_id, a.service_id,
@@ -258,12 +580,104 @@ func (q *Queries) ListAppointmentsByProviderOnDate(ctx context.Context, arg List
 	return items, nil
 }
 
+const listAppointmentsForExport = `-- name: ListAppointmentsForExport :many
+SELECT
+  a.id, a.clinic_id, a.provider_id, a.patient_id,
+  a.start_time, a.end_time, a.status,
+  pr.full_name AS provider_name,
+  pa.full_name AS patient_name,
+  s.name       AS service_name,
+  c.name       AS clinic_name,
+  c.timezone   AS clinic_timezone
+FROM appointments a
+JOIN providers pr ON pr.id = a.provider_id
+LEFT JOIN patients pa ON pa.id = a.patient_id
+JOIN services  s  ON s.id = a.service_id
+JOIN clinics   c  ON c.id = a.clinic_id
+WHERE a.start_time >= $1
+  AND a.start_time <  $2
+  AND ($3::bigint[] IS NULL OR a.clinic_id = ANY($3::bigint[]))
+  AND (a.start_time, a.provider_id, a.id) > ($4::timestamptz, $5::bigint, $6::bigint)
+ORDER BY a.start_time, a.provider_id, a.id
+LIMIT $7
+`
+
+type ListAppointmentsForExportParams struct {
+	RangeStart      time.Time `json:"range_start"`
+	RangeEnd        time.Time `json:"range_end"`
+	ClinicIds       []int64   `json:"clinic_ids"`
+	AfterStart      time.Time `json:"after_start"`
+	AfterProviderID int64     `json:"after_provider_id"`
+	AfterID         int64     `json:"after_id"`
+	RowLimit        int32     `json:"row_limit"`
+}
+
+type ListAppointmentsForExportRow struct {
+	ID             int64     `json:"id"`
+	ClinicID       int64     `json:"clinic_id"`
+	ProviderID     int64     `json:"provider_id"`
+	PatientID      int64     `json:"patient_id"`
+	StartTime      time.Time `json:"start_time"`
+	EndTime        time.Time `json:"end_time"`
+	Status         string    `json:"status"`
+	ProviderName   string    `json:"provider_name"`
+	PatientName    *string   `json:"patient_name"`
+	ServiceName    string    `json:"service_name"`
+	ClinicName     string    `json:"clinic_name"`
+	ClinicTimezone string    `json:"clinic_timezone"`
+}
+
+// The day list for CSV/XLSX export in time order, paged by
+// (start_time, provider_id, id) so long ranges stream in batches off
+// appointments_start_provider. Notes are left out.
+func (q *Queries) ListAppointmentsForExport(ctx context.Context, arg ListAppointmentsForExportParams) ([]ListAppointmentsForExportRow, error) {
+	rows, err := q.db.Query(ctx, listAppointmentsForExport,
+		arg.RangeStart,
+		arg.RangeEnd,
+		arg.ClinicIds,
+		arg.AfterStart,
+		arg.AfterProviderID,
+		arg.AfterID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListAppointmentsForExportRow
+	for rows.Next() {
+		var i ListAppointmentsForExportRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ClinicID,
+			&i.ProviderID,
+			&i.PatientID,
+			&i.StartTime,
+			&i.EndTime,
+			&i.Status,
+			&i.ProviderName,
+			&i.PatientName,
+			&i.ServiceName,
+			&i.ClinicName,
+			&i.ClinicTimezone,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listProviderAppointmentsOnDate = `-- name: ListProviderAppointmentsOnDate :many
 SELECT id, clinic_id, provider_id, patient_id, service_id, start_time, end_time, status
 FROM appointments
 WHERE provider_id = $1
   AND start_time >= $2
   AND start_time <  $3
+  -- cancelled doesn't block
   AND status IN ('scheduled','completed')
 `
 
@@ -313,84 +727,78 @@ func (q *Queries) ListProviderAppointmentsOnDate(ctx context.Context, arg ListPr
 	return items, nil
 }
 
-const listUpcomingAppointmentsByPatient = `-- name: ListUpcomingAppointmentsByPatient :many
-SELECT
-  a.id, a.clinic_id, a.provider_id, a.patient_id, a.service_id,
-  a.start_time, a.end_time, a.status, a.notes, a.created_at, a.updated_at,
-  p.full_name   AS provider_name,
-  s.name        AS service_name,
-  c.name        AS clinic_name
-FROM appointments a
-JOIN providers p ON p.id = a.provider_id
-JOIN services  s ON s.id = a.service_id
-JOIN clinics   c ON c.id = a.clinic_id
-WHERE a.patient_id = $1
-  AND a.status IN ('scheduled','completed')
-  AND a.start_time >= $2
-ORDER BY a.start_time ASC
-LIMIT $3 OFFSET $4
+const lockPatientBookings = `-- name: LockPatientBookings :exec
+SELECT pg_advisory_xact_lock($1::bigint)
 `
 
-type ListUpcomingAppointmentsByPatientParams struct {
-	PatientID int64     `json:"patient_id"`
-	StartTime time.Time `json:"start_time"`
-	Limit     int32     `json:"limit"`
-	Offset    int32     `json:"offset"`
+// Serialises bookings for one patient until the transaction ends, so the
+// limits counted by CountPatientBookingConflicts still hold at insert time.
+// The lock key is the patient id; nothing else takes advisory locks.
+func (q *Queries) LockPatientBookings(ctx context.Context, patientID int64) error {
+	_, err := q.db.Exec(ctx, lockPatientBookings, patientID)
+	return err
 }
 
-type ListUpcomingAppointmentsByPatientRow struct {
-	ID           int64     `json:"id"`
-	ClinicID     int64     `json:"clinic_id"`
-	ProviderID   int64     `json:"provider_id"`
-	PatientID    int64     `json:"patient_id"`
-	ServiceID    int64     `json:"service_id"`
-	StartTime    time.Time `json:"start_time"`
-	EndTime      time.Time `json:"end_time"`
-	Status       string    `json:"status"`
-	Notes        *string   `json:"notes"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
-	ProviderName string    `json:"provider_name"`
-	ServiceName  string    `json:"service_name"`
-	ClinicName   string    `json:"clinic_name"`
+const rescheduleAppointment = `-- name: RescheduleAppointment :one
+UPDATE appointments
+SET start_time = $1,
+    end_time = $2,
+    updated_at = NOW()
+WHERE id = $3 AND status = 'scheduled'
+RETURNING
+  id, clinic_id, provider_id, patient_id, service_id,
+  start_time, end_time, status, notes, created_at, updated_at,
+  cancellation_reason, cancelled_at, cancelled_by
+`
+
+type RescheduleAppointmentParams struct {
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	ID        int64     `json:"id"`
 }
 
-func (q *Queries) ListUpcomingAppointmentsByPatient(ctx context.Context, arg ListUpcomingAppointmentsByPatientParams) ([]ListUpcomingAppointmentsByPatientRow, error) {
-	rows, err := q.db.Query(ctx, listUpcomingAppointmentsByPatient,
-		arg.PatientID,
-		arg.StartTime,
-		arg.Limit,
-		arg.Offset,
+// Moves a scheduled visit. Its rooms and equipment are released and held
+// again separately (ReleaseAppointmentResources, HoldAppointmentResources).
+func (q *Queries) RescheduleAppointment(ctx context.Context, arg RescheduleAppointmentParams) (Appointment, error) {
+	row := q.db.QueryRow(ctx, rescheduleAppointment, arg.StartTime, arg.EndTime, arg.ID)
+	var i Appointment
+	err := row.Scan(
+		&i.ID,
+		&i.ClinicID,
+		&i.ProviderID,
+		&i.PatientID,
+		&i.ServiceID,
+		&i.StartTime,
+		&i.EndTime,
+		&i.Status,
+		&i.Notes,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CancellationReason,
+		&i.CancelledAt,
+		&i.CancelledBy,
 	)
+	return i, err
+}
+
+const updateAppointmentNoteCiphertext = `-- name: UpdateAppointmentNoteCiphertext :execrows
+UPDATE appointments
+SET notes = $1::text
+WHERE id = $2::bigint
+  AND notes IS NOT DISTINCT FROM $3::text
+`
+
+type UpdateAppointmentNoteCiphertextParams struct {
+	NotesCt    *string `json:"notes_ct"`
+	ID         int64   `json:"id"`
+	OldNotesCt *string `json:"old_notes_ct"`
+}
+
+// Skips rows changed since they were read; the next run picks them up.
+func (q *Queries) UpdateAppointmentNoteCiphertext(ctx context.Context, arg UpdateAppointmentNoteCiphertextParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateAppointmentNoteCiphertext, arg.NotesCt, arg.ID, arg.OldNotesCt)
 	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []ListUpcomingAppointmentsByPatientRow
-	for rows.Next() {
-		var i ListUpcomingAppointmentsByPatientRow
-		if err := rows.Scan(
-			&i.ID,
-			&i.ClinicID,
-			&i.ProviderID,
-			&i.PatientID,
-			&i.ServiceID,
-			&i.StartTime,
-			&i.EndTime,
-			&i.Status,
-			&i.Notes,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-			&i.ProviderName,
-			&i.ServiceName,
-			&i.ClinicName,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
+		return 0, err
 	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+	return result.RowsAffected(), nil
 }
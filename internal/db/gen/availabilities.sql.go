@@ -9,6 +9,37 @@ import (
 	"context"
 )
 
+const createAvailability = `-- name: CreateAvailability :one
+INSERT INTO availabilities (provider_id, weekday, start_hhmm, end_hhmm)
+VALUES ($1, $2, $3, $4)
+RETURNING id, provider_id, weekday, start_hhmm, end_hhmm
+`
+
+type CreateAvailabilityParams struct {
+	ProviderID int64  `json:"provider_id"`
+	Weekday    int32  `json:"weekday"`
+	StartHhmm  string `json:"start_hhmm"`
+	EndHhmm    string `json:"end_hhmm"`
+}
+
+func (q *Queries) CreateAvailability(ctx context.Context, arg CreateAvailabilityParams) (Availability, error) {
+	row := q.db.QueryRow(ctx, createAvailability,
+		arg.ProviderID,
+		arg.Weekday,
+		arg.StartHhmm,
+		arg.EndHhmm,
+	)
+	var i Availability
+	err := row.Scan(
+		&i.ID,
+		&i.ProviderID,
+		&i.Weekday,
+		&i.StartHhmm,
+		&i.EndHhmm,
+	)
+	return i, err
+}
+
 const listAvailabilitiesByProvider = `-- name: ListAvailabilitiesByProvider :many
 SELECT
   a.id,
@@ -31,3 +31,50 @@ func (q *Queries) IsDateBlackoutedForProvider(ctx context.Context, arg IsDateBla
 	err := row.Scan(&blocked)
 	return blocked, err
 }
+
+const listBlackoutsForProvider = `-- name: ListBlackoutsForProvider :many
+SELECT id, clinic_id, provider_id, date, reason
+FROM blackouts
+WHERE (provider_id = $1 OR (clinic_id = $2 AND provider_id IS NULL))
+  AND date BETWEEN $3::date AND $4::date
+ORDER BY date, id
+`
+
+type ListBlackoutsForProviderParams struct {
+	ProviderID pgtype.Int8 `json:"provider_id"`
+	ClinicID   pgtype.Int8 `json:"clinic_id"`
+	FromDate   pgtype.Date `json:"from_date"`
+	ToDate     pgtype.Date `json:"to_date"`
+}
+
+// The provider's own and their clinic's closed days in [from_date, to_date].
+func (q *Queries) ListBlackoutsForProvider(ctx context.Context, arg ListBlackoutsForProviderParams) ([]Blackout, error) {
+	rows, err := q.db.Query(ctx, listBlackoutsForProvider,
+		arg.ProviderID,
+		arg.ClinicID,
+		arg.FromDate,
+		arg.ToDate,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Blackout
+	for rows.Next() {
+		var i Blackout
+		if err := rows.Scan(
+			&i.ID,
+			&i.ClinicID,
+			&i.ProviderID,
+			&i.Date,
+			&i.Reason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -9,14 +9,74 @@ import (
 	"context"
 )
 
+const createClinic = `-- name: CreateClinic :one
+INSERT INTO clinics (name, timezone, address)
+VALUES ($1, $2, $3)
+RETURNING id, name, timezone, address, created_at, updated_at
+`
+
+type CreateClinicParams struct {
+	Name     string  `json:"name"`
+	Timezone string  `json:"timezone"`
+	Address  *string `json:"address"`
+}
+
+func (q *Queries) CreateClinic(ctx context.Context, arg CreateClinicParams) (Clinic, error) {
+	row := q.db.QueryRow(ctx, createClinic, arg.Name, arg.Timezone, arg.Address)
+	var i Clinic
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Timezone,
+		&i.Address,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getClinic = `-- name: GetClinic :one
+SELECT id, name, timezone, address, created_at, updated_at
+FROM clinics
+WHERE id = $1
+`
+
+func (q *Queries) GetClinic(ctx context.Context, id int64) (Clinic, error) {
+	row := q.db.QueryRow(ctx, getClinic, id)
+	var i Clinic
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Timezone,
+		&i.Address,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const listClinics = `-- name: ListClinics :many
 SELECT id, name, timezone, address, created_at, updated_at
 FROM clinics
+WHERE ($1::text IS NULL
+       OR name ILIKE '%' || replace(replace(replace($1, '\', '\\'), '%', '\%'), '_', '\_') || '%'
+       OR to_tsvector('simple', name) @@ websearch_to_tsquery('simple', $1))
+  AND id > $2::bigint
 ORDER BY id
+LIMIT $3
 `
 
-func (q *Queries) ListClinics(ctx context.Context) ([]Clinic, error) {
-	rows, err := q.db.Query(ctx, listClinics)
+type ListClinicsParams struct {
+	Q        *string `json:"q"`
+	AfterID  int64   `json:"after_id"`
+	RowLimit int32   `json:"row_limit"`
+}
+
+// Keyset-paged by id, so results are not ranked. q matches names holding
+// it as a case-insensitive substring (% and _ are literal) or, as a
+// websearch query, containing all its words in any order.
+func (q *Queries) ListClinics(ctx context.Context, arg ListClinicsParams) ([]Clinic, error) {
+	rows, err := q.db.Query(ctx, listClinics, arg.Q, arg.AfterID, arg.RowLimit)
 	if err != nil {
 		return nil, err
 	}
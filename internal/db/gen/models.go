@@ -5,23 +5,60 @@
 package gen
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Appointment struct {
-	ID         int64     `json:"id"`
-	ClinicID   int64     `json:"clinic_id"`
-	ProviderID int64     `json:"provider_id"`
-	PatientID  int64     `json:"patient_id"`
-	ServiceID  int64     `json:"service_id"`
-	StartTime  time.Time `json:"start_time"`
-	EndTime    time.Time `json:"end_time"`
-	Status     string    `json:"status"`
-	Notes      *string   `json:"notes"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID                 int64              `json:"id"`
+	ClinicID           int64              `json:"clinic_id"`
+	ProviderID         int64              `json:"provider_id"`
+	PatientID          int64              `json:"patient_id"`
+	ServiceID          int64              `json:"service_id"`
+	StartTime          time.Time          `json:"start_time"`
+	EndTime            time.Time          `json:"end_time"`
+	Status             string             `json:"status"`
+	Notes              *string            `json:"notes"`
+	CreatedAt          time.Time          `json:"created_at"`
+	UpdatedAt          time.Time          `json:"updated_at"`
+	CancellationReason *string            `json:"cancellation_reason"`
+	CancelledAt        pgtype.Timestamptz `json:"cancelled_at"`
+	CancelledBy        pgtype.Int8        `json:"cancelled_by"`
+}
+
+type AppointmentResource struct {
+	AppointmentID int64     `json:"appointment_id"`
+	ResourceID    int64     `json:"resource_id"`
+	StartTime     time.Time `json:"start_time"`
+	EndTime       time.Time `json:"end_time"`
+}
+
+type Attachment struct {
+	ID            int64       `json:"id"`
+	AppointmentID int64       `json:"appointment_id"`
+	StorageKey    string      `json:"storage_key"`
+	Filename      string      `json:"filename"`
+	ContentType   string      `json:"content_type"`
+	SizeBytes     int64       `json:"size_bytes"`
+	Sha256        string      `json:"sha256"`
+	UploadedBy    pgtype.Int8 `json:"uploaded_by"`
+	CreatedAt     time.Time   `json:"created_at"`
+}
+
+type AuditLog struct {
+	ID           int64       `json:"id"`
+	OccurredAt   time.Time   `json:"occurred_at"`
+	ActorUserID  pgtype.Int8 `json:"actor_user_id"`
+	ActorRole    string      `json:"actor_role"`
+	Action       string      `json:"action"`
+	ResourceType string      `json:"resource_type"`
+	ResourceID   pgtype.Int8 `json:"resource_id"`
+	PatientID    pgtype.Int8 `json:"patient_id"`
+	ClinicID     pgtype.Int8 `json:"clinic_id"`
+	RequestID    string      `json:"request_id"`
+	Ip           string      `json:"ip"`
 }
 
 type Availability struct {
@@ -49,9 +86,76 @@ type Clinic struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+type ClinicBookingRule struct {
+	ClinicID       int64       `json:"clinic_id"`
+	MinLeadMinutes pgtype.Int4 `json:"min_lead_minutes"`
+	MaxAdvanceDays pgtype.Int4 `json:"max_advance_days"`
+	SameDayCutoff  *string     `json:"same_day_cutoff"`
+	UpdatedAt      time.Time   `json:"updated_at"`
+}
+
+type EncryptionKey struct {
+	ID         int64     `json:"id"`
+	Purpose    string    `json:"purpose"`
+	Version    int32     `json:"version"`
+	WrappedKey []byte    `json:"wrapped_key"`
+	KekID      string    `json:"kek_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type ErasureRequest struct {
+	ID           int64              `json:"id"`
+	UserID       int64              `json:"user_id"`
+	PatientID    pgtype.Int8        `json:"patient_id"`
+	EraseAccount bool               `json:"erase_account"`
+	Reason       *string            `json:"reason"`
+	Status       string             `json:"status"`
+	RequestedAt  time.Time          `json:"requested_at"`
+	ReviewedBy   pgtype.Int8        `json:"reviewed_by"`
+	ReviewedAt   pgtype.Timestamptz `json:"reviewed_at"`
+	ReviewNote   *string            `json:"review_note"`
+}
+
+type IdempotencyKey struct {
+	UserID       int64       `json:"user_id"`
+	Key          string      `json:"key"`
+	RequestHash  string      `json:"request_hash"`
+	StatusCode   pgtype.Int4 `json:"status_code"`
+	ResponseBody *string     `json:"response_body"`
+	CreatedAt    time.Time   `json:"created_at"`
+	ClaimToken   string      `json:"claim_token"`
+}
+
+type IntakeForm struct {
+	ID        int64           `json:"id"`
+	ServiceID int64           `json:"service_id"`
+	Version   int32           `json:"version"`
+	Schema    json.RawMessage `json:"schema"`
+	CreatedBy pgtype.Int8     `json:"created_by"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type IntakeResponse struct {
+	ID            int64       `json:"id"`
+	AppointmentID int64       `json:"appointment_id"`
+	FormID        int64       `json:"form_id"`
+	Answers       string      `json:"answers"`
+	SubmittedBy   pgtype.Int8 `json:"submitted_by"`
+	SubmittedAt   time.Time   `json:"submitted_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+}
+
+type MfaRecoveryCode struct {
+	ID        int64              `json:"id"`
+	UserID    int64              `json:"user_id"`
+	CodeHash  string             `json:"code_hash"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
 type Patient struct {
 	ID        int64       `json:"id"`
-	UserID    int64       `json:"user_id"`
+	UserID    pgtype.Int8 `json:"user_id"`
 	FullName  string      `json:"full_name"`
 	Phone     *string     `json:"phone"`
 	Dob       pgtype.Date `json:"dob"`
@@ -59,6 +163,13 @@ type Patient struct {
 	UpdatedAt time.Time   `json:"updated_at"`
 }
 
+type PatientGuardian struct {
+	GuardianUserID int64     `json:"guardian_user_id"`
+	PatientID      int64     `json:"patient_id"`
+	Relationship   string    `json:"relationship"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
 type Provider struct {
 	ID         int64     `json:"id"`
 	UserID     int64     `json:"user_id"`
@@ -69,6 +180,44 @@ type Provider struct {
 	UpdatedAt  time.Time `json:"updated_at"`
 }
 
+type ProviderService struct {
+	ProviderID  int64       `json:"provider_id"`
+	ServiceID   int64       `json:"service_id"`
+	DurationMin pgtype.Int4 `json:"duration_min"`
+}
+
+type Resource struct {
+	ID        int64     `json:"id"`
+	ClinicID  int64     `json:"clinic_id"`
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ResourceAvailability struct {
+	ID         int64  `json:"id"`
+	ResourceID int64  `json:"resource_id"`
+	Weekday    int32  `json:"weekday"`
+	StartHhmm  string `json:"start_hhmm"`
+	EndHhmm    string `json:"end_hhmm"`
+}
+
+type RoleAssignment struct {
+	ID        int64       `json:"id"`
+	UserID    int64       `json:"user_id"`
+	ClinicID  pgtype.Int8 `json:"clinic_id"`
+	Role      string      `json:"role"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+type SecurityPolicy struct {
+	ID              bool      `json:"id"`
+	RequireStaffMfa bool      `json:"require_staff_mfa"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
 type Service struct {
 	ID          int64     `json:"id"`
 	ClinicID    int64     `json:"clinic_id"`
@@ -79,6 +228,19 @@ type Service struct {
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+type ServiceBookingRule struct {
+	ServiceID      int64       `json:"service_id"`
+	MinLeadMinutes pgtype.Int4 `json:"min_lead_minutes"`
+	MaxAdvanceDays pgtype.Int4 `json:"max_advance_days"`
+	SameDayCutoff  *string     `json:"same_day_cutoff"`
+	UpdatedAt      time.Time   `json:"updated_at"`
+}
+
+type ServiceResourceKind struct {
+	ServiceID int64  `json:"service_id"`
+	Kind      string `json:"kind"`
+}
+
 type User struct {
 	ID           int64     `json:"id"`
 	Email        string    `json:"email"`
@@ -86,4 +248,27 @@ type User struct {
 	Role         string    `json:"role"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
+	OidcIssuer   *string   `json:"oidc_issuer"`
+	OidcSubject  *string   `json:"oidc_subject"`
+}
+
+type UserMfa struct {
+	UserID         int64              `json:"user_id"`
+	TotpSecret     string             `json:"totp_secret"`
+	EnabledAt      pgtype.Timestamptz `json:"enabled_at"`
+	LastUsedStep   int64              `json:"last_used_step"`
+	FailedAttempts int32              `json:"failed_attempts"`
+	LastFailedAt   pgtype.Timestamptz `json:"last_failed_at"`
+	CreatedAt      time.Time          `json:"created_at"`
+}
+
+type VisitNote struct {
+	ID            int64              `json:"id"`
+	AppointmentID int64              `json:"appointment_id"`
+	Version       int32              `json:"version"`
+	Body          string             `json:"body"`
+	AuthorID      pgtype.Int8        `json:"author_id"`
+	CreatedAt     time.Time          `json:"created_at"`
+	SignedAt      pgtype.Timestamptz `json:"signed_at"`
+	SignedBy      pgtype.Int8        `json:"signed_by"`
 }
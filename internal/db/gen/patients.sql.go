@@ -7,32 +7,286 @@ package gen
 
 import (
 	"context"
-	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const getPatientByUserID = `-- name: GetPatientByUserID :one
-SELECT id, user_id, full_name, phone, created_at, updated_at
+const countPlaintextPatients = `-- name: CountPlaintextPatients :one
+SELECT COUNT(*)
 FROM patients
-WHERE user_id = $1
+WHERE (phone IS NOT NULL AND phone NOT LIKE 'enc:v%')
+   OR (dob IS NOT NULL AND dob NOT LIKE 'enc:v%')
 `
 
-type GetPatientByUserIDRow struct {
-	ID        int64     `json:"id"`
-	UserID    int64     `json:"user_id"`
-	FullName  string    `json:"full_name"`
-	Phone     *string   `json:"phone"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+// Rows whose phone or dob predate field encryption. Phone/dob search only
+// matches sealed values, so these stay unsearchable until cmd/reencrypt runs.
+func (q *Queries) CountPlaintextPatients(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, countPlaintextPatients)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createPatient = `-- name: CreatePatient :one
+INSERT INTO patients (user_id, full_name, phone, dob)
+VALUES ($1, $2, $3, $4)
+RETURNING id, user_id, full_name, phone, dob, created_at, updated_at
+`
+
+type CreatePatientParams struct {
+	UserID   pgtype.Int8 `json:"user_id"`
+	FullName string      `json:"full_name"`
+	Phone    *string     `json:"phone"`
+	Dob      pgtype.Date `json:"dob"`
+}
+
+func (q *Queries) CreatePatient(ctx context.Context, arg CreatePatientParams) (Patient, error) {
+	row := q.db.QueryRow(ctx, createPatient,
+		arg.UserID,
+		arg.FullName,
+		arg.Phone,
+		arg.Dob,
+	)
+	var i Patient
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.FullName,
+		&i.Phone,
+		&i.Dob,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
 }
 
-func (q *Queries) GetPatientByUserID(ctx context.Context, userID int64) (GetPatientByUserIDRow, error) {
+const getPatientByUserID = `-- name: GetPatientByUserID :one
+SELECT id, user_id, full_name, phone, dob, created_at, updated_at
+FROM patients
+WHERE user_id = $1::bigint
+`
+
+func (q *Queries) GetPatientByUserID(ctx context.Context, userID int64) (Patient, error) {
 	row := q.db.QueryRow(ctx, getPatientByUserID, userID)
-	var i GetPatientByUserIDRow
+	var i Patient
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.FullName,
+		&i.Phone,
+		&i.Dob,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const listPatientCiphertexts = `-- name: ListPatientCiphertexts :many
+SELECT id, phone::text AS phone_ct, dob::text AS dob_ct
+FROM patients
+WHERE id > $1::bigint
+ORDER BY id
+LIMIT $2
+`
+
+type ListPatientCiphertextsParams struct {
+	AfterID  int64 `json:"after_id"`
+	RowLimit int32 `json:"row_limit"`
+}
+
+type ListPatientCiphertextsRow struct {
+	ID      int64   `json:"id"`
+	PhoneCt *string `json:"phone_ct"`
+	DobCt   *string `json:"dob_ct"`
+}
+
+// Raw stored values for the re-encryption command; not decrypted.
+func (q *Queries) ListPatientCiphertexts(ctx context.Context, arg ListPatientCiphertextsParams) ([]ListPatientCiphertextsRow, error) {
+	rows, err := q.db.Query(ctx, listPatientCiphertexts, arg.AfterID, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListPatientCiphertextsRow
+	for rows.Next() {
+		var i ListPatientCiphertextsRow
+		if err := rows.Scan(&i.ID, &i.PhoneCt, &i.DobCt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPatientsByIDs = `-- name: ListPatientsByIDs :many
+SELECT id, user_id, full_name, phone, dob, created_at, updated_at
+FROM patients
+WHERE id = ANY($1::bigint[])
+ORDER BY id
+`
+
+func (q *Queries) ListPatientsByIDs(ctx context.Context, ids []int64) ([]Patient, error) {
+	rows, err := q.db.Query(ctx, listPatientsByIDs, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Patient
+	for rows.Next() {
+		var i Patient
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.FullName,
+			&i.Phone,
+			&i.Dob,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchPatients = `-- name: SearchPatients :many
+SELECT p.id, p.user_id, p.full_name, p.phone, p.dob, COALESCE(u.email::text, '') AS email
+FROM patients p
+LEFT JOIN users u ON u.id = p.user_id
+WHERE ($1::text IS NULL OR p.full_name ILIKE '%' || $1 || '%')
+  -- phone and dob are encrypted; the query layer swaps the arguments for
+  -- their blind index, which is the third segment of the stored value
+  AND ($2::text IS NULL OR split_part(p.phone, ':', 3) = $2)
+  AND ($3::text IS NULL OR split_part(p.dob, ':', 3) = $3)
+  -- staff scoped to some clinics only find patients who have booked there
+  AND ($4::bigint[] IS NULL OR EXISTS (
+    SELECT 1 FROM appointments a
+    WHERE a.patient_id = p.id AND a.clinic_id = ANY($4::bigint[])
+  ))
+ORDER BY p.full_name, p.id
+LIMIT $5
+`
+
+type SearchPatientsParams struct {
+	Name      *string `json:"name"`
+	Phone     *string `json:"phone"`
+	Dob       *string `json:"dob"`
+	ClinicIds []int64 `json:"clinic_ids"`
+	RowLimit  int32   `json:"row_limit"`
+}
+
+type SearchPatientsRow struct {
+	ID       int64       `json:"id"`
+	UserID   pgtype.Int8 `json:"user_id"`
+	FullName string      `json:"full_name"`
+	Phone    *string     `json:"phone"`
+	Dob      pgtype.Date `json:"dob"`
+	Email    string      `json:"email"`
+}
+
+func (q *Queries) SearchPatients(ctx context.Context, arg SearchPatientsParams) ([]SearchPatientsRow, error) {
+	rows, err := q.db.Query(ctx, searchPatients,
+		arg.Name,
+		arg.Phone,
+		arg.Dob,
+		arg.ClinicIds,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchPatientsRow
+	for rows.Next() {
+		var i SearchPatientsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.FullName,
+			&i.Phone,
+			&i.Dob,
+			&i.Email,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updatePatientCiphertexts = `-- name: UpdatePatientCiphertexts :execrows
+UPDATE patients
+SET phone = $1::text, dob = $2::text
+WHERE id = $3::bigint
+  AND phone IS NOT DISTINCT FROM $4::text
+  AND dob IS NOT DISTINCT FROM $5::text
+`
+
+type UpdatePatientCiphertextsParams struct {
+	PhoneCt    *string `json:"phone_ct"`
+	DobCt      *string `json:"dob_ct"`
+	ID         int64   `json:"id"`
+	OldPhoneCt *string `json:"old_phone_ct"`
+	OldDobCt   *string `json:"old_dob_ct"`
+}
+
+// Skips rows changed since they were read; the next run picks them up.
+func (q *Queries) UpdatePatientCiphertexts(ctx context.Context, arg UpdatePatientCiphertextsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updatePatientCiphertexts,
+		arg.PhoneCt,
+		arg.DobCt,
+		arg.ID,
+		arg.OldPhoneCt,
+		arg.OldDobCt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updatePatientProfile = `-- name: UpdatePatientProfile :one
+UPDATE patients
+SET full_name  = COALESCE($1, full_name),
+    phone      = COALESCE($2, phone),
+    dob        = COALESCE($3, dob),
+    updated_at = NOW()
+WHERE id = $4
+RETURNING id, user_id, full_name, phone, dob, created_at, updated_at
+`
+
+type UpdatePatientProfileParams struct {
+	FullName *string     `json:"full_name"`
+	Phone    *string     `json:"phone"`
+	Dob      pgtype.Date `json:"dob"`
+	ID       int64       `json:"id"`
+}
+
+// NULL arguments leave the column unchanged.
+func (q *Queries) UpdatePatientProfile(ctx context.Context, arg UpdatePatientProfileParams) (Patient, error) {
+	row := q.db.QueryRow(ctx, updatePatientProfile,
+		arg.FullName,
+		arg.Phone,
+		arg.Dob,
+		arg.ID,
+	)
+	var i Patient
 	err := row.Scan(
 		&i.ID,
 		&i.UserID,
 		&i.FullName,
 		&i.Phone,
+		&i.Dob,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -7,8 +7,61 @@ package gen
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const createProvider = `-- name: CreateProvider :one
+INSERT INTO providers (user_id, full_name, speciality, clinic_id)
+VALUES ($1, $2, $3, $4)
+RETURNING id, user_id, full_name, speciality, clinic_id, created_at, updated_at
+`
+
+type CreateProviderParams struct {
+	UserID     int64  `json:"user_id"`
+	FullName   string `json:"full_name"`
+	Speciality string `json:"speciality"`
+	ClinicID   int64  `json:"clinic_id"`
+}
+
+func (q *Queries) CreateProvider(ctx context.Context, arg CreateProviderParams) (Provider, error) {
+	row := q.db.QueryRow(ctx, createProvider,
+		arg.UserID,
+		arg.FullName,
+		arg.Speciality,
+		arg.ClinicID,
+	)
+	var i Provider
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.FullName,
+		&i.Speciality,
+		&i.ClinicID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const deleteProviderService = `-- name: DeleteProviderService :execrows
+DELETE FROM provider_services
+WHERE provider_id = $1 AND service_id = $2
+`
+
+type DeleteProviderServiceParams struct {
+	ProviderID int64 `json:"provider_id"`
+	ServiceID  int64 `json:"service_id"`
+}
+
+func (q *Queries) DeleteProviderService(ctx context.Context, arg DeleteProviderServiceParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteProviderService, arg.ProviderID, arg.ServiceID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const getProvider = `-- name: GetProvider :one
 SELECT id, user_id, full_name, speciality, clinic_id, created_at, updated_at
 FROM providers
@@ -51,6 +104,26 @@ func (q *Queries) GetProviderByUserID(ctx context.Context, userID int64) (Provid
 	return i, err
 }
 
+const getProviderServiceDuration = `-- name: GetProviderServiceDuration :one
+SELECT COALESCE(ps.duration_min, s.duration_min)::int AS duration_min
+FROM provider_services ps
+JOIN services s ON s.id = ps.service_id
+WHERE ps.provider_id = $1 AND ps.service_id = $2
+`
+
+type GetProviderServiceDurationParams struct {
+	ProviderID int64 `json:"provider_id"`
+	ServiceID  int64 `json:"service_id"`
+}
+
+// Effective duration; no row when the provider doesn't offer the service.
+func (q *Queries) GetProviderServiceDuration(ctx context.Context, arg GetProviderServiceDurationParams) (int32, error) {
+	row := q.db.QueryRow(ctx, getProviderServiceDuration, arg.ProviderID, arg.ServiceID)
+	var duration_min int32
+	err := row.Scan(&duration_min)
+	return duration_min, err
+}
+
 const getProviderWeekdayAvailability = `-- name: GetProviderWeekdayAvailability :many
 SELECT id, provider_id, weekday, start_hhmm, end_hhmm
 FROM availabilities
@@ -89,6 +162,56 @@ func (q *Queries) GetProviderWeekdayAvailability(ctx context.Context, arg GetPro
 	return items, nil
 }
 
+const listProviderServices = `-- name: ListProviderServices :many
+SELECT
+  s.id         AS service_id,
+  s.name,
+  s.description,
+  s.duration_min AS default_duration_min,
+  ps.duration_min AS override_duration_min,
+  COALESCE(ps.duration_min, s.duration_min)::int AS duration_min
+FROM provider_services ps
+JOIN services s ON s.id = ps.service_id
+WHERE ps.provider_id = $1
+ORDER BY s.id
+`
+
+type ListProviderServicesRow struct {
+	ServiceID           int64       `json:"service_id"`
+	Name                string      `json:"name"`
+	Description         *string     `json:"description"`
+	DefaultDurationMin  int32       `json:"default_duration_min"`
+	OverrideDurationMin pgtype.Int4 `json:"override_duration_min"`
+	DurationMin         int32       `json:"duration_min"`
+}
+
+func (q *Queries) ListProviderServices(ctx context.Context, providerID int64) ([]ListProviderServicesRow, error) {
+	rows, err := q.db.Query(ctx, listProviderServices, providerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListProviderServicesRow
+	for rows.Next() {
+		var i ListProviderServicesRow
+		if err := rows.Scan(
+			&i.ServiceID,
+			&i.Name,
+			&i.Description,
+			&i.DefaultDurationMin,
+			&i.OverrideDurationMin,
+			&i.DurationMin,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listProviders = `-- name: ListProviders :many
 SELECT
   p.id,
@@ -96,23 +219,56 @@ SELECT
   p.speciality,
   p.clinic_id,
   c.name       AS clinic_name,
-  c.timezone   AS clinic_timezone
+  c.timezone   AS clinic_timezone,
+  COALESCE(ps.duration_min, s.duration_min) AS duration_min
 FROM providers p
 JOIN clinics   c ON c.id = p.clinic_id
+LEFT JOIN provider_services ps ON ps.provider_id = p.id AND ps.service_id = $1::bigint
+LEFT JOIN services s ON s.id = ps.service_id
+WHERE ($1::bigint IS NULL OR ps.provider_id IS NOT NULL)
+  AND ($2::bigint IS NULL OR p.clinic_id = $2)
+  AND ($3::text IS NULL OR lower(p.speciality) = lower($3))
+  AND ($4::text IS NULL
+       OR p.full_name ILIKE '%' || replace(replace(replace($4, '\', '\\'), '%', '\%'), '_', '\_') || '%'
+       OR to_tsvector('simple', p.full_name) @@ websearch_to_tsquery('simple', $4))
+  AND p.id > $5::bigint
 ORDER BY p.id
+LIMIT $6
 `
 
+type ListProvidersParams struct {
+	ServiceID  pgtype.Int8 `json:"service_id"`
+	ClinicID   pgtype.Int8 `json:"clinic_id"`
+	Speciality *string     `json:"speciality"`
+	Q          *string     `json:"q"`
+	AfterID    int64       `json:"after_id"`
+	RowLimit   int32       `json:"row_limit"`
+}
+
 type ListProvidersRow struct {
-	ID             int64  `json:"id"`
-	FullName       string `json:"full_name"`
-	Speciality     string `json:"speciality"`
-	ClinicID       int64  `json:"clinic_id"`
-	ClinicName     string `json:"clinic_name"`
-	ClinicTimezone string `json:"clinic_timezone"`
+	ID             int64       `json:"id"`
+	FullName       string      `json:"full_name"`
+	Speciality     string      `json:"speciality"`
+	ClinicID       int64       `json:"clinic_id"`
+	ClinicName     string      `json:"clinic_name"`
+	ClinicTimezone string      `json:"clinic_timezone"`
+	DurationMin    pgtype.Int4 `json:"duration_min"`
 }
 
-func (q *Queries) ListProviders(ctx context.Context) ([]ListProvidersRow, error) {
-	rows, err := q.db.Query(ctx, listProviders)
+// With service_id, only providers offering it, plus their duration for it.
+// Keyset-paged by id, so results are not ranked. q matches names holding
+// it as a case-insensitive substring (% and _ are literal) or, as a
+// websearch query, containing all its words in any order. speciality is an
+// exact, case-insensitive match.
+func (q *Queries) ListProviders(ctx context.Context, arg ListProvidersParams) ([]ListProvidersRow, error) {
+	rows, err := q.db.Query(ctx, listProviders,
+		arg.ServiceID,
+		arg.ClinicID,
+		arg.Speciality,
+		arg.Q,
+		arg.AfterID,
+		arg.RowLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -127,6 +283,7 @@ func (q *Queries) ListProviders(ctx context.Context) ([]ListProvidersRow, error)
 			&i.ClinicID,
 			&i.ClinicName,
 			&i.ClinicTimezone,
+			&i.DurationMin,
 		); err != nil {
 			return nil, err
 		}
@@ -137,3 +294,27 @@ func (q *Queries) ListProviders(ctx context.Context) ([]ListProvidersRow, error)
 	}
 	return items, nil
 }
+
+const upsertProviderService = `-- name: UpsertProviderService :one
+INSERT INTO provider_services (provider_id, service_id, duration_min)
+VALUES ($1, $2, $3)
+ON CONFLICT (provider_id, service_id) DO UPDATE SET duration_min = EXCLUDED.duration_min
+RETURNING provider_id, service_id, duration_min
+`
+
+type UpsertProviderServiceParams struct {
+	ProviderID  int64       `json:"provider_id"`
+	ServiceID   int64       `json:"service_id"`
+	DurationMin pgtype.Int4 `json:"duration_min"`
+}
+
+func (q *Queries) UpsertProviderService(ctx context.Context, arg UpsertProviderServiceParams) (ProviderService, error) {
+	row := q.db.QueryRow(ctx, upsertProviderService, arg.ProviderID, arg.ServiceID, arg.DurationMin)
+	var i ProviderService
+	err := row.Scan(
+		&i.ProviderID,
+		&i.ServiceID,
+		&i.DurationMin,
+	)
+	return i, err
+}
@@ -7,8 +7,43 @@ package gen
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const createService = `-- name: CreateService :one
+INSERT INTO services (clinic_id, name, description, duration_min)
+VALUES ($1, $2, $3, $4)
+RETURNING id, clinic_id, name, description, duration_min, created_at, updated_at
+`
+
+type CreateServiceParams struct {
+	ClinicID    int64   `json:"clinic_id"`
+	Name        string  `json:"name"`
+	Description *string `json:"description"`
+	DurationMin int32   `json:"duration_min"`
+}
+
+func (q *Queries) CreateService(ctx context.Context, arg CreateServiceParams) (Service, error) {
+	row := q.db.QueryRow(ctx, createService,
+		arg.ClinicID,
+		arg.Name,
+		arg.Description,
+		arg.DurationMin,
+	)
+	var i Service
+	err := row.Scan(
+		&i.ID,
+		&i.ClinicID,
+		&i.Name,
+		&i.Description,
+		&i.DurationMin,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const getService = `-- name: GetService :one
 SELECT id, clinic_id, name, description, duration_min, created_at, updated_at
 FROM services
@@ -30,6 +65,38 @@ func (q *Queries) GetService(ctx context.Context, id int64) (Service, error) {
 	return i, err
 }
 
+const listServiceNamesByClinic = `-- name: ListServiceNamesByClinic :many
+SELECT id, name
+FROM services
+WHERE clinic_id = $1
+ORDER BY id
+`
+
+type ListServiceNamesByClinicRow struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+func (q *Queries) ListServiceNamesByClinic(ctx context.Context, clinicID int64) ([]ListServiceNamesByClinicRow, error) {
+	rows, err := q.db.Query(ctx, listServiceNamesByClinic, clinicID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListServiceNamesByClinicRow
+	for rows.Next() {
+		var i ListServiceNamesByClinicRow
+		if err := rows.Scan(&i.ID, &i.Name); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listServices = `-- name: ListServices :many
 SELECT
   s.id,
@@ -41,9 +108,22 @@ SELECT
   c.timezone AS clinic_timezone
 FROM services s
 JOIN clinics  c ON c.id = s.clinic_id
+WHERE ($1::bigint IS NULL OR s.clinic_id = $1)
+  AND ($2::text IS NULL
+       OR s.name ILIKE '%' || replace(replace(replace($2, '\', '\\'), '%', '\%'), '_', '\_') || '%'
+       OR to_tsvector('simple', s.name) @@ websearch_to_tsquery('simple', $2))
+  AND s.id > $3::bigint
 ORDER BY s.id
+LIMIT $4
 `
 
+type ListServicesParams struct {
+	ClinicID pgtype.Int8 `json:"clinic_id"`
+	Q        *string     `json:"q"`
+	AfterID  int64       `json:"after_id"`
+	RowLimit int32       `json:"row_limit"`
+}
+
 type ListServicesRow struct {
 	ID             int64   `json:"id"`
 	ClinicID       int64   `json:"clinic_id"`
@@ -54,8 +134,16 @@ type ListServicesRow struct {
 	ClinicTimezone string  `json:"clinic_timezone"`
 }
 
-func (q *Queries) ListServices(ctx context.Context) ([]ListServicesRow, error) {
-	rows, err := q.db.Query(ctx, listServices)
+// Keyset-paged by id, so results are not ranked. q matches names holding
+// it as a case-insensitive substring (% and _ are literal) or, as a
+// websearch query, containing all its words in any order.
+func (q *Queries) ListServices(ctx context.Context, arg ListServicesParams) ([]ListServicesRow, error) {
+	rows, err := q.db.Query(ctx, listServices,
+		arg.ClinicID,
+		arg.Q,
+		arg.AfterID,
+		arg.RowLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -12,7 +12,7 @@ import (
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (email, password_hash, role)
 VALUES ($1, $2, $3)
-RETURNING id, email, password_hash, role, created_at, updated_at
+RETURNING id, email, password_hash, role, created_at, updated_at, oidc_issuer, oidc_subject
 `
 
 type CreateUserParams struct {
@@ -31,12 +31,14 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Role,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.OidcIssuer,
+		&i.OidcSubject,
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, email, password_hash, role, created_at, updated_at
+SELECT id, email, password_hash, role, created_at, updated_at, oidc_issuer, oidc_subject
 FROM users
 WHERE email = $1
 `
@@ -51,6 +53,123 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Role,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.OidcIssuer,
+		&i.OidcSubject,
 	)
 	return i, err
 }
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, email, password_hash, role, created_at, updated_at, oidc_issuer, oidc_subject
+FROM users
+WHERE id = $1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id int64) (User, error) {
+	row := q.db.QueryRow(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Role,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.OidcIssuer,
+		&i.OidcSubject,
+	)
+	return i, err
+}
+
+const getUserByOIDCSubject = `-- name: GetUserByOIDCSubject :one
+SELECT id, email, password_hash, role, created_at, updated_at, oidc_issuer, oidc_subject
+FROM users
+WHERE oidc_issuer = $1 AND oidc_subject = $2
+`
+
+type GetUserByOIDCSubjectParams struct {
+	OidcIssuer  *string `json:"oidc_issuer"`
+	OidcSubject *string `json:"oidc_subject"`
+}
+
+func (q *Queries) GetUserByOIDCSubject(ctx context.Context, arg GetUserByOIDCSubjectParams) (User, error) {
+	row := q.db.QueryRow(ctx, getUserByOIDCSubject, arg.OidcIssuer, arg.OidcSubject)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Role,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.OidcIssuer,
+		&i.OidcSubject,
+	)
+	return i, err
+}
+
+const linkUserOIDC = `-- name: LinkUserOIDC :one
+UPDATE users
+SET oidc_issuer = $2, oidc_subject = $3, updated_at = NOW()
+WHERE id = $1
+RETURNING id, email, password_hash, role, created_at, updated_at, oidc_issuer, oidc_subject
+`
+
+type LinkUserOIDCParams struct {
+	ID          int64   `json:"id"`
+	OidcIssuer  *string `json:"oidc_issuer"`
+	OidcSubject *string `json:"oidc_subject"`
+}
+
+func (q *Queries) LinkUserOIDC(ctx context.Context, arg LinkUserOIDCParams) (User, error) {
+	row := q.db.QueryRow(ctx, linkUserOIDC, arg.ID, arg.OidcIssuer, arg.OidcSubject)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Role,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.OidcIssuer,
+		&i.OidcSubject,
+	)
+	return i, err
+}
+
+const promoteUserRole = `-- name: PromoteUserRole :exec
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE id = $1 AND role = 'patient'
+`
+
+type PromoteUserRoleParams struct {
+	ID   int64  `json:"id"`
+	Role string `json:"role"`
+}
+
+// Moves a patient account to a staff role when it is first granted one, so
+// the token's role claim matches its role assignments.
+func (q *Queries) PromoteUserRole(ctx context.Context, arg PromoteUserRoleParams) error {
+	_, err := q.db.Exec(ctx, promoteUserRole, arg.ID, arg.Role)
+	return err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :execrows
+UPDATE users
+SET password_hash = $2, updated_at = NOW()
+WHERE email = $1
+`
+
+type UpdateUserPasswordParams struct {
+	Email        string `json:"email"`
+	PasswordHash string `json:"password_hash"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateUserPassword, arg.Email, arg.PasswordHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/justanamir/medappoint/internal/db/gen"
+	"github.com/justanamir/medappoint/internal/fieldcrypt"
+)
+
+const (
+	keyPurposeData       = "data"
+	keyPurposeBlindIndex = "blind_index"
+)
+
+// LoadKeyring unwraps every stored data key with kek. On first start it
+// creates the initial data key and the blind index key.
+func LoadKeyring(ctx context.Context, q *gen.Queries, kek fieldcrypt.KEK) (*fieldcrypt.Keyring, error) {
+	for _, purpose := range []string{keyPurposeData, keyPurposeBlindIndex} {
+		if err := ensureKey(ctx, q, kek, purpose); err != nil {
+			return nil, err
+		}
+	}
+	rows, err := q.ListEncryptionKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data := map[int32][]byte{}
+	var index []byte
+	for _, k := range rows {
+		key, err := kek.Unwrap(ctx, k.WrappedKey)
+		if err != nil {
+			return nil, fmt.Errorf("unwrap %s key v%d (wrapped by %s): %w", k.Purpose, k.Version, k.KekID, err)
+		}
+		switch k.Purpose {
+		case keyPurposeData:
+			data[k.Version] = key
+		case keyPurposeBlindIndex:
+			// only the first is used; changing it would orphan every index
+			if index == nil {
+				index = key
+			}
+		}
+	}
+	return fieldcrypt.NewKeyring(data, index)
+}
+
+// RotateDataKey adds a new data key version. Running servers pick it up on
+// restart; run the re-encryption command afterwards.
+func RotateDataKey(ctx context.Context, q *gen.Queries, kek fieldcrypt.KEK) (int32, error) {
+	k, err := createKey(ctx, q, kek, keyPurposeData)
+	return k.Version, err
+}
+
+func ensureKey(ctx context.Context, q *gen.Queries, kek fieldcrypt.KEK, purpose string) error {
+	rows, err := q.ListEncryptionKeys(ctx)
+	if err != nil {
+		return err
+	}
+	for _, k := range rows {
+		if k.Purpose == purpose {
+			return nil
+		}
+	}
+	_, err = createKey(ctx, q, kek, purpose)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return nil // another instance created it first
+	}
+	return err
+}
+
+func createKey(ctx context.Context, q *gen.Queries, kek fieldcrypt.KEK, purpose string) (gen.EncryptionKey, error) {
+	key, err := fieldcrypt.NewKey()
+	if err != nil {
+		return gen.EncryptionKey{}, err
+	}
+	wrapped, err := kek.Wrap(ctx, key)
+	if err != nil {
+		return gen.EncryptionKey{}, fmt.Errorf("wrap %s key: %w", purpose, err)
+	}
+	return q.CreateEncryptionKey(ctx, gen.CreateEncryptionKeyParams{
+		Purpose:    purpose,
+		WrappedKey: wrapped,
+		KekID:      kek.ID(),
+	})
+}
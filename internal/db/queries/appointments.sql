@@ -4,44 +4,137 @@ FROM appointments
 WHERE provider_id = $1
   AND start_time >= $2
   AND start_time <  $3
-  AND status IN ('scheduled','completed'); -- cancelled doesn't block
+  -- cancelled doesn't block
+  AND status IN ('scheduled','completed');
 
 -- name: CreateAppointment :one
-INSERT INTO appointments (clinic_id, provider_id, patient_id, service_id, start_time, end_time, status, notes)
-VALUES ($1,$2,$3,$4,$5,$6,'scheduled',$7)
-RETURNING id, clinic_id, provider_id, patient_id, service_id, start_time, end_time, status, notes, created_at, updated_at;
+-- Books the provider and the given resources in one statement; a resource
+-- that is already held fails the whole insert with an exclusion violation.
+WITH appt AS (
+  INSERT INTO appointments (clinic_id, provider_id, patient_id, service_id, start_time, end_time, status, notes)
+  VALUES (sqlc.arg('clinic_id'), sqlc.arg('provider_id'), sqlc.arg('patient_id'), sqlc.arg('service_id'),
+          sqlc.arg('start_time'), sqlc.arg('end_time'), 'scheduled', sqlc.narg('notes'))
+  RETURNING id, clinic_id, provider_id, patient_id, service_id, start_time, end_time, status, notes, created_at, updated_at,
+    cancellation_reason, cancelled_at, cancelled_by
+), held AS (
+  INSERT INTO appointment_resources (appointment_id, resource_id, start_time, end_time)
+  SELECT appt.id, r.id, appt.start_time, appt.end_time
+  FROM appt, unnest(sqlc.arg('resource_ids')::bigint[]) AS r(id)
+  RETURNING resource_id
+)
+SELECT id, clinic_id, provider_id, patient_id, service_id, start_time, end_time, status, notes, created_at, updated_at,
+  cancellation_reason, cancelled_at, cancelled_by,
+  (SELECT COALESCE(array_agg(resource_id ORDER BY resource_id), '{}') FROM held)::bigint[] AS resource_ids
+FROM appt;
 
--- name: ListUpcomingAppointmentsByPatient :many
+-- name: ListAppointmentsByPatients :many
+-- Keyset-paged history; newest_first flips both the order and the cursor
+-- comparison. statuses NULL means any status.
 SELECT
   a.id, a.clinic_id, a.provider_id, a.patient_id, a.service_id,
   a.start_time, a.end_time, a.status, a.notes, a.created_at, a.updated_at,
+  a.cancellation_reason, a.cancelled_at,
   p.full_name   AS provider_name,
   s.name        AS service_name,
-  c.name        AS clinic_name
+  c.name        AS clinic_name,
+  pa.full_name  AS patient_name
 FROM appointments a
-JOIN providers p ON p.id = a.provider_id
-JOIN services  s ON s.id = a.service_id
-JOIN clinics   c ON c.id = a.clinic_id
-WHERE a.patient_id = $1
-  AND a.status IN ('scheduled','completed')
-  AND a.start_time >= $2
-ORDER BY a.start_time ASC
-LIMIT $3 OFFSET $4;
+JOIN providers p  ON p.id = a.provider_id
+JOIN services  s  ON s.id = a.service_id
+JOIN clinics   c  ON c.id = a.clinic_id
+JOIN patients  pa ON pa.id = a.patient_id
+WHERE a.patient_id = ANY(sqlc.arg('patient_ids')::bigint[])
+  AND (sqlc.narg('statuses')::text[] IS NULL OR a.status = ANY(sqlc.narg('statuses')::text[]))
+  AND (sqlc.narg('from_time')::timestamptz IS NULL OR a.start_time >= sqlc.narg('from_time'))
+  AND (sqlc.narg('to_time')::timestamptz IS NULL OR a.start_time < sqlc.narg('to_time'))
+  AND (
+    sqlc.narg('cursor_time')::timestamptz IS NULL
+    OR (sqlc.arg('newest_first')::bool AND (a.start_time, a.id) < (sqlc.narg('cursor_time'), sqlc.arg('cursor_id')::bigint))
+    OR (NOT sqlc.arg('newest_first')::bool AND (a.start_time, a.id) > (sqlc.narg('cursor_time'), sqlc.arg('cursor_id')::bigint))
+  )
+ORDER BY
+  CASE WHEN sqlc.arg('newest_first')::bool THEN a.start_time END DESC,
+  CASE WHEN sqlc.arg('newest_first')::bool THEN a.id END DESC,
+  a.start_time ASC, a.id ASC
+LIMIT sqlc.arg('row_limit');
 
 -- name: GetAppointment :one
 SELECT
   id, clinic_id, provider_id, patient_id, service_id,
-  start_time, end_time, status, notes, created_at, updated_at
+  start_time, end_time, status, notes, created_at, updated_at,
+  cancellation_reason, cancelled_at, cancelled_by
 FROM appointments
 WHERE id = $1;
 
 -- name: CancelAppointment :one
+-- Also releases any rooms/equipment the appointment held.
+WITH appt AS (
+  UPDATE appointments
+  SET status = 'cancelled',
+      cancellation_reason = sqlc.narg('reason'),
+      cancelled_at = NOW(),
+      cancelled_by = sqlc.narg('cancelled_by'),
+      updated_at = NOW()
+  WHERE id = sqlc.arg('id') AND status = 'scheduled'
+  RETURNING
+    id, clinic_id, provider_id, patient_id, service_id,
+    start_time, end_time, status, notes, created_at, updated_at,
+    cancellation_reason, cancelled_at, cancelled_by
+), released AS (
+  DELETE FROM appointment_resources WHERE appointment_id IN (SELECT id FROM appt)
+)
+SELECT
+  id, clinic_id, provider_id, patient_id, service_id,
+  start_time, end_time, status, notes, created_at, updated_at,
+  cancellation_reason, cancelled_at, cancelled_by
+FROM appt;
+
+-- name: RescheduleAppointment :one
+-- Moves a scheduled visit. Its rooms and equipment are released and held
+-- again separately (ReleaseAppointmentResources, HoldAppointmentResources).
 UPDATE appointments
-SET status = 'cancelled', updated_at = NOW()
-WHERE id = $1 AND status = 'scheduled'
+SET start_time = sqlc.arg('start_time'),
+    end_time = sqlc.arg('end_time'),
+    updated_at = NOW()
+WHERE id = sqlc.arg('id') AND status = 'scheduled'
 RETURNING
   id, clinic_id, provider_id, patient_id, service_id,
-  start_time, end_time, status, notes, created_at, updated_at;
+  start_time, end_time, status, notes, created_at, updated_at,
+  cancellation_reason, cancelled_at, cancelled_by;
+
+-- name: CompleteAppointment :one
+-- Only once the visit has started; cancelled/completed rows don't match.
+UPDATE appointments
+SET status = 'completed',
+    updated_at = NOW()
+WHERE id = $1 AND status = 'scheduled' AND start_time <= NOW()
+RETURNING
+  id, clinic_id, provider_id, patient_id, service_id,
+  start_time, end_time, status, notes, created_at, updated_at,
+  cancellation_reason, cancelled_at, cancelled_by;
+
+-- name: CountPatientBookingConflicts :one
+-- What a new booking for the patient would collide with among their
+-- scheduled visits other than exclude_id (the one being moved, or 0).
+SELECT
+  COUNT(*) FILTER (WHERE start_time > now()) AS upcoming,
+  COUNT(*) FILTER (
+    WHERE service_id = sqlc.arg('service_id')
+      AND start_time >= sqlc.arg('day_start') AND start_time < sqlc.arg('day_end')
+  ) AS same_service_day,
+  COUNT(*) FILTER (
+    WHERE start_time < sqlc.arg('end_time') AND end_time > sqlc.arg('start_time')
+  ) AS overlapping
+FROM appointments
+WHERE patient_id = sqlc.arg('patient_id')
+  AND status = 'scheduled'
+  AND id <> sqlc.arg('exclude_id')::bigint;
+
+-- name: LockPatientBookings :exec
+-- Serialises bookings for one patient until the transaction ends, so the
+-- limits counted by CountPatientBookingConflicts still hold at insert time.
+-- The lock key is the patient id; nothing else takes advisory locks.
+SELECT pg_advisory_xact_lock(sqlc.arg('patient_id')::bigint);
 
 -- name: ListAppointmentsByProviderOnDate :many
 SELECT
@@ -70,6 +163,46 @@ JOIN providers pr ON pr.id = a.provider_id
 LEFT JOIN patients pa ON pa.id = a.patient_id
 JOIN services  s  ON s.id = a.service_id
 JOIN clinics   c  ON c.id = a.clinic_id
-WHERE a.start_time >= $1
-  AND a.start_time <  $2
+WHERE a.start_time >= sqlc.arg('day_start')
+  AND a.start_time <  sqlc.arg('day_end')
+  AND (sqlc.narg('clinic_ids')::bigint[] IS NULL OR a.clinic_id = ANY(sqlc.narg('clinic_ids')::bigint[]))
 ORDER BY pr.id, a.start_time;
+
+-- name: ListAppointmentsForExport :many
+-- The day list for CSV/XLSX export in time order, paged by
+-- (start_time, provider_id, id) so long ranges stream in batches off
+-- appointments_start_provider. Notes are left out.
+SELECT
+  a.id, a.clinic_id, a.provider_id, a.patient_id,
+  a.start_time, a.end_time, a.status,
+  pr.full_name AS provider_name,
+  pa.full_name AS patient_name,
+  s.name       AS service_name,
+  c.name       AS clinic_name,
+  c.timezone   AS clinic_timezone
+FROM appointments a
+JOIN providers pr ON pr.id = a.provider_id
+LEFT JOIN patients pa ON pa.id = a.patient_id
+JOIN services  s  ON s.id = a.service_id
+JOIN clinics   c  ON c.id = a.clinic_id
+WHERE a.start_time >= sqlc.arg('range_start')
+  AND a.start_time <  sqlc.arg('range_end')
+  AND (sqlc.narg('clinic_ids')::bigint[] IS NULL OR a.clinic_id = ANY(sqlc.narg('clinic_ids')::bigint[]))
+  AND (a.start_time, a.provider_id, a.id) > (sqlc.arg('after_start')::timestamptz, sqlc.arg('after_provider_id')::bigint, sqlc.arg('after_id')::bigint)
+ORDER BY a.start_time, a.provider_id, a.id
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListAppointmentNoteCiphertexts :many
+-- Raw stored values for the re-encryption command; not decrypted.
+SELECT id, notes::text AS notes_ct
+FROM appointments
+WHERE id > sqlc.arg('after_id')::bigint AND notes IS NOT NULL
+ORDER BY id
+LIMIT sqlc.arg('row_limit');
+
+-- name: UpdateAppointmentNoteCiphertext :execrows
+-- Skips rows changed since they were read; the next run picks them up.
+UPDATE appointments
+SET notes = sqlc.narg('notes_ct')::text
+WHERE id = sqlc.arg('id')::bigint
+  AND notes IS NOT DISTINCT FROM sqlc.narg('old_notes_ct')::text;
@@ -0,0 +1,45 @@
+-- name: CreateAttachment :one
+INSERT INTO attachments (appointment_id, storage_key, filename, content_type, size_bytes, sha256, uploaded_by)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, appointment_id, storage_key, filename, content_type, size_bytes, sha256, uploaded_by, created_at;
+
+-- name: GetAttachment :one
+SELECT id, appointment_id, storage_key, filename, content_type, size_bytes, sha256, uploaded_by, created_at
+FROM attachments
+WHERE id = $1;
+
+-- name: ListAttachmentsByAppointment :many
+SELECT id, appointment_id, storage_key, filename, content_type, size_bytes, sha256, uploaded_by, created_at
+FROM attachments
+WHERE appointment_id = $1
+ORDER BY id;
+
+-- name: ListAttachmentsByAppointments :many
+-- Metadata for a data export; the storage key stays internal.
+SELECT id, appointment_id, filename, content_type, size_bytes, sha256, created_at
+FROM attachments
+WHERE appointment_id = ANY(sqlc.arg('appointment_ids')::bigint[])
+ORDER BY appointment_id, id;
+
+-- name: CountAttachmentsByAppointment :one
+SELECT COUNT(*) FROM attachments WHERE appointment_id = $1;
+
+-- name: DeleteAttachment :one
+DELETE FROM attachments
+WHERE id = sqlc.arg('id') AND appointment_id = sqlc.arg('appointment_id')
+RETURNING storage_key;
+
+-- name: ListAttachmentFilenameCiphertexts :many
+-- Raw stored values for the re-encryption command; not decrypted.
+SELECT id, filename::text AS filename_ct
+FROM attachments
+WHERE id > sqlc.arg('after_id')::bigint
+ORDER BY id
+LIMIT sqlc.arg('row_limit');
+
+-- name: UpdateAttachmentFilenameCiphertext :execrows
+-- Skips rows changed since they were read; the next run picks them up.
+UPDATE attachments
+SET filename = sqlc.arg('filename_ct')::text
+WHERE id = sqlc.arg('id')::bigint
+  AND filename = sqlc.arg('old_filename_ct')::text;
@@ -0,0 +1,30 @@
+-- name: CreateAuditEvent :exec
+INSERT INTO audit_log (actor_user_id, actor_role, action, resource_type, resource_id, patient_id, clinic_id, request_id, ip)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);
+
+-- name: CreateAuditEventsForPatients :exec
+-- One row per patient touched by a list read.
+INSERT INTO audit_log (actor_user_id, actor_role, action, resource_type, resource_id, patient_id, clinic_id, request_id, ip)
+SELECT
+  sqlc.narg('actor_user_id')::bigint,
+  sqlc.arg('actor_role')::text,
+  sqlc.arg('action')::text,
+  sqlc.arg('resource_type')::text,
+  sqlc.narg('resource_id')::bigint,
+  unnest(sqlc.arg('patient_ids')::bigint[]),
+  sqlc.narg('clinic_id')::bigint,
+  sqlc.arg('request_id')::text,
+  sqlc.arg('ip')::text;
+
+-- name: ListAuditEvents :many
+SELECT id, occurred_at, actor_user_id, actor_role, action, resource_type, resource_id, patient_id, clinic_id, request_id, ip
+FROM audit_log
+WHERE (sqlc.narg('actor_user_id')::bigint IS NULL OR actor_user_id = sqlc.narg('actor_user_id'))
+  AND (sqlc.narg('patient_id')::bigint IS NULL OR patient_id = sqlc.narg('patient_id'))
+  AND (sqlc.narg('action')::text IS NULL OR action = sqlc.narg('action'))
+  AND (sqlc.narg('resource_type')::text IS NULL OR resource_type = sqlc.narg('resource_type'))
+  AND (sqlc.narg('from')::timestamptz IS NULL OR occurred_at >= sqlc.narg('from'))
+  AND (sqlc.narg('to')::timestamptz IS NULL OR occurred_at < sqlc.narg('to'))
+  AND (sqlc.narg('before_id')::bigint IS NULL OR id < sqlc.narg('before_id'))
+ORDER BY id DESC
+LIMIT sqlc.arg('row_limit');
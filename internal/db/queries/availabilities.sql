@@ -10,3 +10,8 @@ FROM availabilities a
 JOIN providers p ON p.id = a.provider_id
 WHERE a.provider_id = $1
 ORDER BY a.weekday;
+
+-- name: CreateAvailability :one
+INSERT INTO availabilities (provider_id, weekday, start_hhmm, end_hhmm)
+VALUES ($1, $2, $3, $4)
+RETURNING id, provider_id, weekday, start_hhmm, end_hhmm;
@@ -4,3 +4,11 @@ SELECT EXISTS (
   WHERE (provider_id = $1 OR (clinic_id = $2 AND provider_id IS NULL))
     AND date = $3
 ) AS blocked;
+
+-- name: ListBlackoutsForProvider :many
+-- The provider's own and their clinic's closed days in [from_date, to_date].
+SELECT id, clinic_id, provider_id, date, reason
+FROM blackouts
+WHERE (provider_id = sqlc.arg('provider_id') OR (clinic_id = sqlc.arg('clinic_id') AND provider_id IS NULL))
+  AND date BETWEEN sqlc.arg('from_date')::date AND sqlc.arg('to_date')::date
+ORDER BY date, id;
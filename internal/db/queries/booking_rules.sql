@@ -0,0 +1,31 @@
+-- name: GetBookingRules :one
+-- Effective rules for a service: its own values, else its clinic's.
+SELECT
+  s.id AS service_id,
+  COALESCE(sr.min_lead_minutes, cr.min_lead_minutes, 0)::int AS min_lead_minutes,
+  COALESCE(sr.max_advance_days, cr.max_advance_days) AS max_advance_days,
+  COALESCE(sr.same_day_cutoff, cr.same_day_cutoff) AS same_day_cutoff
+FROM services s
+LEFT JOIN clinic_booking_rules  cr ON cr.clinic_id = s.clinic_id
+LEFT JOIN service_booking_rules sr ON sr.service_id = s.id
+WHERE s.id = $1;
+
+-- name: UpsertClinicBookingRules :one
+INSERT INTO clinic_booking_rules (clinic_id, min_lead_minutes, max_advance_days, same_day_cutoff)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (clinic_id) DO UPDATE SET
+  min_lead_minutes = EXCLUDED.min_lead_minutes,
+  max_advance_days = EXCLUDED.max_advance_days,
+  same_day_cutoff  = EXCLUDED.same_day_cutoff,
+  updated_at       = now()
+RETURNING clinic_id, min_lead_minutes, max_advance_days, same_day_cutoff, updated_at;
+
+-- name: UpsertServiceBookingRules :one
+INSERT INTO service_booking_rules (service_id, min_lead_minutes, max_advance_days, same_day_cutoff)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (service_id) DO UPDATE SET
+  min_lead_minutes = EXCLUDED.min_lead_minutes,
+  max_advance_days = EXCLUDED.max_advance_days,
+  same_day_cutoff  = EXCLUDED.same_day_cutoff,
+  updated_at       = now()
+RETURNING service_id, min_lead_minutes, max_advance_days, same_day_cutoff, updated_at;
@@ -1,4 +1,22 @@
 -- name: ListClinics :many
+-- Keyset-paged by id, so results are not ranked. q matches names holding
+-- it as a case-insensitive substring (% and _ are literal) or, as a
+-- websearch query, containing all its words in any order.
 SELECT id, name, timezone, address, created_at, updated_at
 FROM clinics
-ORDER BY id;
+WHERE (sqlc.narg('q')::text IS NULL
+       OR name ILIKE '%' || replace(replace(replace(sqlc.narg('q'), '\', '\\'), '%', '\%'), '_', '\_') || '%'
+       OR to_tsvector('simple', name) @@ websearch_to_tsquery('simple', sqlc.narg('q')))
+  AND id > sqlc.arg('after_id')::bigint
+ORDER BY id
+LIMIT sqlc.arg('row_limit');
+
+-- name: GetClinic :one
+SELECT id, name, timezone, address, created_at, updated_at
+FROM clinics
+WHERE id = $1;
+
+-- name: CreateClinic :one
+INSERT INTO clinics (name, timezone, address)
+VALUES ($1, $2, $3)
+RETURNING id, name, timezone, address, created_at, updated_at;
@@ -0,0 +1,35 @@
+-- name: ListDependents :many
+SELECT p.id, p.user_id, p.full_name, p.phone, p.dob, g.relationship, g.created_at AS linked_at
+FROM patient_guardians g
+JOIN patients p ON p.id = g.patient_id
+WHERE g.guardian_user_id = $1
+ORDER BY p.full_name, p.id;
+
+-- name: CreateDependent :one
+-- Creates a login-less patient and links it to the guardian in one statement.
+WITH p AS (
+  INSERT INTO patients (full_name, phone, dob)
+  VALUES (sqlc.arg('full_name'), sqlc.narg('phone'), sqlc.narg('dob'))
+  RETURNING id, user_id, full_name, phone, dob, created_at, updated_at
+), g AS (
+  INSERT INTO patient_guardians (guardian_user_id, patient_id, relationship)
+  SELECT sqlc.arg('guardian_user_id')::bigint, p.id, sqlc.arg('relationship')::text FROM p
+)
+SELECT id, user_id, full_name, phone, dob, created_at, updated_at FROM p;
+
+-- name: DeleteDependentLink :execrows
+DELETE FROM patient_guardians
+WHERE guardian_user_id = $1 AND patient_id = $2;
+
+-- name: CanActForPatient :one
+-- True when the user is the patient or one of their guardians.
+SELECT (
+  EXISTS (SELECT 1 FROM patients WHERE id = sqlc.arg('patient_id')::bigint AND user_id = sqlc.arg('user_id')::bigint)
+  OR EXISTS (SELECT 1 FROM patient_guardians WHERE patient_id = sqlc.arg('patient_id')::bigint AND guardian_user_id = sqlc.arg('user_id')::bigint)
+)::boolean AS allowed;
+
+-- name: ListManagedPatientIDs :many
+-- The user's own patient profile plus every dependent.
+SELECT id FROM patients WHERE user_id = sqlc.arg('user_id')::bigint
+UNION
+SELECT patient_id FROM patient_guardians WHERE guardian_user_id = sqlc.arg('user_id')::bigint;
@@ -0,0 +1,12 @@
+-- name: ListEncryptionKeys :many
+SELECT id, purpose, version, wrapped_key, kek_id, created_at
+FROM encryption_keys
+ORDER BY purpose, version;
+
+-- name: CreateEncryptionKey :one
+-- Adds the next version for purpose.
+INSERT INTO encryption_keys (purpose, version, wrapped_key, kek_id)
+SELECT sqlc.arg('purpose')::text, COALESCE(MAX(version), 0) + 1, sqlc.arg('wrapped_key')::bytea, sqlc.arg('kek_id')::text
+FROM encryption_keys
+WHERE purpose = sqlc.arg('purpose')::text
+RETURNING id, purpose, version, wrapped_key, kek_id, created_at;
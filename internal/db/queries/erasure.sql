@@ -0,0 +1,102 @@
+-- name: CountSoleDependents :one
+-- Login-less dependents for whom this user is the only guardian; erasing
+-- the account would leave them with nobody able to manage them.
+SELECT COUNT(*)
+FROM patient_guardians g
+JOIN patients p ON p.id = g.patient_id
+WHERE g.guardian_user_id = $1
+  AND p.user_id IS NULL
+  AND NOT EXISTS (
+    SELECT 1 FROM patient_guardians o
+    WHERE o.patient_id = g.patient_id AND o.guardian_user_id <> g.guardian_user_id
+  );
+
+-- name: CreateErasureRequest :one
+INSERT INTO erasure_requests (user_id, patient_id, erase_account, reason)
+VALUES ($1, $2, $3, $4)
+RETURNING id, user_id, patient_id, erase_account, reason, status, requested_at, reviewed_by, reviewed_at, review_note;
+
+-- name: ListErasureRequests :many
+SELECT
+  r.id, r.user_id, r.patient_id, r.erase_account, r.reason, r.status,
+  r.requested_at, r.reviewed_by, r.reviewed_at, r.review_note,
+  u.email::text AS requester_email,
+  p.full_name   AS patient_name
+FROM erasure_requests r
+JOIN users u ON u.id = r.user_id
+LEFT JOIN patients p ON p.id = r.patient_id
+WHERE (sqlc.narg('status')::text IS NULL OR r.status = sqlc.narg('status'))
+  AND (sqlc.narg('before_id')::bigint IS NULL OR r.id < sqlc.narg('before_id'))
+ORDER BY r.id DESC
+LIMIT sqlc.arg('row_limit');
+
+-- name: RejectErasureRequest :one
+UPDATE erasure_requests
+SET status = 'rejected', reviewed_by = sqlc.arg('reviewed_by')::bigint, reviewed_at = NOW(), review_note = sqlc.narg('review_note')::text
+WHERE id = sqlc.arg('id')::bigint AND status = 'pending'
+RETURNING id, user_id, patient_id, erase_account, reason, status, requested_at, reviewed_by, reviewed_at, review_note;
+
+-- name: ExecuteErasureRequest :one
+-- Anonymizes the subject of a pending request in one statement. The user row
+-- is kept (audit_log refers to it by id) but can no longer log in.
+-- attachment_keys are the deleted attachments' objects, for the caller to
+-- remove from storage. An account that is still the only guardian of
+-- login-less dependents is not erased (no row comes back); see
+-- CountSoleDependents. Stored idempotent responses go with the account, as
+-- they hold appointment JSON. visit_notes are kept on purpose: they are
+-- clinical records the clinic must retain.
+WITH req AS (
+  UPDATE erasure_requests r
+  SET status = 'completed', reviewed_by = sqlc.arg('reviewed_by')::bigint, reviewed_at = NOW(),
+      review_note = sqlc.narg('review_note')::text, reason = NULL
+  WHERE r.id = sqlc.arg('id')::bigint AND r.status = 'pending'
+    AND NOT (r.erase_account AND EXISTS (
+      SELECT 1
+      FROM patient_guardians g
+      JOIN patients p ON p.id = g.patient_id
+      WHERE g.guardian_user_id = r.user_id
+        AND p.user_id IS NULL
+        AND NOT EXISTS (
+          SELECT 1 FROM patient_guardians o
+          WHERE o.patient_id = g.patient_id AND o.guardian_user_id <> g.guardian_user_id
+        )
+    ))
+  RETURNING id, user_id, patient_id, erase_account, reason, status, requested_at, reviewed_by, reviewed_at, review_note
+), acct AS (
+  SELECT user_id FROM req WHERE erase_account
+), pat AS (
+  UPDATE patients
+  SET full_name = 'Erased patient', phone = NULL, dob = NULL, user_id = NULL, updated_at = NOW()
+  WHERE id = (SELECT patient_id FROM req)
+  RETURNING id
+), appts AS (
+  UPDATE appointments
+  SET notes = NULL, cancellation_reason = NULL, updated_at = NOW()
+  WHERE patient_id IN (SELECT id FROM pat)
+), intake AS (
+  DELETE FROM intake_responses
+  WHERE appointment_id IN (SELECT id FROM appointments WHERE patient_id IN (SELECT id FROM pat))
+), files AS (
+  DELETE FROM attachments
+  WHERE appointment_id IN (SELECT id FROM appointments WHERE patient_id IN (SELECT id FROM pat))
+  RETURNING storage_key
+), usr AS (
+  UPDATE users
+  SET email = 'erased-' || id || '@erased.invalid', password_hash = '',
+      oidc_issuer = NULL, oidc_subject = NULL, updated_at = NOW()
+  WHERE id IN (SELECT user_id FROM acct)
+), links AS (
+  DELETE FROM patient_guardians
+  WHERE guardian_user_id IN (SELECT user_id FROM acct) OR patient_id IN (SELECT id FROM pat)
+), mfa AS (
+  DELETE FROM user_mfa WHERE user_id IN (SELECT user_id FROM acct)
+), codes AS (
+  DELETE FROM mfa_recovery_codes WHERE user_id IN (SELECT user_id FROM acct)
+), roles AS (
+  DELETE FROM role_assignments WHERE user_id IN (SELECT user_id FROM acct)
+), idem AS (
+  DELETE FROM idempotency_keys WHERE user_id IN (SELECT user_id FROM acct)
+)
+SELECT id, user_id, patient_id, erase_account, reason, status, requested_at, reviewed_by, reviewed_at, review_note,
+  (SELECT COALESCE(array_agg(storage_key), '{}') FROM files)::text[] AS attachment_keys
+FROM req;
@@ -0,0 +1,32 @@
+-- name: ClaimIdempotencyKey :execrows
+-- 1 when the key is ours to run: new, expired, or left in flight by a
+-- request that died. 0 means a live entry exists. An in-flight claim is only
+-- presumed dead well past the server's 15s write timeout, so a slow request
+-- and its retry don't both run.
+INSERT INTO idempotency_keys (user_id, key, request_hash, claim_token)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id, key) DO UPDATE
+SET request_hash = EXCLUDED.request_hash, claim_token = EXCLUDED.claim_token,
+    status_code = NULL, response_body = NULL, created_at = now()
+WHERE idempotency_keys.created_at < now() - interval '24 hours'
+   OR (idempotency_keys.status_code IS NULL AND idempotency_keys.created_at < now() - interval '10 minutes');
+
+-- name: GetIdempotencyKey :one
+SELECT user_id, key, request_hash, status_code, response_body, created_at
+FROM idempotency_keys
+WHERE user_id = $1 AND key = $2;
+
+-- name: SaveIdempotentResponse :execrows
+-- 0 when the claim was taken over; the response is then not stored.
+UPDATE idempotency_keys
+SET status_code = $1, response_body = $2
+WHERE user_id = $3 AND key = $4 AND claim_token = $5;
+
+-- name: ReleaseIdempotencyKey :exec
+-- Drops an in-flight claim so the client can retry after a server error.
+DELETE FROM idempotency_keys
+WHERE user_id = $1 AND key = $2 AND claim_token = $3 AND status_code IS NULL;
+
+-- name: DeleteExpiredIdempotencyKeys :execrows
+DELETE FROM idempotency_keys
+WHERE created_at < now() - interval '24 hours';
@@ -0,0 +1,55 @@
+-- name: CreateIntakeForm :one
+-- Publishes the next version of the service's form.
+INSERT INTO intake_forms (service_id, version, schema, created_by)
+SELECT sqlc.arg('service_id')::bigint, COALESCE(MAX(version), 0) + 1, sqlc.arg('schema')::jsonb, sqlc.narg('created_by')::bigint
+FROM intake_forms
+WHERE service_id = sqlc.arg('service_id')::bigint
+RETURNING id, service_id, version, schema, created_by, created_at;
+
+-- name: GetLatestIntakeForm :one
+SELECT id, service_id, version, schema, created_by, created_at
+FROM intake_forms
+WHERE service_id = $1
+ORDER BY version DESC
+LIMIT 1;
+
+-- name: GetIntakeForm :one
+SELECT id, service_id, version, schema, created_by, created_at
+FROM intake_forms
+WHERE id = $1;
+
+-- name: UpsertIntakeResponse :one
+INSERT INTO intake_responses (appointment_id, form_id, answers, submitted_by)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (appointment_id) DO UPDATE
+SET form_id = EXCLUDED.form_id,
+    answers = EXCLUDED.answers,
+    submitted_by = EXCLUDED.submitted_by,
+    updated_at = NOW()
+RETURNING id, appointment_id, form_id, answers, submitted_by, submitted_at, updated_at;
+
+-- name: GetIntakeResponseByAppointment :one
+SELECT id, appointment_id, form_id, answers, submitted_by, submitted_at, updated_at
+FROM intake_responses
+WHERE appointment_id = $1;
+
+-- name: ListIntakeResponsesByAppointments :many
+SELECT r.appointment_id, r.form_id, f.version AS form_version, r.answers, r.submitted_at, r.updated_at
+FROM intake_responses r
+JOIN intake_forms f ON f.id = r.form_id
+WHERE r.appointment_id = ANY(sqlc.arg('appointment_ids')::bigint[]);
+
+-- name: ListIntakeAnswerCiphertexts :many
+-- Raw stored values for the re-encryption command; not decrypted.
+SELECT id, answers::text AS answers_ct
+FROM intake_responses
+WHERE id > sqlc.arg('after_id')::bigint
+ORDER BY id
+LIMIT sqlc.arg('row_limit');
+
+-- name: UpdateIntakeAnswerCiphertext :execrows
+-- Skips rows changed since they were read; the next run picks them up.
+UPDATE intake_responses
+SET answers = sqlc.arg('answers_ct')::text
+WHERE id = sqlc.arg('id')::bigint
+  AND answers = sqlc.arg('old_answers_ct')::text;
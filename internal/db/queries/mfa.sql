@@ -0,0 +1,65 @@
+-- name: GetUserMFA :one
+SELECT user_id, totp_secret, enabled_at, last_used_step, failed_attempts, last_failed_at, created_at
+FROM user_mfa
+WHERE user_id = $1;
+
+-- name: UpsertPendingUserMFA :one
+-- Starts (or restarts) enrollment; never touches an already enabled factor.
+INSERT INTO user_mfa (user_id, totp_secret)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+SET totp_secret = EXCLUDED.totp_secret, last_used_step = 0, failed_attempts = 0, last_failed_at = NULL
+WHERE user_mfa.enabled_at IS NULL
+RETURNING user_id, totp_secret, enabled_at, last_used_step, failed_attempts, last_failed_at, created_at;
+
+-- name: EnableUserMFA :exec
+UPDATE user_mfa
+SET enabled_at = NOW(), last_used_step = $2, failed_attempts = 0, last_failed_at = NULL
+WHERE user_id = $1;
+
+-- name: AcceptMFAStep :execrows
+-- Records a successful code; 0 rows means the step was already used.
+UPDATE user_mfa
+SET last_used_step = $2, failed_attempts = 0, last_failed_at = NULL
+WHERE user_id = $1 AND last_used_step < $2;
+
+-- name: RecordMFAFailure :one
+UPDATE user_mfa
+SET failed_attempts = CASE
+      WHEN last_failed_at IS NULL OR last_failed_at < NOW() - INTERVAL '15 minutes' THEN 1
+      ELSE failed_attempts + 1
+    END,
+    last_failed_at = NOW()
+WHERE user_id = $1
+RETURNING failed_attempts;
+
+-- name: DeleteUserMFA :exec
+DELETE FROM user_mfa
+WHERE user_id = $1;
+
+-- name: ReplaceRecoveryCodes :exec
+WITH cleared AS (
+  DELETE FROM mfa_recovery_codes WHERE user_id = sqlc.arg('user_id')
+)
+INSERT INTO mfa_recovery_codes (user_id, code_hash)
+SELECT sqlc.arg('user_id')::bigint, unnest(sqlc.arg('code_hashes')::text[]);
+
+-- name: UseRecoveryCode :execrows
+UPDATE mfa_recovery_codes
+SET used_at = NOW()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM mfa_recovery_codes
+WHERE user_id = $1;
+
+-- name: GetSecurityPolicy :one
+SELECT id, require_staff_mfa, updated_at
+FROM security_policy
+WHERE id;
+
+-- name: UpdateSecurityPolicy :one
+UPDATE security_policy
+SET require_staff_mfa = $1, updated_at = NOW()
+WHERE id
+RETURNING id, require_staff_mfa, updated_at;
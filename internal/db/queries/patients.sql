@@ -1,4 +1,66 @@
 -- name: GetPatientByUserID :one
-SELECT id, user_id, full_name, phone, created_at, updated_at
+SELECT id, user_id, full_name, phone, dob, created_at, updated_at
 FROM patients
-WHERE user_id = $1;
+WHERE user_id = sqlc.arg('user_id')::bigint;
+
+-- name: CreatePatient :one
+INSERT INTO patients (user_id, full_name, phone, dob)
+VALUES ($1, $2, $3, $4)
+RETURNING id, user_id, full_name, phone, dob, created_at, updated_at;
+
+-- name: UpdatePatientProfile :one
+-- NULL arguments leave the column unchanged.
+UPDATE patients
+SET full_name  = COALESCE(sqlc.narg('full_name'), full_name),
+    phone      = COALESCE(sqlc.narg('phone'), phone),
+    dob        = COALESCE(sqlc.narg('dob'), dob),
+    updated_at = NOW()
+WHERE id = sqlc.arg('id')
+RETURNING id, user_id, full_name, phone, dob, created_at, updated_at;
+
+-- name: SearchPatients :many
+SELECT p.id, p.user_id, p.full_name, p.phone, p.dob, COALESCE(u.email::text, '') AS email
+FROM patients p
+LEFT JOIN users u ON u.id = p.user_id
+WHERE (sqlc.narg('name')::text IS NULL OR p.full_name ILIKE '%' || sqlc.narg('name') || '%')
+  -- phone and dob are encrypted; the query layer swaps the arguments for
+  -- their blind index, which is the third segment of the stored value
+  AND (sqlc.narg('phone')::text IS NULL OR split_part(p.phone, ':', 3) = sqlc.narg('phone'))
+  AND (sqlc.narg('dob')::text IS NULL OR split_part(p.dob, ':', 3) = sqlc.narg('dob'))
+  -- staff scoped to some clinics only find patients who have booked there
+  AND (sqlc.narg('clinic_ids')::bigint[] IS NULL OR EXISTS (
+    SELECT 1 FROM appointments a
+    WHERE a.patient_id = p.id AND a.clinic_id = ANY(sqlc.narg('clinic_ids')::bigint[])
+  ))
+ORDER BY p.full_name, p.id
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListPatientsByIDs :many
+SELECT id, user_id, full_name, phone, dob, created_at, updated_at
+FROM patients
+WHERE id = ANY(sqlc.arg('ids')::bigint[])
+ORDER BY id;
+
+-- name: ListPatientCiphertexts :many
+-- Raw stored values for the re-encryption command; not decrypted.
+SELECT id, phone::text AS phone_ct, dob::text AS dob_ct
+FROM patients
+WHERE id > sqlc.arg('after_id')::bigint
+ORDER BY id
+LIMIT sqlc.arg('row_limit');
+
+-- name: UpdatePatientCiphertexts :execrows
+-- Skips rows changed since they were read; the next run picks them up.
+UPDATE patients
+SET phone = sqlc.narg('phone_ct')::text, dob = sqlc.narg('dob_ct')::text
+WHERE id = sqlc.arg('id')::bigint
+  AND phone IS NOT DISTINCT FROM sqlc.narg('old_phone_ct')::text
+  AND dob IS NOT DISTINCT FROM sqlc.narg('old_dob_ct')::text;
+
+-- name: CountPlaintextPatients :one
+-- Rows whose phone or dob predate field encryption. Phone/dob search only
+-- matches sealed values, so these stay unsearchable until cmd/reencrypt runs.
+SELECT COUNT(*)
+FROM patients
+WHERE (phone IS NOT NULL AND phone NOT LIKE 'enc:v%')
+   OR (dob IS NOT NULL AND dob NOT LIKE 'enc:v%');
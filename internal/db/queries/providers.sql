@@ -1,14 +1,30 @@
 -- name: ListProviders :many
+-- With service_id, only providers offering it, plus their duration for it.
+-- Keyset-paged by id, so results are not ranked. q matches names holding
+-- it as a case-insensitive substring (% and _ are literal) or, as a
+-- websearch query, containing all its words in any order. speciality is an
+-- exact, case-insensitive match.
 SELECT
   p.id,
   p.full_name,
   p.speciality,
   p.clinic_id,
   c.name       AS clinic_name,
-  c.timezone   AS clinic_timezone
+  c.timezone   AS clinic_timezone,
+  COALESCE(ps.duration_min, s.duration_min) AS duration_min
 FROM providers p
 JOIN clinics   c ON c.id = p.clinic_id
-ORDER BY p.id;
+LEFT JOIN provider_services ps ON ps.provider_id = p.id AND ps.service_id = sqlc.narg('service_id')::bigint
+LEFT JOIN services s ON s.id = ps.service_id
+WHERE (sqlc.narg('service_id')::bigint IS NULL OR ps.provider_id IS NOT NULL)
+  AND (sqlc.narg('clinic_id')::bigint IS NULL OR p.clinic_id = sqlc.narg('clinic_id'))
+  AND (sqlc.narg('speciality')::text IS NULL OR lower(p.speciality) = lower(sqlc.narg('speciality')))
+  AND (sqlc.narg('q')::text IS NULL
+       OR p.full_name ILIKE '%' || replace(replace(replace(sqlc.narg('q'), '\', '\\'), '%', '\%'), '_', '\_') || '%'
+       OR to_tsvector('simple', p.full_name) @@ websearch_to_tsquery('simple', sqlc.narg('q')))
+  AND p.id > sqlc.arg('after_id')::bigint
+ORDER BY p.id
+LIMIT sqlc.arg('row_limit');
 
 -- name: GetProvider :one
 SELECT id, user_id, full_name, speciality, clinic_id, created_at, updated_at
@@ -25,3 +41,38 @@ ORDER BY start_hhmm;
 SELECT id, user_id, full_name, speciality, clinic_id, created_at, updated_at
 FROM providers
 WHERE user_id = $1;
+
+-- name: GetProviderServiceDuration :one
+-- Effective duration; no row when the provider doesn't offer the service.
+SELECT COALESCE(ps.duration_min, s.duration_min)::int AS duration_min
+FROM provider_services ps
+JOIN services s ON s.id = ps.service_id
+WHERE ps.provider_id = $1 AND ps.service_id = $2;
+
+-- name: ListProviderServices :many
+SELECT
+  s.id         AS service_id,
+  s.name,
+  s.description,
+  s.duration_min AS default_duration_min,
+  ps.duration_min AS override_duration_min,
+  COALESCE(ps.duration_min, s.duration_min)::int AS duration_min
+FROM provider_services ps
+JOIN services s ON s.id = ps.service_id
+WHERE ps.provider_id = $1
+ORDER BY s.id;
+
+-- name: UpsertProviderService :one
+INSERT INTO provider_services (provider_id, service_id, duration_min)
+VALUES ($1, $2, $3)
+ON CONFLICT (provider_id, service_id) DO UPDATE SET duration_min = EXCLUDED.duration_min
+RETURNING provider_id, service_id, duration_min;
+
+-- name: DeleteProviderService :execrows
+DELETE FROM provider_services
+WHERE provider_id = $1 AND service_id = $2;
+
+-- name: CreateProvider :one
+INSERT INTO providers (user_id, full_name, speciality, clinic_id)
+VALUES ($1, $2, $3, $4)
+RETURNING id, user_id, full_name, speciality, clinic_id, created_at, updated_at;
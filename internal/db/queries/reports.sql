@@ -0,0 +1,100 @@
+-- name: ReportBookingsPerDay :many
+-- Per visit day (clinic time). A no-show is a visit whose end has passed
+-- while it is still scheduled, i.e. it was never completed or cancelled.
+SELECT
+  (a.start_time AT TIME ZONE sqlc.arg('tz')::text)::date AS day,
+  COUNT(*) AS total,
+  COUNT(*) FILTER (WHERE a.status = 'completed') AS completed,
+  COUNT(*) FILTER (WHERE a.status = 'cancelled') AS cancelled,
+  COUNT(*) FILTER (WHERE a.status = 'scheduled' AND a.end_time < now()) AS no_show
+FROM appointments a
+WHERE a.start_time >= (sqlc.arg('from_date')::date::timestamp AT TIME ZONE sqlc.arg('tz')::text)
+  AND a.start_time <  ((sqlc.arg('to_date')::date + 1)::timestamp AT TIME ZONE sqlc.arg('tz')::text)
+  AND (sqlc.narg('clinic_ids')::bigint[] IS NULL OR a.clinic_id = ANY(sqlc.narg('clinic_ids')::bigint[]))
+GROUP BY 1
+ORDER BY 1;
+
+-- name: ReportProviderUtilization :many
+-- Available minutes come from the weekly windows on each day of the range,
+-- skipping blackout days; booked minutes are all non-cancelled visits.
+WITH days AS (
+  SELECT d::date AS day
+  FROM generate_series(sqlc.arg('from_date')::date, sqlc.arg('to_date')::date, interval '1 day') AS d
+), avail AS (
+  SELECT av.provider_id,
+         SUM(EXTRACT(EPOCH FROM (av.end_hhmm::time - av.start_hhmm::time)) / 60)::bigint AS minutes
+  FROM availabilities av
+  JOIN providers p ON p.id = av.provider_id
+  JOIN days ON EXTRACT(ISODOW FROM days.day) = av.weekday
+  WHERE NOT EXISTS (
+    SELECT 1 FROM blackouts b
+    WHERE b.date = days.day
+      AND (b.provider_id = p.id OR (b.clinic_id = p.clinic_id AND b.provider_id IS NULL))
+  )
+  GROUP BY av.provider_id
+), booked AS (
+  SELECT a.provider_id,
+         COUNT(*) AS appointments,
+         SUM(EXTRACT(EPOCH FROM (a.end_time - a.start_time)) / 60)::bigint AS minutes
+  FROM appointments a
+  WHERE a.status <> 'cancelled'
+    AND a.start_time >= (sqlc.arg('from_date')::date::timestamp AT TIME ZONE sqlc.arg('tz')::text)
+    AND a.start_time <  ((sqlc.arg('to_date')::date + 1)::timestamp AT TIME ZONE sqlc.arg('tz')::text)
+  GROUP BY a.provider_id
+)
+SELECT
+  p.id        AS provider_id,
+  p.full_name AS provider_name,
+  p.clinic_id,
+  COALESCE(b.appointments, 0)::bigint AS appointments,
+  COALESCE(av.minutes, 0)::bigint     AS available_minutes,
+  COALESCE(b.minutes, 0)::bigint      AS booked_minutes
+FROM providers p
+LEFT JOIN avail  av ON av.provider_id = p.id
+LEFT JOIN booked b  ON b.provider_id = p.id
+WHERE (sqlc.narg('clinic_ids')::bigint[] IS NULL OR p.clinic_id = ANY(sqlc.narg('clinic_ids')::bigint[]))
+ORDER BY p.id;
+
+-- name: ReportLeadTimes :many
+-- How long before the visit it was booked, bucketed; cancelled included.
+SELECT
+  CASE
+    WHEN a.start_time - a.created_at < interval '1 hour'  THEN 0
+    WHEN a.start_time - a.created_at < interval '1 day'   THEN 1
+    WHEN a.start_time - a.created_at < interval '3 days'  THEN 2
+    WHEN a.start_time - a.created_at < interval '7 days'  THEN 3
+    WHEN a.start_time - a.created_at < interval '30 days' THEN 4
+    ELSE 5
+  END::int AS bucket,
+  COUNT(*) AS appointments
+FROM appointments a
+WHERE a.start_time >= (sqlc.arg('from_date')::date::timestamp AT TIME ZONE sqlc.arg('tz')::text)
+  AND a.start_time <  ((sqlc.arg('to_date')::date + 1)::timestamp AT TIME ZONE sqlc.arg('tz')::text)
+  AND (sqlc.narg('clinic_ids')::bigint[] IS NULL OR a.clinic_id = ANY(sqlc.narg('clinic_ids')::bigint[]))
+GROUP BY 1
+ORDER BY 1;
+
+-- name: ReportLeadTimeStats :one
+SELECT
+  COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM (a.start_time - a.created_at)) / 3600), 0)::float8 AS median_hours,
+  COALESCE(percentile_cont(0.9) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM (a.start_time - a.created_at)) / 3600), 0)::float8 AS p90_hours,
+  COALESCE(AVG(EXTRACT(EPOCH FROM (a.start_time - a.created_at)) / 3600), 0)::float8 AS mean_hours
+FROM appointments a
+WHERE a.start_time >= (sqlc.arg('from_date')::date::timestamp AT TIME ZONE sqlc.arg('tz')::text)
+  AND a.start_time <  ((sqlc.arg('to_date')::date + 1)::timestamp AT TIME ZONE sqlc.arg('tz')::text)
+  AND (sqlc.narg('clinic_ids')::bigint[] IS NULL OR a.clinic_id = ANY(sqlc.narg('clinic_ids')::bigint[]));
+
+-- name: ReportBusiestHours :many
+-- Non-cancelled visits by weekday (1=Mon ... 7=Sun) and starting hour,
+-- clinic time, busiest first.
+SELECT
+  EXTRACT(ISODOW FROM a.start_time AT TIME ZONE sqlc.arg('tz')::text)::int AS weekday,
+  EXTRACT(HOUR FROM a.start_time AT TIME ZONE sqlc.arg('tz')::text)::int   AS hour,
+  COUNT(*) AS appointments
+FROM appointments a
+WHERE a.status <> 'cancelled'
+  AND a.start_time >= (sqlc.arg('from_date')::date::timestamp AT TIME ZONE sqlc.arg('tz')::text)
+  AND a.start_time <  ((sqlc.arg('to_date')::date + 1)::timestamp AT TIME ZONE sqlc.arg('tz')::text)
+  AND (sqlc.narg('clinic_ids')::bigint[] IS NULL OR a.clinic_id = ANY(sqlc.narg('clinic_ids')::bigint[]))
+GROUP BY 1, 2
+ORDER BY appointments DESC, weekday, hour;
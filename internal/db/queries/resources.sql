@@ -0,0 +1,92 @@
+-- name: CreateResource :one
+INSERT INTO resources (clinic_id, kind, name)
+VALUES ($1, $2, $3)
+RETURNING id, clinic_id, kind, name, active, created_at, updated_at;
+
+-- name: GetResource :one
+SELECT id, clinic_id, kind, name, active, created_at, updated_at
+FROM resources
+WHERE id = $1;
+
+-- name: ListResources :many
+-- clinic_ids NULL means every clinic.
+SELECT id, clinic_id, kind, name, active, created_at, updated_at
+FROM resources
+WHERE (sqlc.narg('clinic_ids')::bigint[] IS NULL OR clinic_id = ANY(sqlc.narg('clinic_ids')::bigint[]))
+ORDER BY clinic_id, kind, name;
+
+-- name: UpdateResource :one
+UPDATE resources
+SET name = COALESCE(sqlc.narg('name'), name),
+    active = COALESCE(sqlc.narg('active'), active),
+    updated_at = NOW()
+WHERE id = sqlc.arg('id')
+RETURNING id, clinic_id, kind, name, active, created_at, updated_at;
+
+-- name: ListResourceAvailabilities :many
+SELECT id, resource_id, weekday, start_hhmm, end_hhmm
+FROM resource_availabilities
+WHERE resource_id = $1
+ORDER BY weekday, start_hhmm;
+
+-- name: ReplaceResourceAvailabilities :many
+-- Swaps the whole weekly schedule in one statement.
+WITH del AS (
+  DELETE FROM resource_availabilities WHERE resource_id = sqlc.arg('resource_id')::bigint
+), ins AS (
+  INSERT INTO resource_availabilities (resource_id, weekday, start_hhmm, end_hhmm)
+  SELECT sqlc.arg('resource_id')::bigint, w.weekday, w.start_hhmm, w.end_hhmm
+  FROM unnest(sqlc.arg('weekdays')::int[], sqlc.arg('start_hhmms')::text[], sqlc.arg('end_hhmms')::text[])
+    AS w(weekday, start_hhmm, end_hhmm)
+  RETURNING id, resource_id, weekday, start_hhmm, end_hhmm
+)
+SELECT id, resource_id, weekday, start_hhmm, end_hhmm FROM ins
+ORDER BY weekday, start_hhmm;
+
+-- name: ListServiceResourceKinds :many
+SELECT kind
+FROM service_resource_kinds
+WHERE service_id = $1
+ORDER BY kind;
+
+-- name: ReplaceServiceResourceKinds :exec
+WITH del AS (
+  DELETE FROM service_resource_kinds
+  WHERE service_id = sqlc.arg('service_id')::bigint AND kind <> ALL(sqlc.arg('kinds')::text[])
+)
+INSERT INTO service_resource_kinds (service_id, kind)
+SELECT sqlc.arg('service_id')::bigint, unnest(sqlc.arg('kinds')::text[])
+ON CONFLICT DO NOTHING;
+
+-- name: ListActiveResourcesByKinds :many
+SELECT id, kind
+FROM resources
+WHERE clinic_id = sqlc.arg('clinic_id') AND active AND kind = ANY(sqlc.arg('kinds')::text[])
+ORDER BY kind, id;
+
+-- name: ListResourceWeekdayAvailabilities :many
+SELECT resource_id, start_hhmm, end_hhmm
+FROM resource_availabilities
+WHERE resource_id = ANY(sqlc.arg('resource_ids')::bigint[]) AND weekday = sqlc.arg('weekday')
+ORDER BY resource_id, start_hhmm;
+
+-- name: ReleaseAppointmentResources :exec
+DELETE FROM appointment_resources
+WHERE appointment_id = $1;
+
+-- name: HoldAppointmentResources :many
+-- A resource that is already held fails the insert with an exclusion
+-- violation.
+INSERT INTO appointment_resources (appointment_id, resource_id, start_time, end_time)
+SELECT sqlc.arg('appointment_id'), r.id, sqlc.arg('start_time'), sqlc.arg('end_time')
+FROM unnest(sqlc.arg('resource_ids')::bigint[]) AS r(id)
+RETURNING resource_id;
+
+-- name: ListResourceBookings :many
+-- Held intervals overlapping [range_start, range_end).
+SELECT resource_id, start_time, end_time
+FROM appointment_resources
+WHERE resource_id = ANY(sqlc.arg('resource_ids')::bigint[])
+  AND start_time < sqlc.arg('range_end')
+  AND end_time > sqlc.arg('range_start')
+ORDER BY resource_id, start_time;
@@ -0,0 +1,28 @@
+-- name: ListRoleAssignmentsByUser :many
+SELECT id, user_id, clinic_id, role, created_at
+FROM role_assignments
+WHERE user_id = $1
+ORDER BY id;
+
+-- name: ListRoleAssignments :many
+SELECT
+  ra.id, ra.user_id, ra.clinic_id, ra.role, ra.created_at,
+  u.email AS user_email
+FROM role_assignments ra
+JOIN users u ON u.id = ra.user_id
+WHERE (sqlc.narg('clinic_ids')::bigint[] IS NULL OR ra.clinic_id = ANY(sqlc.narg('clinic_ids')::bigint[]))
+ORDER BY ra.clinic_id NULLS FIRST, ra.user_id, ra.role;
+
+-- name: GetRoleAssignment :one
+SELECT id, user_id, clinic_id, role, created_at
+FROM role_assignments
+WHERE id = $1;
+
+-- name: CreateRoleAssignment :one
+INSERT INTO role_assignments (user_id, clinic_id, role)
+VALUES ($1, $2, $3)
+RETURNING id, user_id, clinic_id, role, created_at;
+
+-- name: DeleteRoleAssignment :exec
+DELETE FROM role_assignments
+WHERE id = $1;
@@ -1,4 +1,7 @@
 -- name: ListServices :many
+-- Keyset-paged by id, so results are not ranked. q matches names holding
+-- it as a case-insensitive substring (% and _ are literal) or, as a
+-- websearch query, containing all its words in any order.
 SELECT
   s.id,
   s.clinic_id,
@@ -9,9 +12,26 @@ SELECT
   c.timezone AS clinic_timezone
 FROM services s
 JOIN clinics  c ON c.id = s.clinic_id
-ORDER BY s.id;
+WHERE (sqlc.narg('clinic_id')::bigint IS NULL OR s.clinic_id = sqlc.narg('clinic_id'))
+  AND (sqlc.narg('q')::text IS NULL
+       OR s.name ILIKE '%' || replace(replace(replace(sqlc.narg('q'), '\', '\\'), '%', '\%'), '_', '\_') || '%'
+       OR to_tsvector('simple', s.name) @@ websearch_to_tsquery('simple', sqlc.narg('q')))
+  AND s.id > sqlc.arg('after_id')::bigint
+ORDER BY s.id
+LIMIT sqlc.arg('row_limit');
 
 -- name: GetService :one
 SELECT id, clinic_id, name, description, duration_min, created_at, updated_at
 FROM services
 WHERE id = $1;
+
+-- name: ListServiceNamesByClinic :many
+SELECT id, name
+FROM services
+WHERE clinic_id = $1
+ORDER BY id;
+
+-- name: CreateService :one
+INSERT INTO services (clinic_id, name, description, duration_min)
+VALUES ($1, $2, $3, $4)
+RETURNING id, clinic_id, name, description, duration_min, created_at, updated_at;
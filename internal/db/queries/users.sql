@@ -1,9 +1,37 @@
 -- name: CreateUser :one
 INSERT INTO users (email, password_hash, role)
 VALUES ($1, $2, $3)
-RETURNING id, email, password_hash, role, created_at, updated_at;
+RETURNING id, email, password_hash, role, created_at, updated_at, oidc_issuer, oidc_subject;
 
 -- name: GetUserByEmail :one
-SELECT id, email, password_hash, role, created_at, updated_at
+SELECT id, email, password_hash, role, created_at, updated_at, oidc_issuer, oidc_subject
 FROM users
 WHERE email = $1;
+
+-- name: GetUserByID :one
+SELECT id, email, password_hash, role, created_at, updated_at, oidc_issuer, oidc_subject
+FROM users
+WHERE id = $1;
+
+-- name: GetUserByOIDCSubject :one
+SELECT id, email, password_hash, role, created_at, updated_at, oidc_issuer, oidc_subject
+FROM users
+WHERE oidc_issuer = $1 AND oidc_subject = $2;
+
+-- name: LinkUserOIDC :one
+UPDATE users
+SET oidc_issuer = $2, oidc_subject = $3, updated_at = NOW()
+WHERE id = $1
+RETURNING id, email, password_hash, role, created_at, updated_at, oidc_issuer, oidc_subject;
+
+-- name: PromoteUserRole :exec
+-- Moves a patient account to a staff role when it is first granted one, so
+-- the token's role claim matches its role assignments.
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE id = $1 AND role = 'patient';
+
+-- name: UpdateUserPassword :execrows
+UPDATE users
+SET password_hash = $2, updated_at = NOW()
+WHERE email = $1;
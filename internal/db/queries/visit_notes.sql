@@ -0,0 +1,43 @@
+-- name: CreateVisitNote :one
+-- Appends the next version, but only if base_version is still the latest
+-- and the note hasn't been signed; otherwise returns no row.
+INSERT INTO visit_notes (appointment_id, version, body, author_id)
+SELECT sqlc.arg('appointment_id')::bigint, COALESCE(MAX(version), 0) + 1, sqlc.arg('body')::text, sqlc.narg('author_id')::bigint
+FROM visit_notes
+WHERE appointment_id = sqlc.arg('appointment_id')::bigint
+HAVING COALESCE(MAX(version), 0) = sqlc.arg('base_version')::int
+   AND COUNT(signed_at) = 0
+RETURNING id, appointment_id, version, body, author_id, created_at, signed_at, signed_by;
+
+-- name: ListVisitNotes :many
+-- Every version, newest first.
+SELECT id, appointment_id, version, body, author_id, created_at, signed_at, signed_by
+FROM visit_notes
+WHERE appointment_id = $1
+ORDER BY version DESC;
+
+-- name: SignVisitNote :one
+-- Signs the latest version; no row if it is already signed or not the latest.
+UPDATE visit_notes
+SET signed_at = NOW(),
+    signed_by = sqlc.narg('signed_by')::bigint
+WHERE appointment_id = sqlc.arg('appointment_id')::bigint
+  AND version = sqlc.arg('version')::int
+  AND signed_at IS NULL
+  AND version = (SELECT MAX(v.version) FROM visit_notes v WHERE v.appointment_id = sqlc.arg('appointment_id')::bigint)
+RETURNING id, appointment_id, version, body, author_id, created_at, signed_at, signed_by;
+
+-- name: ListVisitNoteCiphertexts :many
+-- Raw stored values for the re-encryption command; not decrypted.
+SELECT id, body::text AS body_ct
+FROM visit_notes
+WHERE id > sqlc.arg('after_id')::bigint
+ORDER BY id
+LIMIT sqlc.arg('row_limit');
+
+-- name: UpdateVisitNoteCiphertext :execrows
+-- Skips rows changed since they were read; the next run picks them up.
+UPDATE visit_notes
+SET body = sqlc.arg('body_ct')::text
+WHERE id = sqlc.arg('id')::bigint
+  AND body = sqlc.arg('old_body_ct')::text;
@@ -0,0 +1,171 @@
+// Package fieldcrypt encrypts individual column values at rest.
+//
+// Values are sealed with AES-256-GCM under a data key. Data keys are random,
+// versioned, and stored in the database wrapped by a key-encryption key (KEK)
+// that lives outside it: a local keyfile or a KMS. Rotating means adding a
+// new data key version; old versions stay readable until everything has been
+// re-encrypted.
+//
+// Stored format:
+//
+//	enc:v<version>:<blind index>:<base64(nonce || ciphertext)>
+//
+// The blind index is a keyed hash of the plaintext, present only for fields
+// that must support exact-match search, so queries can compare
+// split_part(col, ':', 3) without decrypting.
+//
+// A field is named "table.column" and that name is the AEAD associated data,
+// so a value copied into a column of another table does not decrypt. Rows
+// are not bound: most values are sealed before the row has an id. Values
+// sealed under the bare column name, before fields carried their table,
+// still open until they are re-encrypted.
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const prefix = "enc:v"
+
+// KeySize is the length of data keys and the blind index key (AES-256).
+const KeySize = 32
+
+var ErrUnknownKey = errors.New("fieldcrypt: unknown key version")
+
+// Keyring holds the unwrapped data keys and the blind index key.
+type Keyring struct {
+	current int32
+	keys    map[int32]cipher.AEAD
+	index   []byte
+}
+
+// NewKeyring builds a keyring from unwrapped data keys by version. The highest
+// version encrypts new values.
+func NewKeyring(dataKeys map[int32][]byte, indexKey []byte) (*Keyring, error) {
+	if len(dataKeys) == 0 {
+		return nil, errors.New("fieldcrypt: no data keys")
+	}
+	if len(indexKey) != KeySize {
+		return nil, errors.New("fieldcrypt: blind index key must be 32 bytes")
+	}
+	kr := &Keyring{keys: map[int32]cipher.AEAD{}, index: indexKey}
+	for v, k := range dataKeys {
+		block, err := aes.NewCipher(k)
+		if err != nil {
+			return nil, fmt.Errorf("fieldcrypt: data key v%d: %w", v, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		kr.keys[v] = aead
+		if v > kr.current {
+			kr.current = v
+		}
+	}
+	return kr, nil
+}
+
+// Current is the data key version used for new values.
+func (kr *Keyring) Current() int32 { return kr.current }
+
+// Encrypt seals plaintext for field ("table.column"). The field name is
+// bound as associated data, so a value copied into another column will not
+// decrypt. searchable adds the blind index.
+func (kr *Keyring) Encrypt(field, plaintext string, searchable bool) (string, error) {
+	aead := kr.keys[kr.current]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(field))
+	var idx string
+	if searchable {
+		idx = kr.BlindIndex(field, plaintext)
+	}
+	return prefix + strconv.Itoa(int(kr.current)) + ":" + idx + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value written by Encrypt. Values without the prefix are
+// legacy plaintext and returned unchanged.
+func (kr *Keyring) Decrypt(field, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	pt, _, err := kr.open(field, value)
+	return pt, err
+}
+
+// open decrypts value for field; legacy is true when it was sealed under the
+// bare column name rather than "table.column".
+func (kr *Keyring) open(field, value string) (pt string, legacy bool, err error) {
+	parts := strings.SplitN(value[len(prefix):], ":", 3)
+	if len(parts) != 3 {
+		return "", false, errors.New("fieldcrypt: malformed value")
+	}
+	v, err := strconv.ParseInt(parts[0], 10, 32)
+	if err != nil {
+		return "", false, errors.New("fieldcrypt: malformed version")
+	}
+	aead, ok := kr.keys[int32(v)]
+	if !ok {
+		return "", false, fmt.Errorf("%w v%d", ErrUnknownKey, v)
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", false, errors.New("fieldcrypt: malformed ciphertext")
+	}
+	n := aead.NonceSize()
+	b, err := aead.Open(nil, sealed[:n], sealed[n:], []byte(field))
+	if err != nil {
+		if i := strings.LastIndexByte(field, '.'); i >= 0 {
+			if b, lerr := aead.Open(nil, sealed[:n], sealed[n:], []byte(field[i+1:])); lerr == nil {
+				return string(b), true, nil
+			}
+		}
+		return "", false, fmt.Errorf("fieldcrypt: %s: %w", field, err)
+	}
+	return string(b), false, nil
+}
+
+// BlindIndex is the search token for plaintext in field: a truncated
+// HMAC-SHA256, stable across data key rotations.
+func (kr *Keyring) BlindIndex(field, plaintext string) string {
+	mac := hmac.New(sha256.New, kr.index)
+	mac.Write([]byte(field))
+	mac.Write([]byte{0})
+	mac.Write([]byte(plaintext))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// IsCurrent reports whether value is already sealed under the current key
+// and bound to field, so re-encryption can leave it alone.
+func (kr *Keyring) IsCurrent(field, value string) bool {
+	if !strings.HasPrefix(value, prefix+strconv.Itoa(int(kr.current))+":") {
+		return false
+	}
+	_, legacy, err := kr.open(field, value)
+	return err == nil && !legacy
+}
+
+// IsEncrypted reports whether value is in the stored format.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// NewKey returns a random key suitable for NewKeyring.
+func NewKey() ([]byte, error) {
+	k := make([]byte, KeySize)
+	_, err := rand.Read(k)
+	return k, err
+}
@@ -0,0 +1,218 @@
+package fieldcrypt
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestKeyring(t *testing.T, versions ...int32) (*Keyring, map[int32][]byte, []byte) {
+	t.Helper()
+	keys := map[int32][]byte{}
+	for _, v := range versions {
+		k, err := NewKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[v] = k
+	}
+	idx, _ := NewKey()
+	kr, err := NewKeyring(keys, idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kr, keys, idx
+}
+
+func TestRoundTrip(t *testing.T) {
+	kr, _, _ := newTestKeyring(t, 1)
+	for _, pt := range []string{"", "+60123456789", "1990-05-17", "notes: α β γ\nline two"} {
+		for _, searchable := range []bool{false, true} {
+			ct, err := kr.Encrypt("patients.phone", pt, searchable)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !IsEncrypted(ct) || (pt != "" && strings.Contains(ct, pt)) {
+				t.Fatalf("Encrypt(%q) = %q does not look sealed", pt, ct)
+			}
+			got, err := kr.Decrypt("patients.phone", ct)
+			if err != nil || got != pt {
+				t.Fatalf("Decrypt(Encrypt(%q)) = %q, %v", pt, got, err)
+			}
+		}
+	}
+}
+
+func TestEncryptIsRandomized(t *testing.T) {
+	kr, _, _ := newTestKeyring(t, 1)
+	a, _ := kr.Encrypt("patients.phone", "+60123456789", true)
+	b, _ := kr.Encrypt("patients.phone", "+60123456789", true)
+	if a == b {
+		t.Fatal("same plaintext sealed to the same ciphertext")
+	}
+	// but the blind index segment matches, which is what search compares
+	if strings.Split(a, ":")[2] != strings.Split(b, ":")[2] {
+		t.Fatal("blind index differs between two sealings of one value")
+	}
+}
+
+func TestFieldIsAssociatedData(t *testing.T) {
+	kr, _, _ := newTestKeyring(t, 1)
+	ct, _ := kr.Encrypt("patients.phone", "+60123456789", false)
+	for _, field := range []string{"patients.dob", "dependents.phone", "phone"} {
+		if _, err := kr.Decrypt(field, ct); err == nil {
+			t.Errorf("a patients.phone value decrypted as %s", field)
+		}
+	}
+}
+
+func TestLegacyColumnBinding(t *testing.T) {
+	kr, _, _ := newTestKeyring(t, 1)
+	// sealed before fields carried their table
+	ct, _ := kr.Encrypt("phone", "+60123456789", false)
+
+	if got, err := kr.Decrypt("patients.phone", ct); err != nil || got != "+60123456789" {
+		t.Fatalf("legacy value: %q, %v", got, err)
+	}
+	if _, err := kr.Decrypt("patients.dob", ct); err == nil {
+		t.Fatal("a legacy phone value decrypted as dob")
+	}
+	if kr.IsCurrent("patients.phone", ct) {
+		t.Fatal("legacy value reported as current")
+	}
+	bound, _ := kr.Encrypt("patients.phone", "+60123456789", false)
+	if !kr.IsCurrent("patients.phone", bound) || kr.IsCurrent("patients.dob", bound) {
+		t.Fatal("IsCurrent ignores the field")
+	}
+}
+
+func TestTamperedCiphertext(t *testing.T) {
+	kr, _, _ := newTestKeyring(t, 1)
+	ct, _ := kr.Encrypt("appointments.notes", "allergic to penicillin", false)
+	b := []byte(ct)
+	b[len(b)-2] ^= 'A' ^ 'B'
+	if _, err := kr.Decrypt("appointments.notes", string(b)); err == nil {
+		t.Fatal("tampered ciphertext decrypted")
+	}
+	for _, bad := range []string{"enc:v1", "enc:vX:idx:AAAA", "enc:v1::not base64!"} {
+		if _, err := kr.Decrypt("appointments.notes", bad); err == nil {
+			t.Errorf("Decrypt(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestLegacyPlaintextPassesThrough(t *testing.T) {
+	kr, _, _ := newTestKeyring(t, 1)
+	got, err := kr.Decrypt("patients.phone", "+60123456789")
+	if err != nil || got != "+60123456789" {
+		t.Fatalf("Decrypt(plaintext) = %q, %v", got, err)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	old, keys, idx := newTestKeyring(t, 1)
+	ct1, _ := old.Encrypt("patients.dob", "1990-05-17", true)
+
+	k2, _ := NewKey()
+	keys[2] = k2
+	kr, err := NewKeyring(keys, idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kr.Current() != 2 {
+		t.Fatalf("Current() = %d, want 2", kr.Current())
+	}
+	if kr.IsCurrent("patients.dob", ct1) {
+		t.Fatal("v1 value reported as current")
+	}
+	if got, err := kr.Decrypt("patients.dob", ct1); err != nil || got != "1990-05-17" {
+		t.Fatalf("old value after rotation: %q, %v", got, err)
+	}
+	ct2, _ := kr.Encrypt("patients.dob", "1990-05-17", true)
+	if !strings.HasPrefix(ct2, "enc:v2:") || !kr.IsCurrent("patients.dob", ct2) {
+		t.Fatalf("new value %q not under v2", ct2)
+	}
+	// the blind index survives rotation, so search keeps matching
+	if strings.Split(ct1, ":")[2] != strings.Split(ct2, ":")[2] {
+		t.Fatal("blind index changed with the data key")
+	}
+
+	// a keyring that lost v2 cannot read values sealed under it
+	if _, err := old.Decrypt("patients.dob", ct2); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("got %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestBlindIndex(t *testing.T) {
+	kr, _, _ := newTestKeyring(t, 1)
+	a := kr.BlindIndex("patients.phone", "+60123456789")
+	if a != kr.BlindIndex("patients.phone", "+60123456789") {
+		t.Fatal("blind index is not deterministic")
+	}
+	if a == kr.BlindIndex("patients.dob", "+60123456789") {
+		t.Fatal("blind index ignores the field")
+	}
+	if a == kr.BlindIndex("patients.phone", "+60123456780") {
+		t.Fatal("different values share a blind index")
+	}
+	other, _, _ := newTestKeyring(t, 1)
+	if a == other.BlindIndex("patients.phone", "+60123456789") {
+		t.Fatal("blind index does not depend on the index key")
+	}
+	if len(a) != 32 || strings.Contains(a, ":") {
+		t.Fatalf("blind index %q is not 16 hex bytes", a)
+	}
+}
+
+func TestNewKeyringValidates(t *testing.T) {
+	idx, _ := NewKey()
+	if _, err := NewKeyring(nil, idx); err == nil {
+		t.Error("accepted no data keys")
+	}
+	k, _ := NewKey()
+	if _, err := NewKeyring(map[int32][]byte{1: k}, idx[:16]); err == nil {
+		t.Error("accepted a short index key")
+	}
+	if _, err := NewKeyring(map[int32][]byte{1: k[:7]}, idx); err == nil {
+		t.Error("accepted a malformed data key")
+	}
+}
+
+func TestFileKEK(t *testing.T) {
+	dir := t.TempDir()
+	raw, _ := NewKey()
+	path := filepath.Join(dir, "kek.hex")
+	if err := os.WriteFile(path, []byte(hex.EncodeToString(raw)+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	kek, err := LoadKEK(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kek.ID() != "file:kek.hex" {
+		t.Errorf("ID() = %q", kek.ID())
+	}
+	ctx := context.Background()
+	dk, _ := NewKey()
+	wrapped, err := kek.Wrap(ctx, dk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := kek.Unwrap(ctx, wrapped)
+	if err != nil || string(got) != string(dk) {
+		t.Fatalf("Unwrap(Wrap(k)) = %x, %v", got, err)
+	}
+	if _, err := DevKEK().Unwrap(ctx, wrapped); err == nil {
+		t.Fatal("another KEK unwrapped the key")
+	}
+
+	bad := filepath.Join(dir, "short")
+	_ = os.WriteFile(bad, []byte("abcd"), 0o600)
+	if _, err := LoadKEK(bad); err == nil {
+		t.Fatal("accepted a short keyfile")
+	}
+}
@@ -0,0 +1,106 @@
+package fieldcrypt
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// KEK wraps and unwraps data keys. A cloud KMS adapter implements this by
+// calling its Encrypt/Decrypt API; the data keys themselves never leave the
+// process unwrapped.
+type KEK interface {
+	// ID names the key so stored data keys record what wrapped them.
+	ID() string
+	Wrap(ctx context.Context, key []byte) ([]byte, error)
+	Unwrap(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// FileKEK is a KEK held in a local file: 32 bytes, raw or hex/base64 encoded.
+type FileKEK struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// LoadFileKEK reads a keyfile. Its ID is "file:" plus the file name, so
+// several keyfiles can be told apart after a KEK rotation.
+func LoadFileKEK(path string) (*FileKEK, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("kek file: %w", err)
+	}
+	key, err := decodeKey(b)
+	if err != nil {
+		return nil, fmt.Errorf("kek file %s: %w", path, err)
+	}
+	return newFileKEK("file:"+filepath.Base(path), key)
+}
+
+// DevKEK is a fixed, publicly known KEK for local development only.
+func DevKEK() *FileKEK {
+	sum := sha256.Sum256([]byte("medappoint-dev-kek"))
+	k, _ := newFileKEK("dev", sum[:])
+	return k
+}
+
+func newFileKEK(id string, key []byte) (*FileKEK, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &FileKEK{id: id, aead: aead}, nil
+}
+
+func (k *FileKEK) ID() string { return k.id }
+
+func (k *FileKEK) Wrap(_ context.Context, key []byte) ([]byte, error) {
+	nonce := make([]byte, k.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return k.aead.Seal(nonce, nonce, key, nil), nil
+}
+
+func (k *FileKEK) Unwrap(_ context.Context, wrapped []byte) ([]byte, error) {
+	n := k.aead.NonceSize()
+	if len(wrapped) < n {
+		return nil, errors.New("wrapped key too short")
+	}
+	return k.aead.Open(nil, wrapped[:n], wrapped[n:], nil)
+}
+
+func decodeKey(b []byte) ([]byte, error) {
+	if len(b) == KeySize {
+		return b, nil
+	}
+	s := strings.TrimSpace(string(b))
+	if k, err := hex.DecodeString(s); err == nil && len(k) == KeySize {
+		return k, nil
+	}
+	if k, err := base64.StdEncoding.DecodeString(s); err == nil && len(k) == KeySize {
+		return k, nil
+	}
+	return nil, errors.New("want 32 bytes (raw, hex or base64)")
+}
+
+// LoadKEK returns the keyfile KEK at path, or the dev KEK when path is empty.
+// config.Validate keeps the latter out of production.
+func LoadKEK(path string) (KEK, error) {
+	if path == "" {
+		return DevKEK(), nil
+	}
+	return LoadFileKEK(path)
+}
@@ -0,0 +1,187 @@
+// Package forms defines intake questionnaires as a small subset of JSON
+// Schema and validates submitted answers against them.
+//
+// A schema is an object whose properties are one of four field kinds:
+//
+//	text    {"type": "string", "maxLength": 500}
+//	choice  {"type": "string", "enum": ["none", "mild", "severe"]}
+//	yes/no  {"type": "boolean"}
+//	date    {"type": "string", "format": "date"}
+//
+// Every field may carry "title" and "description" for display; "required"
+// lists the mandatory ones. Anything else is rejected so clients never
+// render a keyword the server does not enforce.
+package forms
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxFields        = 100
+	defaultMaxLength = 2000
+	maxMaxLength     = 10000
+)
+
+var fieldNameRe = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+type Schema struct {
+	SchemaURI   string           `json:"$schema,omitempty"`
+	Title       string           `json:"title,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Type        string           `json:"type"`
+	Required    []string         `json:"required,omitempty"`
+	Properties  map[string]Field `json:"properties"`
+}
+
+type Field struct {
+	Type        string   `json:"type"`
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Format      string   `json:"format,omitempty"`
+	Enum        []string `json:"enum,omitempty"`
+	MaxLength   int      `json:"maxLength,omitempty"`
+}
+
+// Parse decodes and checks a schema definition.
+func Parse(raw []byte) (*Schema, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	var s Schema
+	if err := dec.Decode(&s); err != nil {
+		return nil, fmt.Errorf("schema: %w", err)
+	}
+	if s.Type != "object" {
+		return nil, errors.New(`schema: type must be "object"`)
+	}
+	if len(s.Properties) == 0 || len(s.Properties) > maxFields {
+		return nil, fmt.Errorf("schema: properties must have 1-%d fields", maxFields)
+	}
+	for name, f := range s.Properties {
+		if !fieldNameRe.MatchString(name) {
+			return nil, fmt.Errorf("schema: field name %q must be lower_snake_case", name)
+		}
+		if err := f.check(); err != nil {
+			return nil, fmt.Errorf("schema: %s: %w", name, err)
+		}
+	}
+	for _, name := range s.Required {
+		if _, ok := s.Properties[name]; !ok {
+			return nil, fmt.Errorf("schema: required field %q is not defined", name)
+		}
+	}
+	return &s, nil
+}
+
+func (f Field) check() error {
+	switch f.Type {
+	case "boolean":
+		if f.Format != "" || f.Enum != nil || f.MaxLength != 0 {
+			return errors.New("boolean fields take no format, enum or maxLength")
+		}
+	case "string":
+		switch {
+		case f.Format != "" && f.Format != "date":
+			return errors.New(`only format "date" is supported`)
+		case f.Format != "" && (f.Enum != nil || f.MaxLength != 0):
+			return errors.New("date fields take no enum or maxLength")
+		case f.Enum != nil && len(f.Enum) == 0:
+			return errors.New("enum must not be empty")
+		case f.MaxLength < 0 || f.MaxLength > maxMaxLength:
+			return fmt.Errorf("maxLength must be 1-%d", maxMaxLength)
+		}
+		seen := map[string]bool{}
+		for _, v := range f.Enum {
+			if v == "" || seen[v] {
+				return errors.New("enum values must be unique and non-empty")
+			}
+			seen[v] = true
+		}
+	default:
+		return errors.New(`type must be "string" or "boolean"`)
+	}
+	return nil
+}
+
+// Validate checks answers against the schema. It returns the canonical
+// answers to store (nulls dropped) or per-field error messages.
+func (s *Schema) Validate(answers map[string]json.RawMessage) (json.RawMessage, map[string]string) {
+	errs := map[string]string{}
+	clean := map[string]interface{}{}
+	for name, raw := range answers {
+		f, ok := s.Properties[name]
+		if !ok {
+			errs[name] = "unknown field"
+			continue
+		}
+		if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+			continue
+		}
+		v, err := f.value(raw)
+		if err != nil {
+			errs[name] = err.Error()
+			continue
+		}
+		if v != nil {
+			clean[name] = v
+		}
+	}
+	for _, name := range s.Required {
+		if _, ok := clean[name]; !ok && errs[name] == "" {
+			errs[name] = "required"
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	out, _ := json.Marshal(clean)
+	return out, nil
+}
+
+// value decodes one answer; nil means "not answered" (an empty string).
+func (f Field) value(raw json.RawMessage) (interface{}, error) {
+	if f.Type == "boolean" {
+		var b bool
+		if err := json.Unmarshal(raw, &b); err != nil {
+			return nil, errors.New("must be true or false")
+		}
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, errors.New("must be a string")
+	}
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	switch {
+	case f.Format == "date":
+		if _, err := time.Parse("2006-01-02", s); err != nil {
+			return nil, errors.New("must be a date (YYYY-MM-DD)")
+		}
+	case f.Enum != nil:
+		for _, v := range f.Enum {
+			if v == s {
+				return s, nil
+			}
+		}
+		return nil, errors.New("must be one of " + strings.Join(f.Enum, ", "))
+	default:
+		max := f.MaxLength
+		if max == 0 {
+			max = defaultMaxLength
+		}
+		if utf8.RuneCountInString(s) > max {
+			return nil, fmt.Errorf("must be at most %d characters", max)
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,117 @@
+package forms
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		wantErr string // substring; "" means valid
+	}{
+		{"every kind", `{"type":"object","required":["smoker"],"properties":{
+			"allergies":{"type":"string","title":"Allergies","maxLength":500},
+			"pain":{"type":"string","enum":["none","mild","severe"]},
+			"smoker":{"type":"boolean","description":"Currently smoking"},
+			"last_visit":{"type":"string","format":"date"}}}`, ""},
+		{"not an object", `{"type":"array","properties":{"a":{"type":"string"}}}`, `type must be "object"`},
+		{"no properties", `{"type":"object","properties":{}}`, "properties must have"},
+		{"unknown keyword", `{"type":"object","properties":{"a":{"type":"string","pattern":"x"}}}`, "unknown field"},
+		{"unknown top-level keyword", `{"type":"object","additionalProperties":false,"properties":{"a":{"type":"string"}}}`, "unknown field"},
+		{"field name", `{"type":"object","properties":{"Allergies":{"type":"string"}}}`, "lower_snake_case"},
+		{"field type", `{"type":"object","properties":{"age":{"type":"integer"}}}`, `type must be "string" or "boolean"`},
+		{"boolean with enum", `{"type":"object","properties":{"a":{"type":"boolean","enum":["yes"]}}}`, "boolean fields take no"},
+		{"boolean with maxLength", `{"type":"object","properties":{"a":{"type":"boolean","maxLength":5}}}`, "boolean fields take no"},
+		{"unsupported format", `{"type":"object","properties":{"a":{"type":"string","format":"email"}}}`, `only format "date"`},
+		{"date with enum", `{"type":"object","properties":{"a":{"type":"string","format":"date","enum":["2030-01-01"]}}}`, "date fields take no"},
+		{"date with maxLength", `{"type":"object","properties":{"a":{"type":"string","format":"date","maxLength":10}}}`, "date fields take no"},
+		{"empty enum", `{"type":"object","properties":{"a":{"type":"string","enum":[]}}}`, "enum must not be empty"},
+		{"duplicate enum value", `{"type":"object","properties":{"a":{"type":"string","enum":["x","x"]}}}`, "unique and non-empty"},
+		{"empty enum value", `{"type":"object","properties":{"a":{"type":"string","enum":[""]}}}`, "unique and non-empty"},
+		{"negative maxLength", `{"type":"object","properties":{"a":{"type":"string","maxLength":-1}}}`, "maxLength must be"},
+		{"maxLength too large", `{"type":"object","properties":{"a":{"type":"string","maxLength":10001}}}`, "maxLength must be"},
+		{"required not defined", `{"type":"object","required":["b"],"properties":{"a":{"type":"string"}}}`, `required field "b"`},
+		{"not json", `{"type":`, "schema:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse([]byte(tt.schema))
+			if tt.wantErr == "" {
+				if err != nil || s == nil {
+					t.Fatalf("Parse: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Parse error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	s, err := Parse([]byte(`{"type":"object","required":["smoker","pain"],"properties":{
+		"allergies":{"type":"string","maxLength":5},
+		"history":{"type":"string"},
+		"pain":{"type":"string","enum":["none","mild","severe"]},
+		"smoker":{"type":"boolean"},
+		"last_visit":{"type":"string","format":"date"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		answers string
+		want    string            // canonical answers when valid
+		errs    map[string]string // per-field errors otherwise
+	}{
+		{"all kinds",
+			`{"allergies":" nuts ","pain":"mild","smoker":false,"last_visit":"2030-01-02"}`,
+			`{"allergies":"nuts","last_visit":"2030-01-02","pain":"mild","smoker":false}`, nil},
+		{"nulls and blanks are dropped",
+			`{"allergies":null,"history":"   ","last_visit":null,"pain":"none","smoker":true}`,
+			`{"pain":"none","smoker":true}`, nil},
+		{"maxLength counts characters", `{"allergies":"αβγδε","pain":"none","smoker":true}`,
+			`{"allergies":"αβγδε","pain":"none","smoker":true}`, nil},
+		{"maxLength exceeded", `{"allergies":"peanuts","pain":"none","smoker":true}`,
+			"", map[string]string{"allergies": "must be at most 5 characters"}},
+		{"default maxLength", `{"history":"` + strings.Repeat("x", 2001) + `","pain":"none","smoker":true}`,
+			"", map[string]string{"history": "must be at most 2000 characters"}},
+		{"enum", `{"pain":"unbearable","smoker":true}`,
+			"", map[string]string{"pain": "must be one of none, mild, severe"}},
+		{"boolean type", `{"pain":"none","smoker":"yes"}`,
+			"", map[string]string{"smoker": "must be true or false"}},
+		{"string type", `{"pain":3,"smoker":true}`,
+			"", map[string]string{"pain": "must be a string"}},
+		{"date", `{"last_visit":"02/01/2030","pain":"none","smoker":true}`,
+			"", map[string]string{"last_visit": "must be a date (YYYY-MM-DD)"}},
+		{"required missing", `{"allergies":"none"}`,
+			"", map[string]string{"pain": "required", "smoker": "required"}},
+		{"required null or blank", `{"pain":"  ","smoker":null}`,
+			"", map[string]string{"pain": "required", "smoker": "required"}},
+		{"required with a bad value keeps its own error", `{"pain":"other","smoker":true}`,
+			"", map[string]string{"pain": "must be one of none, mild, severe"}},
+		{"unknown field", `{"pain":"none","smoker":true,"weight":"80kg"}`,
+			"", map[string]string{"weight": "unknown field"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var answers map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(tt.answers), &answers); err != nil {
+				t.Fatal(err)
+			}
+			got, errs := s.Validate(answers)
+			if !reflect.DeepEqual(errs, tt.errs) {
+				t.Fatalf("errors = %v, want %v", errs, tt.errs)
+			}
+			if string(got) != tt.want {
+				t.Fatalf("answers = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Sections of a batch that can be read from CSV, and their columns.
+// Columns are matched by header name in any order; blank optional cells
+// are left unset.
+//
+//	services:     name*, description, duration_min*
+//	providers:    email*, password, full_name*, speciality*, services (";"-separated names)
+//	availability: provider_email*, weekday* (1-7 or mon..sun), start*, end*
+var csvColumns = map[string][]string{
+	"services":     {"name", "description", "duration_min"},
+	"providers":    {"email", "password", "full_name", "speciality", "services"},
+	"availability": {"provider_email", "weekday", "start", "end"},
+}
+
+var csvRequired = map[string][]string{
+	"services":     {"name", "duration_min"},
+	"providers":    {"email", "full_name", "speciality"},
+	"availability": {"provider_email", "weekday", "start", "end"},
+}
+
+// Sections lists the CSV sections in the order they are applied.
+var Sections = []string{"services", "providers", "availability"}
+
+var weekdays = map[string]int32{"mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6, "sun": 7}
+
+// ReadCSV appends the rows of one section, read from CSV with a header
+// row, to b. Errors are about the file's shape; values are checked by Run.
+func ReadCSV(b *Batch, section string, r io.Reader) error {
+	known, ok := csvColumns[section]
+	if !ok {
+		return fmt.Errorf("unknown section %q", section)
+	}
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", section, err)
+	}
+	col := map[string]int{}
+	for i, h := range header {
+		h = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+		if !contains(known, h) {
+			return fmt.Errorf("%s: unknown column %q", section, h)
+		}
+		col[h] = i
+	}
+	for _, h := range csvRequired[section] {
+		if _, ok := col[h]; !ok {
+			return fmt.Errorf("%s: missing column %q", section, h)
+		}
+	}
+
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", section, err)
+		}
+		get := func(name string) string {
+			if i, ok := col[name]; ok {
+				return strings.TrimSpace(rec[i])
+			}
+			return ""
+		}
+		switch section {
+		case "services":
+			n, err := strconv.ParseInt(get("duration_min"), 10, 32)
+			if err != nil {
+				return fmt.Errorf("services line %d: duration_min must be a whole number of minutes", line)
+			}
+			s := Service{Name: get("name"), DurationMin: int32(n)}
+			if d := get("description"); d != "" {
+				s.Description = &d
+			}
+			b.Services = append(b.Services, s)
+		case "providers":
+			p := Provider{
+				Email:      get("email"),
+				Password:   get("password"),
+				FullName:   get("full_name"),
+				Speciality: get("speciality"),
+			}
+			for _, name := range strings.Split(get("services"), ";") {
+				if name = strings.TrimSpace(name); name != "" {
+					p.Services = append(p.Services, name)
+				}
+			}
+			b.Providers = append(b.Providers, p)
+		case "availability":
+			wd, ok := weekdays[strings.ToLower(get("weekday"))]
+			if !ok {
+				n, err := strconv.ParseInt(get("weekday"), 10, 32)
+				if err != nil {
+					return fmt.Errorf("availability line %d: weekday must be 1-7 or mon..sun", line)
+				}
+				wd = int32(n)
+			}
+			b.Availability = append(b.Availability, Availability{
+				ProviderEmail: get("provider_email"),
+				Weekday:       wd,
+				Start:         get("start"),
+				End:           get("end"),
+			})
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadCSV(t *testing.T) {
+	desc := "Routine check, 30 min"
+	tests := []struct {
+		name    string
+		section string
+		csv     string
+		want    Batch
+		wantErr string // substring; "" means no error
+	}{
+		{"unknown section", "clinics", "name\nA\n", Batch{}, `unknown section "clinics"`},
+		{"empty file", "services", "", Batch{}, ""},
+		{"header only", "services", "name,duration_min\n", Batch{}, ""},
+
+		// services
+		{"services", "services",
+			"\ufeffName, Description ,DURATION_MIN\nCheck-up,\"Routine check, 30 min\",30\nX-ray, ,15\n",
+			Batch{Services: []Service{
+				{Name: "Check-up", Description: &desc, DurationMin: 30},
+				{Name: "X-ray", DurationMin: 15},
+			}}, ""},
+		{"services columns in any order", "services", "duration_min,name\n20,Dental\n",
+			Batch{Services: []Service{{Name: "Dental", DurationMin: 20}}}, ""},
+		{"services missing column", "services", "name,description\nCheck-up,x\n", Batch{}, `services: missing column "duration_min"`},
+		{"services unknown column", "services", "name,duration_min,price\nCheck-up,30,10\n", Batch{}, `services: unknown column "price"`},
+		{"duration with unit", "services", "name,duration_min\nCheck-up,30m\n", Batch{}, "services line 2: duration_min must be a whole number"},
+		{"blank duration", "services", "name,duration_min\nCheck-up,30\nX-ray,\n", Batch{}, "services line 3: duration_min"},
+		{"fractional duration", "services", "name,duration_min\nCheck-up,7.5\n", Batch{}, "services line 2: duration_min"},
+		{"ragged row", "services", "name,duration_min\nCheck-up\n", Batch{}, "services:"},
+
+		// providers
+		{"providers", "providers",
+			"email,password,full_name,speciality,services\n" +
+				"a@example.com,secret123,Dr A,GP,Check-up; X-ray;\n" +
+				"b@example.com,,Dr B,Dentist,\n",
+			Batch{Providers: []Provider{
+				{Email: "a@example.com", Password: "secret123", FullName: "Dr A", Speciality: "GP", Services: []string{"Check-up", "X-ray"}},
+				{Email: "b@example.com", FullName: "Dr B", Speciality: "Dentist"},
+			}}, ""},
+		{"providers without optional columns", "providers", "full_name,email,speciality\nDr A,a@example.com,GP\n",
+			Batch{Providers: []Provider{{Email: "a@example.com", FullName: "Dr A", Speciality: "GP"}}}, ""},
+		{"providers missing column", "providers", "email,full_name\na@example.com,Dr A\n", Batch{}, `providers: missing column "speciality"`},
+		{"providers unknown column", "providers", "email,full_name,speciality,phone\na@example.com,Dr A,GP,1\n", Batch{}, `providers: unknown column "phone"`},
+
+		// availability
+		{"availability", "availability",
+			"provider_email,weekday,start,end\na@example.com,Mon,09:00,12:00\na@example.com,7,14:00,17:00\na@example.com, sat ,08:00,10:00\n",
+			Batch{Availability: []Availability{
+				{ProviderEmail: "a@example.com", Weekday: 1, Start: "09:00", End: "12:00"},
+				{ProviderEmail: "a@example.com", Weekday: 7, Start: "14:00", End: "17:00"},
+				{ProviderEmail: "a@example.com", Weekday: 6, Start: "08:00", End: "10:00"},
+			}}, ""},
+		// the range is checked with the other values, by Run
+		{"weekday out of range is left to Run", "availability", "provider_email,weekday,start,end\na@example.com,9,09:00,12:00\n",
+			Batch{Availability: []Availability{{ProviderEmail: "a@example.com", Weekday: 9, Start: "09:00", End: "12:00"}}}, ""},
+		{"weekday full name", "availability", "provider_email,weekday,start,end\na@example.com,monday,09:00,12:00\n", Batch{}, "availability line 2: weekday must be 1-7 or mon..sun"},
+		{"blank weekday", "availability", "provider_email,weekday,start,end\na@example.com,,09:00,12:00\n", Batch{}, "availability line 2: weekday"},
+		{"availability missing column", "availability", "provider_email,weekday,start\na@example.com,1,09:00\n", Batch{}, `availability: missing column "end"`},
+		{"availability unknown column", "availability", "provider_email,weekday,start,end,clinic\na@example.com,1,09:00,12:00,1\n", Batch{}, `availability: unknown column "clinic"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b Batch
+			err := ReadCSV(&b, tt.section, strings.NewReader(tt.csv))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(b, tt.want) {
+				t.Fatalf("batch = %+v, want %+v", b, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidHHMM(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"00:00", true},
+		{"09:30", true},
+		{"23:59", true},
+		{"24:00", false},
+		{"12:60", false},
+		{"9:30", false},
+		{"09:30:00", false},
+		{"0930", false},
+		{"ab:cd", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := validHHMM(tt.in); got != tt.want {
+			t.Errorf("validHHMM(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,422 @@
+// Package importer loads a clinic's catalog in one go: services, providers
+// with their login accounts, and weekly availability. It backs both the
+// admin import endpoint and medctl import.
+//
+// A batch is validated as a whole and then written in a single transaction;
+// a dry run does the same work and rolls it back, so its report also
+// catches what only the database can tell (a concurrent signup taking an
+// email, say). Nothing is written unless the whole batch is clean.
+//
+// Provider accounts get the "provider" user role and a provider role
+// assignment in the clinic. Rows without a password get an unusable one:
+// those providers sign in through OIDC or have a password set later.
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/justanamir/medappoint/internal/auth"
+	dbconn "github.com/justanamir/medappoint/internal/db"
+	"github.com/justanamir/medappoint/internal/db/gen"
+	"github.com/justanamir/medappoint/internal/fieldcrypt"
+	"github.com/justanamir/medappoint/internal/rbac"
+)
+
+// MaxRows caps each section of a batch.
+const MaxRows = 1000
+
+// unusablePassword never matches a bcrypt comparison.
+const unusablePassword = "!"
+
+// Batch is one import, all for a single clinic.
+type Batch struct {
+	Clinic       Clinic         `json:"clinic"`
+	Services     []Service      `json:"services"`
+	Providers    []Provider     `json:"providers"`
+	Availability []Availability `json:"availability"`
+}
+
+// Clinic picks an existing clinic by ID, or creates one when ID is zero.
+type Clinic struct {
+	ID       int64   `json:"id"`
+	Name     string  `json:"name"`
+	Timezone string  `json:"timezone"`
+	Address  *string `json:"address"`
+}
+
+type Service struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description"`
+	DurationMin int32   `json:"duration_min"`
+}
+
+// Provider is a new provider and their account. Services are names of the
+// clinic's services, existing or in the same batch; empty means all of
+// them.
+type Provider struct {
+	Email      string   `json:"email"`
+	Password   string   `json:"password"`
+	FullName   string   `json:"full_name"`
+	Speciality string   `json:"speciality"`
+	Services   []string `json:"services"`
+}
+
+// Availability is one weekly window (1=Mon ... 7=Sun, "HH:MM" clinic time)
+// for a provider in the batch or already in the clinic.
+type Availability struct {
+	ProviderEmail string `json:"provider_email"`
+	Weekday       int32  `json:"weekday"`
+	Start         string `json:"start"`
+	End           string `json:"end"`
+}
+
+// Problem is one validation failure. Row is 1-based within its section
+// (not counting a CSV header); 0 is the clinic.
+type Problem struct {
+	Section string `json:"section"`
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	if p.Field == "" {
+		return fmt.Sprintf("%s row %d: %s", p.Section, p.Row, p.Message)
+	}
+	return fmt.Sprintf("%s row %d: %s: %s", p.Section, p.Row, p.Field, p.Message)
+}
+
+// WriteError is a database error hit while writing one row of a batch
+// that passed validation, typically a row that clashed with data created
+// since. Section and Row locate it as in Problem.
+type WriteError struct {
+	Section string
+	Row     int
+	Err     error
+}
+
+func (e *WriteError) Error() string {
+	if e.Row == 0 {
+		return e.Section + ": " + e.Err.Error()
+	}
+	return fmt.Sprintf("%s row %d: %v", e.Section, e.Row, e.Err)
+}
+
+func (e *WriteError) Unwrap() error { return e.Err }
+
+// Report is the outcome of Run. Counts are what was (or, on a dry run,
+// would have been) created.
+type Report struct {
+	DryRun       bool      `json:"dry_run"`
+	Committed    bool      `json:"committed"`
+	ClinicID     int64     `json:"clinic_id,omitempty"`
+	Services     int       `json:"services"`
+	Providers    int       `json:"providers"`
+	Availability int       `json:"availability"`
+	Problems     []Problem `json:"problems"`
+}
+
+// OK reports whether the batch passed validation.
+func (r Report) OK() bool { return len(r.Problems) == 0 }
+
+// Run validates b and, unless dryRun is set or there are problems, commits
+// it. Problems come back in the report; the error is for everything else,
+// including constraint violations hit while writing, which come as a
+// *WriteError.
+func Run(ctx context.Context, pool *pgxpool.Pool, kr *fieldcrypt.Keyring, b Batch, dryRun bool) (Report, error) {
+	rep := Report{DryRun: dryRun, Problems: []Problem{}}
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return rep, err
+	}
+	defer func() { _ = tx.Rollback(context.WithoutCancel(ctx)) }()
+	q := gen.New(dbconn.Encrypted(tx, kr))
+
+	st, err := validate(ctx, q, b, &rep)
+	if err != nil || !rep.OK() {
+		return rep, err
+	}
+	if err := apply(ctx, q, b, st, dryRun, &rep); err != nil {
+		return rep, err
+	}
+	if dryRun {
+		return rep, nil
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return rep, err
+	}
+	rep.Committed = true
+	return rep, nil
+}
+
+// state is what validation learned about the clinic.
+type state struct {
+	services  map[string]int64 // lower(name) -> id; 0 while still to be created
+	providers map[string]int64 // lower(email) -> id; apply adds the new ones
+}
+
+func validate(ctx context.Context, q *gen.Queries, b Batch, rep *Report) (state, error) {
+	st := state{services: map[string]int64{}, providers: map[string]int64{}}
+	add := func(section string, row int, field, msg string) {
+		rep.Problems = append(rep.Problems, Problem{Section: section, Row: row, Field: field, Message: msg})
+	}
+	sizes := []struct {
+		section string
+		n       int
+	}{{"services", len(b.Services)}, {"providers", len(b.Providers)}, {"availability", len(b.Availability)}}
+	for _, sz := range sizes {
+		if sz.n > MaxRows {
+			add(sz.section, 0, "", fmt.Sprintf("at most %d rows per import", MaxRows))
+		}
+	}
+	if !rep.OK() {
+		return st, nil
+	}
+
+	c := b.Clinic
+	if c.ID > 0 {
+		if _, err := q.GetClinic(ctx, c.ID); errors.Is(err, pgx.ErrNoRows) {
+			add("clinic", 0, "id", "clinic not found")
+			return st, nil
+		} else if err != nil {
+			return st, err
+		}
+		rep.ClinicID = c.ID
+		existing, err := q.ListServiceNamesByClinic(ctx, c.ID)
+		if err != nil {
+			return st, err
+		}
+		for _, s := range existing {
+			st.services[strings.ToLower(s.Name)] = s.ID
+		}
+	} else {
+		if strings.TrimSpace(c.Name) == "" {
+			add("clinic", 0, "name", "required when id is not given")
+		}
+		if _, err := time.LoadLocation(c.Timezone); err != nil || c.Timezone == "" {
+			add("clinic", 0, "timezone", "must be an IANA timezone such as Asia/Kuala_Lumpur")
+		}
+	}
+
+	for i, s := range b.Services {
+		row := i + 1
+		key := strings.ToLower(strings.TrimSpace(s.Name))
+		switch _, dup := st.services[key]; {
+		case key == "":
+			add("services", row, "name", "required")
+		case dup:
+			add("services", row, "name", "the clinic already has a service with this name")
+		default:
+			st.services[key] = 0
+		}
+		if s.DurationMin <= 0 || s.DurationMin > 24*60 {
+			add("services", row, "duration_min", "must be between 1 and 1440")
+		}
+	}
+
+	inBatch := map[string]bool{}
+	for i, p := range b.Providers {
+		row := i + 1
+		email := strings.ToLower(strings.TrimSpace(p.Email))
+		if a, err := mail.ParseAddress(email); err != nil || a.Address != email {
+			add("providers", row, "email", "not a valid email address")
+		} else if inBatch[email] {
+			add("providers", row, "email", "appears more than once")
+		} else if _, err := q.GetUserByEmail(ctx, email); err == nil {
+			add("providers", row, "email", "already registered")
+		} else if !errors.Is(err, pgx.ErrNoRows) {
+			return st, err
+		}
+		inBatch[email] = true
+		if p.Password != "" && len(p.Password) < 8 {
+			add("providers", row, "password", "must be at least 8 characters")
+		}
+		if strings.TrimSpace(p.FullName) == "" {
+			add("providers", row, "full_name", "required")
+		}
+		if strings.TrimSpace(p.Speciality) == "" {
+			add("providers", row, "speciality", "required")
+		}
+		for _, name := range p.Services {
+			if _, ok := st.services[strings.ToLower(strings.TrimSpace(name))]; !ok {
+				add("providers", row, "services", fmt.Sprintf("unknown service %q", name))
+			}
+		}
+	}
+
+	type window struct{ start, end string }
+	windows := map[string]map[int32][]window{}
+	for i, a := range b.Availability {
+		row := i + 1
+		email := strings.ToLower(strings.TrimSpace(a.ProviderEmail))
+		if _, known := windows[email]; !known && !inBatch[email] {
+			id, err := existingProvider(ctx, q, email, c.ID)
+			if err != nil {
+				return st, err
+			}
+			if id == 0 {
+				add("availability", row, "provider_email", "not a provider in this import or clinic")
+				continue
+			}
+			st.providers[email] = id
+		}
+		if windows[email] == nil {
+			windows[email] = map[int32][]window{}
+		}
+		if a.Weekday < 1 || a.Weekday > 7 {
+			add("availability", row, "weekday", "must be 1 (Mon) to 7 (Sun)")
+			continue
+		}
+		if !validHHMM(a.Start) || !validHHMM(a.End) || a.Start >= a.End {
+			add("availability", row, "start", "start and end must be HH:MM with start before end")
+			continue
+		}
+		if id := st.providers[email]; id > 0 && windows[email][a.Weekday] == nil {
+			cur, err := q.GetProviderWeekdayAvailability(ctx, gen.GetProviderWeekdayAvailabilityParams{ProviderID: id, Weekday: a.Weekday})
+			if err != nil {
+				return st, err
+			}
+			for _, w := range cur {
+				windows[email][a.Weekday] = append(windows[email][a.Weekday], window{w.StartHhmm, w.EndHhmm})
+			}
+		}
+		overlap := false
+		for _, w := range windows[email][a.Weekday] {
+			if a.Start < w.end && w.start < a.End {
+				overlap = true
+			}
+		}
+		if overlap {
+			add("availability", row, "start", "overlaps another window for this provider and weekday")
+			continue
+		}
+		windows[email][a.Weekday] = append(windows[email][a.Weekday], window{a.Start, a.End})
+	}
+	return st, nil
+}
+
+// existingProvider is the ID of the provider with that email in clinicID,
+// or 0.
+func existingProvider(ctx context.Context, q *gen.Queries, email string, clinicID int64) (int64, error) {
+	if clinicID == 0 || email == "" {
+		return 0, nil
+	}
+	u, err := q.GetUserByEmail(ctx, email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	p, err := q.GetProviderByUserID(ctx, u.ID)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && p.ClinicID != clinicID) {
+		return 0, nil
+	}
+	return p.ID, err
+}
+
+func apply(ctx context.Context, q *gen.Queries, b Batch, st state, dryRun bool, rep *Report) error {
+	clinicID := b.Clinic.ID
+	if clinicID == 0 {
+		c, err := q.CreateClinic(ctx, gen.CreateClinicParams{
+			Name:     strings.TrimSpace(b.Clinic.Name),
+			Timezone: b.Clinic.Timezone,
+			Address:  b.Clinic.Address,
+		})
+		if err != nil {
+			return &WriteError{Section: "clinic", Err: err}
+		}
+		clinicID = c.ID
+	}
+	rep.ClinicID = clinicID
+
+	for i, s := range b.Services {
+		svc, err := q.CreateService(ctx, gen.CreateServiceParams{
+			ClinicID:    clinicID,
+			Name:        strings.TrimSpace(s.Name),
+			Description: s.Description,
+			DurationMin: s.DurationMin,
+		})
+		if err != nil {
+			return &WriteError{Section: "services", Row: i + 1, Err: err}
+		}
+		st.services[strings.ToLower(svc.Name)] = svc.ID
+		rep.Services++
+	}
+
+	for i, p := range b.Providers {
+		email := strings.ToLower(strings.TrimSpace(p.Email))
+		hash := unusablePassword
+		if p.Password != "" && !dryRun {
+			var err error
+			if hash, err = auth.HashPassword(p.Password); err != nil {
+				return err
+			}
+		}
+		u, err := q.CreateUser(ctx, gen.CreateUserParams{Email: email, PasswordHash: hash, Role: rbac.RoleProvider})
+		if err != nil {
+			return &WriteError{Section: "providers", Row: i + 1, Err: err}
+		}
+		prov, err := q.CreateProvider(ctx, gen.CreateProviderParams{
+			UserID:     u.ID,
+			FullName:   strings.TrimSpace(p.FullName),
+			Speciality: strings.TrimSpace(p.Speciality),
+			ClinicID:   clinicID,
+		})
+		if err != nil {
+			return &WriteError{Section: "providers", Row: i + 1, Err: err}
+		}
+		if _, err := q.CreateRoleAssignment(ctx, gen.CreateRoleAssignmentParams{
+			UserID:   u.ID,
+			ClinicID: pgtype.Int8{Int64: clinicID, Valid: true},
+			Role:     rbac.RoleProvider,
+		}); err != nil {
+			return &WriteError{Section: "providers", Row: i + 1, Err: err}
+		}
+		var serviceIDs []int64
+		if len(p.Services) == 0 {
+			for _, id := range st.services {
+				serviceIDs = append(serviceIDs, id)
+			}
+		}
+		for _, name := range p.Services {
+			serviceIDs = append(serviceIDs, st.services[strings.ToLower(strings.TrimSpace(name))])
+		}
+		for _, sid := range serviceIDs {
+			if _, err := q.UpsertProviderService(ctx, gen.UpsertProviderServiceParams{ProviderID: prov.ID, ServiceID: sid}); err != nil {
+				return &WriteError{Section: "providers", Row: i + 1, Err: err}
+			}
+		}
+		st.providers[email] = prov.ID
+		rep.Providers++
+	}
+
+	for i, a := range b.Availability {
+		if _, err := q.CreateAvailability(ctx, gen.CreateAvailabilityParams{
+			ProviderID: st.providers[strings.ToLower(strings.TrimSpace(a.ProviderEmail))],
+			Weekday:    a.Weekday,
+			StartHhmm:  a.Start,
+			EndHhmm:    a.End,
+		}); err != nil {
+			return &WriteError{Section: "availability", Row: i + 1, Err: err}
+		}
+		rep.Availability++
+	}
+	return nil
+}
+
+func validHHMM(s string) bool {
+	if len(s) != 5 {
+		return false
+	}
+	_, err := time.Parse("15:04", s)
+	return err == nil
+}
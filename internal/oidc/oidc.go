@@ -0,0 +1,239 @@
+// Package oidc is a small OpenID Connect relying party: discovery, the
+// authorization-code flow with PKCE, and ID token verification against the
+// provider's JWKS. It only covers what staff login needs.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type Config struct {
+	Issuer       string // e.g. https://login.example.com/realms/staff
+	ClientID     string
+	ClientSecret string // optional for public clients
+	RedirectURL  string // our /v1/auth/oidc/callback
+	Scopes       []string
+}
+
+// Discovery is the subset of /.well-known/openid-configuration we use.
+type Discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// IDTokenClaims are the claims we read from the provider's ID token.
+type IDTokenClaims struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Nonce         string `json:"nonce"`
+	jwt.RegisteredClaims
+}
+
+type Client struct {
+	cfg  Config
+	http *http.Client
+
+	mu        sync.Mutex
+	disc      *Discovery
+	keys      map[string]interface{}
+	keysFetch time.Time
+}
+
+func New(cfg Config) *Client {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &Client{cfg: cfg, http: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// discovery fetches (once) and caches the provider metadata.
+func (c *Client) discovery(ctx context.Context) (*Discovery, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.disc != nil {
+		return c.disc, nil
+	}
+	u := strings.TrimSuffix(c.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	var d Discovery
+	if err := c.getJSON(ctx, u, &d); err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	if strings.TrimSuffix(d.Issuer, "/") != strings.TrimSuffix(c.cfg.Issuer, "/") {
+		return nil, fmt.Errorf("oidc discovery: issuer mismatch %q", d.Issuer)
+	}
+	c.disc = &d
+	return c.disc, nil
+}
+
+// Pending is what we must remember between redirect and callback.
+type Pending struct {
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+// AuthURL starts a login: it returns the provider URL to redirect to and the
+// values to keep until the callback.
+func (c *Client) AuthURL(ctx context.Context) (string, Pending, error) {
+	d, err := c.discovery(ctx)
+	if err != nil {
+		return "", Pending{}, err
+	}
+	p := Pending{State: randomString(), Nonce: randomString(), Verifier: randomString()}
+	sum := sha256.Sum256([]byte(p.Verifier))
+
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", c.cfg.ClientID)
+	q.Set("redirect_uri", c.cfg.RedirectURL)
+	q.Set("scope", strings.Join(c.cfg.Scopes, " "))
+	q.Set("state", p.State)
+	q.Set("nonce", p.Nonce)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(sum[:]))
+	q.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return d.AuthorizationEndpoint + sep + q.Encode(), p, nil
+}
+
+// Exchange redeems the authorization code and returns the verified ID token claims.
+func (c *Client) Exchange(ctx context.Context, code string, p Pending) (*IDTokenClaims, error) {
+	d, err := c.discovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.cfg.RedirectURL)
+	form.Set("client_id", c.cfg.ClientID)
+	form.Set("code_verifier", p.Verifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(c.cfg.ClientID), url.QueryEscape(c.cfg.ClientSecret))
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint: %s", resp.Status)
+	}
+	var tr struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tr); err != nil || tr.IDToken == "" {
+		return nil, errors.New("token endpoint: no id_token")
+	}
+
+	claims, err := c.verify(ctx, d, tr.IDToken)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Nonce != p.Nonce {
+		return nil, errors.New("id_token nonce mismatch")
+	}
+	return claims, nil
+}
+
+func (c *Client) verify(ctx context.Context, d *Discovery, raw string) (*IDTokenClaims, error) {
+	tok, err := jwt.ParseWithClaims(raw, &IDTokenClaims{}, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return c.key(ctx, d, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "EdDSA"}),
+		jwt.WithIssuer(d.Issuer),
+		jwt.WithAudience(c.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("id_token: %w", err)
+	}
+	claims, ok := tok.Claims.(*IDTokenClaims)
+	if !ok || !tok.Valid || claims.Subject == "" {
+		return nil, errors.New("id_token: invalid")
+	}
+	return claims, nil
+}
+
+// key returns the provider key for kid, refetching the JWKS when the kid is
+// unknown (the provider rotated) but at most once a minute.
+func (c *Client) key(ctx context.Context, d *Discovery, kid string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if k, ok := c.keys[kid]; ok {
+		return k, nil
+	}
+	if time.Since(c.keysFetch) < time.Minute && c.keys != nil {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	var set jwkSet
+	if err := c.getJSON(ctx, d.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+	c.keys = set.publicKeys()
+	c.keysFetch = time.Now()
+	if k, ok := c.keys[kid]; ok {
+		return k, nil
+	}
+	// Providers with a single key sometimes omit kid.
+	if kid == "" && len(c.keys) == 1 {
+		for _, k := range c.keys {
+			return k, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown kid %q", kid)
+}
+
+func (c *Client) getJSON(ctx context.Context, u string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+func randomString() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
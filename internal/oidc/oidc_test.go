@@ -0,0 +1,195 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockIdP is a minimal OpenID provider: discovery, JWKS, and a token
+// endpoint that checks PKCE against the challenge sent to /authorize.
+type mockIdP struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	key       ed25519.PrivateKey // signs ID tokens; the JWKS keeps the original
+	challenge string
+	claims    func(c *IDTokenClaims) // sets the nonce and tweaks the ID token
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockIdP{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(Discovery{
+			Issuer:                m.URL,
+			AuthorizationEndpoint: m.URL + "/authorize",
+			TokenEndpoint:         m.URL + "/token",
+			JWKSURI:               m.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		pub := key.Public().(ed25519.PublicKey)
+		_ = json.NewEncoder(w).Encode(jwkSet{Keys: []jwk{{
+			Kty: "OKP", Crv: "Ed25519", Kid: "k1", Use: "sig",
+			X: base64.RawURLEncoding.EncodeToString(pub),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		m.mu.Lock()
+		challenge, tweak := m.challenge, m.claims
+		m.mu.Unlock()
+		if r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("code") != "the-code" ||
+			base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		c := &IDTokenClaims{
+			Email:         "dr.tan@example.com",
+			EmailVerified: true,
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    m.URL,
+				Subject:   "user-123",
+				Audience:  jwt.ClaimStrings{"medappoint"},
+				IssuedAt:  jwt.NewNumericDate(time.Now()),
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(5 * time.Minute)),
+			},
+		}
+		if tweak != nil {
+			tweak(c)
+		}
+		tok := jwt.NewWithClaims(jwt.SigningMethodEdDSA, c)
+		tok.Header["kid"] = "k1"
+		m.mu.Lock()
+		signer := m.key
+		m.mu.Unlock()
+		raw, err := tok.SignedString(signer)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"id_token": raw, "token_type": "Bearer"})
+	})
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+	return m
+}
+
+// login runs AuthURL and plays the browser's visit to /authorize.
+func (m *mockIdP) login(t *testing.T, c *Client) Pending {
+	t.Helper()
+	authURL, p, err := c.AuthURL(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if !strings.HasPrefix(authURL, m.URL+"/authorize?") || q.Get("code_challenge_method") != "S256" ||
+		q.Get("state") != p.State || q.Get("client_id") != "medappoint" {
+		t.Fatalf("unexpected authorization URL %s", authURL)
+	}
+	m.mu.Lock()
+	m.challenge = q.Get("code_challenge")
+	nonce := q.Get("nonce")
+	prev := m.claims
+	m.claims = func(c *IDTokenClaims) {
+		c.Nonce = nonce
+		if prev != nil {
+			prev(c)
+		}
+	}
+	m.mu.Unlock()
+	return p
+}
+
+func newClient(m *mockIdP) *Client {
+	return New(Config{Issuer: m.URL, ClientID: "medappoint", RedirectURL: "http://localhost/cb"})
+}
+
+func TestExchange(t *testing.T) {
+	m := newMockIdP(t)
+	c := newClient(m)
+	p := m.login(t, c)
+	claims, err := c.Exchange(context.Background(), "the-code", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "user-123" || claims.Email != "dr.tan@example.com" || !claims.EmailVerified {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+}
+
+func TestExchangeRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		tweak func(c *IDTokenClaims)
+		pend  func(p *Pending)
+	}{
+		{name: "wrong PKCE verifier", pend: func(p *Pending) { p.Verifier = "something-else" }},
+		{name: "nonce mismatch", pend: func(p *Pending) { p.Nonce = "other" }},
+		{name: "wrong audience", tweak: func(c *IDTokenClaims) { c.Audience = jwt.ClaimStrings{"someone-else"} }},
+		{name: "wrong issuer", tweak: func(c *IDTokenClaims) { c.Issuer = "https://evil.example.com" }},
+		{name: "expired", tweak: func(c *IDTokenClaims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute)) }},
+		{name: "no subject", tweak: func(c *IDTokenClaims) { c.Subject = "" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMockIdP(t)
+			m.claims = tt.tweak
+			c := newClient(m)
+			p := m.login(t, c)
+			if tt.pend != nil {
+				tt.pend(&p)
+			}
+			if _, err := c.Exchange(context.Background(), "the-code", p); err == nil {
+				t.Fatal("Exchange succeeded")
+			}
+		})
+	}
+}
+
+func TestExchangeRejectsForeignKey(t *testing.T) {
+	m := newMockIdP(t)
+	_, other, _ := ed25519.GenerateKey(rand.Reader)
+	m.mu.Lock()
+	m.key = other // sign with a key that isn't in the JWKS
+	m.mu.Unlock()
+	c := newClient(m)
+	p := m.login(t, c)
+	if _, err := c.Exchange(context.Background(), "the-code", p); err == nil {
+		t.Fatal("Exchange accepted a token signed with an unknown key")
+	}
+}
+
+func TestStateStoreSingleUse(t *testing.T) {
+	s := NewStateStore(time.Minute)
+	s.Put(Pending{State: "abc", Nonce: "n"})
+	if p, ok := s.Take("abc"); !ok || p.Nonce != "n" {
+		t.Fatal("state not found")
+	}
+	if _, ok := s.Take("abc"); ok {
+		t.Fatal("state taken twice")
+	}
+}
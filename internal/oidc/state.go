@@ -0,0 +1,52 @@
+package oidc
+
+import (
+	"sync"
+	"time"
+)
+
+// StateStore keeps pending logins between the redirect and the callback.
+// Entries are single-use and expire; it is in-memory, so run the callback on
+// the instance that started the login (sticky sessions) or swap in a shared store.
+type StateStore struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	pending map[string]entry
+}
+
+type entry struct {
+	p       Pending
+	expires time.Time
+}
+
+func NewStateStore(ttl time.Duration) *StateStore {
+	return &StateStore{ttl: ttl, pending: map[string]entry{}}
+}
+
+func (s *StateStore) Put(p Pending) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for k, e := range s.pending {
+		if now.After(e.expires) {
+			delete(s.pending, k)
+		}
+	}
+	s.pending[p.State] = entry{p: p, expires: now.Add(s.ttl)}
+}
+
+// Take returns and forgets the pending login for state.
+func (s *StateStore) Take(state string) (Pending, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.pending[state]
+	if !ok {
+		return Pending{}, false
+	}
+	delete(s.pending, state)
+	if time.Now().After(e.expires) {
+		return Pending{}, false
+	}
+	return e.p, true
+}
@@ -0,0 +1,145 @@
+package rbac
+
+// Permission names a single capability, "<resource>:<action>".
+type Permission string
+
+const (
+	AppointmentsRead  Permission = "appointments:read"
+	AppointmentsWrite Permission = "appointments:write" // book/cancel on behalf of patients
+	ScheduleRead      Permission = "schedule:read"      // any provider schedule in the clinic
+	ScheduleReadOwn   Permission = "schedule:read:own"  // only the caller's own provider schedule
+	ScheduleWrite     Permission = "schedule:write"
+	ScheduleWriteOwn  Permission = "schedule:write:own"
+	PatientsRead      Permission = "patients:read"
+	PatientsWrite     Permission = "patients:write"
+	PatientsErase     Permission = "patients:erase"  // approve GDPR/PDPA erasure requests
+	NotesRead         Permission = "notes:read"      // any provider's visit notes in the clinic
+	NotesWriteOwn     Permission = "notes:write:own" // write and sign notes on the caller's own appointments
+	CatalogWrite      Permission = "catalog:write"   // clinics, providers, services
+	ReportsRead       Permission = "reports:read"
+	StaffManage       Permission = "staff:manage" // assign clinic roles
+	SecurityManage    Permission = "security:manage"
+	AuditRead         Permission = "audit:read"
+)
+
+// Staff roles that can be assigned per clinic (or globally, for admin).
+const (
+	RoleAdmin         = "admin"
+	RoleClinicManager = "clinic_manager"
+	RoleReceptionist  = "receptionist"
+	RoleProvider      = "provider"
+)
+
+var rolePermissions = map[string][]Permission{
+	RoleAdmin: {
+		AppointmentsRead, AppointmentsWrite,
+		ScheduleRead, ScheduleWrite,
+		PatientsRead, PatientsWrite, PatientsErase,
+		NotesRead,
+		CatalogWrite, ReportsRead, StaffManage,
+		SecurityManage, AuditRead,
+	},
+	RoleClinicManager: {
+		AppointmentsRead, AppointmentsWrite,
+		ScheduleRead, ScheduleWrite,
+		PatientsRead,
+		CatalogWrite, ReportsRead, StaffManage,
+	},
+	RoleReceptionist: {
+		AppointmentsRead, AppointmentsWrite,
+		ScheduleRead,
+		PatientsRead, PatientsWrite,
+	},
+	RoleProvider: {
+		ScheduleReadOwn, ScheduleWriteOwn,
+		NotesWriteOwn,
+	},
+}
+
+// ValidRole reports whether role can be assigned.
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// RoleHas reports whether role carries perm (ignoring clinic scope).
+func RoleHas(role string, perm Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Grant is one role assignment: a role held in one clinic, or in all of them.
+type Grant struct {
+	Role       string
+	ClinicID   int64
+	AllClinics bool
+}
+
+// Grants are everything a user holds.
+type Grants []Grant
+
+// Can reports whether any grant gives perm in clinicID.
+func (gs Grants) Can(perm Permission, clinicID int64) bool {
+	return gs.Scope(perm).Allows(clinicID)
+}
+
+// Any reports whether perm is held in at least one clinic.
+func (gs Grants) Any(perm Permission) bool {
+	return !gs.Scope(perm).Empty()
+}
+
+// Scope collects the clinics in which perm is held.
+func (gs Grants) Scope(perm Permission) Scope {
+	var s Scope
+	seen := map[int64]bool{}
+	for _, g := range gs {
+		if !RoleHas(g.Role, perm) {
+			continue
+		}
+		if g.AllClinics {
+			return Scope{All: true}
+		}
+		if !seen[g.ClinicID] {
+			seen[g.ClinicID] = true
+			s.ClinicIDs = append(s.ClinicIDs, g.ClinicID)
+		}
+	}
+	return s
+}
+
+// Scope is the set of clinics a permission applies to.
+type Scope struct {
+	All       bool
+	ClinicIDs []int64
+}
+
+func (s Scope) Empty() bool {
+	return !s.All && len(s.ClinicIDs) == 0
+}
+
+func (s Scope) Allows(clinicID int64) bool {
+	if s.All {
+		return true
+	}
+	for _, id := range s.ClinicIDs {
+		if id == clinicID {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns the clinic IDs to pass to a scoped query; nil means "no filter".
+func (s Scope) Filter() []int64 {
+	if s.All {
+		return nil
+	}
+	if s.ClinicIDs == nil {
+		return []int64{}
+	}
+	return s.ClinicIDs
+}
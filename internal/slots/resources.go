@@ -0,0 +1,68 @@
+package slots
+
+import "time"
+
+// Resource is a room or piece of equipment on a given date: its opening
+// windows (none means open whenever the provider is) and the intervals
+// already held by other appointments.
+type Resource struct {
+	ID     int64
+	Kind   string
+	Avails []AvailWindow
+	Booked []BookedRange
+}
+
+// Allocate picks one free resource of every kind in kinds for [start, end),
+// preferring the lowest ID. ok is false when some kind has nothing free.
+// pool is expected in ascending ID order within each kind.
+func Allocate(start, end time.Time, loc *time.Location, kinds []string, pool []Resource) (ids []int64, ok bool) {
+	start, end = start.In(loc), end.In(loc)
+	dayStart := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc)
+	for _, kind := range kinds {
+		found := false
+		for _, res := range pool {
+			if res.Kind != kind || !res.open(dayStart, loc, start, end) || overlapsAny(start, end, res.Booked) {
+				continue
+			}
+			ids = append(ids, res.ID)
+			found = true
+			break
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return ids, true
+}
+
+// FilterByResources drops the starts for which Allocate finds no resources.
+func FilterByResources(starts []time.Time, durationMin int, loc *time.Location, kinds []string, pool []Resource) []time.Time {
+	if len(kinds) == 0 {
+		return starts
+	}
+	step := time.Duration(durationMin) * time.Minute
+	out := starts[:0:0]
+	for _, t := range starts {
+		if _, ok := Allocate(t, t.Add(step), loc, kinds, pool); ok {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// open reports whether [start, end) fits inside one of the resource's windows.
+func (r Resource) open(dayStart time.Time, loc *time.Location, start, end time.Time) bool {
+	if len(r.Avails) == 0 {
+		return true
+	}
+	for _, w := range r.Avails {
+		ws, we, err := windowTimes(dayStart, loc, w.StartHHMM, w.EndHHMM)
+		if err != nil {
+			continue
+		}
+		if !start.Before(ws) && !end.After(we) {
+			return true
+		}
+	}
+	return false
+}
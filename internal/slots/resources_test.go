@@ -0,0 +1,55 @@
+package slots
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAllocate(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Kuala_Lumpur")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(h, m int) time.Time { return time.Date(2030, 3, 4, h, m, 0, 0, loc) }
+	pool := []Resource{
+		{ID: 1, Kind: "room", Booked: []BookedRange{{Start: at(9, 0), End: at(10, 0)}}},
+		{ID: 2, Kind: "room"},
+		{ID: 5, Kind: "xray", Avails: []AvailWindow{{StartHHMM: "13:00", EndHHMM: "17:00"}}},
+		{ID: 6, Kind: "xray", Avails: []AvailWindow{{StartHHMM: "08:00", EndHHMM: "12:00"}},
+			Booked: []BookedRange{{Start: at(11, 30), End: at(12, 0)}}},
+	}
+
+	tests := []struct {
+		name       string
+		start, end time.Time
+		kinds      []string
+		want       []int64
+		ok         bool
+	}{
+		{"lowest free id", at(10, 0), at(10, 30), []string{"room"}, []int64{1}, true},
+		{"first one is booked", at(9, 30), at(10, 0), []string{"room"}, []int64{2}, true},
+		{"touching bookings don't overlap", at(8, 30), at(9, 0), []string{"room"}, []int64{1}, true},
+		{"one of each kind", at(14, 0), at(14, 30), []string{"room", "xray"}, []int64{1, 5}, true},
+		{"only inside opening hours", at(10, 0), at(10, 30), []string{"xray"}, []int64{6}, true},
+		{"crossing a window edge", at(11, 45), at(12, 15), []string{"xray"}, nil, false},
+		{"nothing free", at(11, 30), at(12, 0), []string{"xray"}, nil, false},
+		{"unknown kind", at(10, 0), at(10, 30), []string{"room", "laser"}, nil, false},
+		{"no kinds", at(10, 0), at(10, 30), nil, nil, true},
+		{"times in another zone", at(14, 0).UTC(), at(14, 30).UTC(), []string{"xray"}, []int64{5}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Allocate(tt.start, tt.end, loc, tt.kinds, pool)
+			if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Allocate = %v, %v; want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+
+	starts := []time.Time{at(9, 0), at(11, 0), at(11, 30), at(14, 0)}
+	got := FilterByResources(starts, 30, loc, []string{"xray"}, pool)
+	if want := []time.Time{at(9, 0), at(11, 0), at(14, 0)}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("FilterByResources = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,54 @@
+package slots
+
+import (
+	"sort"
+	"time"
+)
+
+// Span is a half-open interval [Start, End).
+type Span struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Utilization splits the availability windows on date into the parts not
+// covered by booked (free, in order) and reports how many minutes the
+// windows hold and how many of those are booked. Bookings outside the
+// windows don't count towards either.
+func Utilization(date time.Time, loc *time.Location, avails []AvailWindow, booked []BookedRange) (free []Span, availMin, bookedMin int, err error) {
+	date = date.In(loc)
+	dayStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+
+	bs := make([]BookedRange, len(booked))
+	copy(bs, booked)
+	sort.Slice(bs, func(i, j int) bool { return bs[i].Start.Before(bs[j].Start) })
+
+	for _, w := range avails {
+		ws, we, err := windowTimes(dayStart, loc, w.StartHHMM, w.EndHHMM)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		availMin += int(we.Sub(ws) / time.Minute)
+		cur := ws
+		for _, b := range bs {
+			if !b.End.After(cur) || !b.Start.Before(we) {
+				continue
+			}
+			if b.Start.After(cur) {
+				free = append(free, Span{Start: cur, End: b.Start})
+				cur = b.Start
+			}
+			end := b.End
+			if end.After(we) {
+				end = we
+			}
+			bookedMin += int(end.Sub(cur) / time.Minute)
+			cur = end
+		}
+		if cur.Before(we) {
+			free = append(free, Span{Start: cur, End: we})
+		}
+	}
+	sort.Slice(free, func(i, j int) bool { return free[i].Start.Before(free[j].Start) })
+	return free, availMin, bookedMin, nil
+}
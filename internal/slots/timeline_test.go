@@ -0,0 +1,76 @@
+package slots
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestUtilization(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Kuala_Lumpur")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(h, m int) time.Time { return time.Date(2030, 3, 4, h, m, 0, 0, loc) }
+	span := func(h1, m1, h2, m2 int) Span { return Span{Start: at(h1, m1), End: at(h2, m2)} }
+	booked := func(h1, m1, h2, m2 int) BookedRange { return BookedRange{Start: at(h1, m1), End: at(h2, m2)} }
+	morning := AvailWindow{StartHHMM: "09:00", EndHHMM: "12:00"}
+	afternoon := AvailWindow{StartHHMM: "14:00", EndHHMM: "17:00"}
+
+	tests := []struct {
+		name      string
+		avails    []AvailWindow
+		booked    []BookedRange
+		free      []Span
+		availMin  int
+		bookedMin int
+	}{
+		{"empty day", []AvailWindow{morning}, nil, []Span{span(9, 0, 12, 0)}, 180, 0},
+		{"no availability", nil, []BookedRange{booked(9, 0, 10, 0)}, nil, 0, 0},
+		{
+			"bookings given out of order",
+			[]AvailWindow{morning},
+			[]BookedRange{booked(11, 0, 11, 30), booked(9, 0, 9, 30)},
+			[]Span{span(9, 30, 11, 0), span(11, 30, 12, 0)}, 180, 60,
+		},
+		{
+			"back to back",
+			[]AvailWindow{morning},
+			[]BookedRange{booked(9, 0, 10, 0), booked(10, 0, 12, 0)},
+			nil, 180, 180,
+		},
+		{
+			"overlapping bookings count once",
+			[]AvailWindow{morning},
+			[]BookedRange{booked(9, 0, 10, 0), booked(9, 30, 10, 30)},
+			[]Span{span(10, 30, 12, 0)}, 180, 90,
+		},
+		{
+			"clipped to the window",
+			[]AvailWindow{morning, afternoon},
+			[]BookedRange{booked(8, 0, 9, 30), booked(11, 45, 14, 15), booked(18, 0, 19, 0)},
+			[]Span{span(9, 30, 11, 45), span(14, 15, 17, 0)}, 360, 60,
+		},
+		{
+			"windows given out of order",
+			[]AvailWindow{afternoon, morning},
+			[]BookedRange{booked(15, 0, 16, 0)},
+			[]Span{span(9, 0, 12, 0), span(14, 0, 15, 0), span(16, 0, 17, 0)}, 360, 60,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			free, availMin, bookedMin, err := Utilization(at(0, 0), loc, tt.avails, tt.booked)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(free, tt.free) || availMin != tt.availMin || bookedMin != tt.bookedMin {
+				t.Fatalf("Utilization = %v, %d, %d; want %v, %d, %d", free, availMin, bookedMin, tt.free, tt.availMin, tt.bookedMin)
+			}
+		})
+	}
+
+	if _, _, _, err := Utilization(at(0, 0), loc, []AvailWindow{{StartHHMM: "17:00", EndHHMM: "09:00"}}, nil); err == nil {
+		t.Fatal("accepted a window ending before it starts")
+	}
+}
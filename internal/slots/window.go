@@ -0,0 +1,66 @@
+package slots
+
+import (
+	"errors"
+	"time"
+)
+
+// Window is how close to and how far ahead of now a start may be booked.
+// Zero values mean no limit.
+type Window struct {
+	MinLead        time.Duration
+	MaxAdvanceDays int    // last bookable day is today + MaxAdvanceDays
+	SameDayCutoff  string // "HH:MM"; from then on today is closed
+}
+
+var (
+	ErrTooSoon      = errors.New("start is within the minimum lead time")
+	ErrTooFarAhead  = errors.New("start is beyond the advance booking window")
+	ErrSameDayClose = errors.New("same-day booking has closed")
+)
+
+// Check reports why start can't be booked at now, or nil if it can.
+func (w Window) Check(start, now time.Time, loc *time.Location) error {
+	start, now = start.In(loc), now.In(loc)
+	if start.Before(now.Add(w.MinLead)) {
+		return ErrTooSoon
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	if w.MaxAdvanceDays > 0 && !start.Before(today.AddDate(0, 0, w.MaxAdvanceDays+1)) {
+		return ErrTooFarAhead
+	}
+	if w.SameDayCutoff != "" && sameYMD(start, now) {
+		m, err := parseHHMM(w.SameDayCutoff)
+		if err == nil && !now.Before(today.Add(time.Duration(m)*time.Minute)) {
+			return ErrSameDayClose
+		}
+	}
+	return nil
+}
+
+// Filter keeps the starts that Check accepts.
+func (w Window) Filter(starts []time.Time, now time.Time, loc *time.Location) []time.Time {
+	out := starts[:0]
+	for _, t := range starts {
+		if w.Check(t, now, loc) == nil {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// ClosesAt is the moment start stops being bookable because of the lead
+// time or the same-day cutoff.
+func (w Window) ClosesAt(start time.Time, loc *time.Location) time.Time {
+	start = start.In(loc)
+	at := start.Add(-w.MinLead)
+	if w.SameDayCutoff != "" {
+		if m, err := parseHHMM(w.SameDayCutoff); err == nil {
+			day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc)
+			if cut := day.Add(time.Duration(m) * time.Minute); cut.Before(at) {
+				at = cut
+			}
+		}
+	}
+	return at
+}
@@ -0,0 +1,70 @@
+package slots
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWindowCheck(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Kuala_Lumpur")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2030, 3, 4, 10, 0, 0, 0, loc)
+	at := func(day, h, m int) time.Time { return time.Date(2030, 3, day, h, m, 0, 0, loc) }
+
+	tests := []struct {
+		name  string
+		w     Window
+		start time.Time
+		want  error
+	}{
+		{"no limits", Window{}, at(4, 10, 30), nil},
+		{"no limits, in the past", Window{}, at(4, 9, 0), ErrTooSoon},
+		{"lead time met", Window{MinLead: 2 * time.Hour}, at(4, 12, 0), nil},
+		{"inside lead time", Window{MinLead: 2 * time.Hour}, at(4, 11, 59), ErrTooSoon},
+		{"lead time across midnight", Window{MinLead: 24 * time.Hour}, at(5, 9, 0), ErrTooSoon},
+		{"last advance day", Window{MaxAdvanceDays: 7}, at(11, 23, 30), nil},
+		{"past the advance window", Window{MaxAdvanceDays: 7}, at(12, 0, 0), ErrTooFarAhead},
+		{"before the cutoff", Window{SameDayCutoff: "12:00"}, at(4, 15, 0), nil},
+		{"cutoff only closes today", Window{SameDayCutoff: "09:00"}, at(5, 8, 0), nil},
+		{"after the cutoff", Window{SameDayCutoff: "10:00"}, at(4, 15, 0), ErrSameDayClose},
+		{"malformed cutoff is ignored", Window{SameDayCutoff: "ten"}, at(4, 15, 0), nil},
+		{"start given in UTC", Window{SameDayCutoff: "10:00"}, at(4, 15, 0).UTC(), ErrSameDayClose},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.w.Check(tt.start, now, loc); got != tt.want {
+				t.Fatalf("Check = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// the advance window counts calendar days in loc, not in UTC
+	late := time.Date(2030, 3, 4, 23, 30, 0, 0, loc) // 15:30 UTC
+	if err := (Window{MaxAdvanceDays: 1}).Check(at(5, 20, 0), late.UTC(), loc); err != nil {
+		t.Fatalf("tomorrow in loc rejected: %v", err)
+	}
+	if err := (Window{MaxAdvanceDays: 1}).Check(at(6, 0, 30), late.UTC(), loc); err != ErrTooFarAhead {
+		t.Fatalf("two days ahead in loc: %v", err)
+	}
+}
+
+func TestWindowClosesAt(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Kuala_Lumpur")
+	start := time.Date(2030, 3, 4, 15, 0, 0, 0, loc)
+	tests := []struct {
+		w    Window
+		want time.Time
+	}{
+		{Window{}, start},
+		{Window{MinLead: time.Hour}, start.Add(-time.Hour)},
+		{Window{MinLead: time.Hour, SameDayCutoff: "12:00"}, time.Date(2030, 3, 4, 12, 0, 0, 0, loc)},
+		{Window{MinLead: 4 * time.Hour, SameDayCutoff: "12:00"}, time.Date(2030, 3, 4, 11, 0, 0, 0, loc)},
+	}
+	for _, tt := range tests {
+		if got := tt.w.ClosesAt(start, loc); !got.Equal(tt.want) {
+			t.Errorf("%+v.ClosesAt = %v, want %v", tt.w, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSigningKey(t *testing.T) {
+	// the derivation example from the AWS SigV4 documentation
+	got := hex.EncodeToString(signingKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam"))
+	if want := "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d"; got != want {
+		t.Fatalf("signingKey = %s, want %s", got, want)
+	}
+}
+
+func TestCanonicalRequest(t *testing.T) {
+	s, err := NewS3("https://s3.ap-southeast-1.amazonaws.com", "ap-southeast-1", "medappoint", "AKIDEXAMPLE", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := s.request(context.Background(), http.MethodGet, "appointments/7/Referral letter (1)~ü.pdf", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"GET",
+		"/medappoint/appointments/7/Referral%20letter%20%281%29~%C3%BC.pdf",
+		"",
+		"host:s3.ap-southeast-1.amazonaws.com",
+		"x-amz-content-sha256:UNSIGNED-PAYLOAD",
+		"x-amz-date:20240315T083000Z",
+		"",
+		"host;x-amz-content-sha256;x-amz-date",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	if got := canonicalRequest(req, "20240315T083000Z"); got != want {
+		t.Fatalf("canonical request:\n%s\nwant:\n%s", got, want)
+	}
+	if req.URL.String() != "https://s3.ap-southeast-1.amazonaws.com/medappoint/appointments/7/Referral%20letter%20%281%29~%C3%BC.pdf" {
+		t.Fatalf("request URL %s differs from the signed path", req.URL)
+	}
+
+	s.sign(req, time.Date(2024, 3, 15, 8, 30, 0, 0, time.UTC))
+	sum := sha256.Sum256([]byte(want))
+	toSign := "AWS4-HMAC-SHA256\n20240315T083000Z\n20240315/ap-southeast-1/s3/aws4_request\n" + hex.EncodeToString(sum[:])
+	sig := hex.EncodeToString(hmacSHA256(signingKey("secret", "20240315", "ap-southeast-1", "s3"), toSign))
+	wantAuth := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20240315/ap-southeast-1/s3/aws4_request, " +
+		"SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=" + sig
+	if got := req.Header.Get("Authorization"); got != wantAuth {
+		t.Fatalf("Authorization = %s\nwant %s", got, wantAuth)
+	}
+}
+
+// fakeS3 keeps objects in memory and checks each request's signature
+// against the request as it arrived.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	secret  string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	amzDate := r.Header.Get("X-Amz-Date")
+	day, _, _ := strings.Cut(amzDate, "T")
+	// a server sees the host in r.Host rather than r.URL.Host
+	rr := r.Clone(r.Context())
+	rr.URL.Host = r.Host
+	sum := sha256.Sum256([]byte(canonicalRequest(rr, amzDate)))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + day + "/us-east-1/s3/aws4_request\n" + hex.EncodeToString(sum[:])
+	sig := hex.EncodeToString(hmacSHA256(signingKey(f.secret, day, "us-east-1", "s3"), toSign))
+	if !strings.HasSuffix(r.Header.Get("Authorization"), "Signature="+sig) {
+		http.Error(w, "SignatureDoesNotMatch", http.StatusForbidden)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		b, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.EscapedPath()] = b
+	case http.MethodGet:
+		b, ok := f.objects[r.URL.EscapedPath()]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(b)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.EscapedPath())
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestS3RoundTrip(t *testing.T) {
+	fake := &fakeS3{objects: map[string][]byte{}, secret: "s3cr3t"}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	s, err := NewS3(srv.URL, "", "bucket", "AKID", "s3cr3t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	key := "appointments/7/x ray (left).png"
+	body := []byte("\x89PNG...")
+	if err := s.Put(ctx, key, bytes.NewReader(body), int64(len(body)), "image/png"); err != nil {
+		t.Fatal(err)
+	}
+	rc, err := s.Get(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(rc)
+	rc.Close()
+	if !bytes.Equal(got, body) {
+		t.Fatalf("Get = %q, want %q", got, body)
+	}
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after Delete: %v", err)
+	}
+
+	bad, _ := NewS3(srv.URL, "", "bucket", "AKID", "wrong")
+	if _, err := bad.Get(ctx, key); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("wrong secret: %v", err)
+	}
+	if _, err := s.Get(ctx, "../other-bucket/x"); err == nil {
+		t.Fatal("Get accepted a key escaping the bucket")
+	}
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidKey(t *testing.T) {
+	for _, key := range []string{"a", "appointments/7/3f2a.pdf", "x/y/z", "..a/b..", "a b/ü.txt"} {
+		if !validKey(key) {
+			t.Errorf("validKey(%q) = false", key)
+		}
+	}
+	for _, key := range []string{"", "/etc/passwd", "../x", "a/../../x", "a/./b", "a//b", "a/", `a\b`, ".."} {
+		if validKey(key) {
+			t.Errorf("validKey(%q) = true", key)
+		}
+	}
+}
+
+func TestLocal(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLocal(filepath.Join(dir, "store"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	body := []byte("%PDF-1.7 referral letter")
+
+	if err := l.Put(ctx, "appointments/7/a.pdf", bytes.NewReader(body), int64(len(body)), "application/pdf"); err != nil {
+		t.Fatal(err)
+	}
+	rc, err := l.Get(ctx, "appointments/7/a.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(rc)
+	rc.Close()
+	if !bytes.Equal(got, body) {
+		t.Fatalf("Get = %q, want %q", got, body)
+	}
+
+	// a short body is refused and leaves neither the object nor a temp file
+	if err := l.Put(ctx, "appointments/7/b.pdf", strings.NewReader("abc"), 10, ""); err == nil {
+		t.Fatal("Put accepted a short body")
+	}
+	if _, err := l.Get(ctx, "appointments/7/b.pdf"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after failed Put: %v", err)
+	}
+	entries, _ := os.ReadDir(filepath.Join(l.Dir, "appointments", "7"))
+	if len(entries) != 1 {
+		t.Fatalf("directory holds %d entries, want 1", len(entries))
+	}
+
+	if err := l.Delete(ctx, "appointments/7/a.pdf"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Get(ctx, "appointments/7/a.pdf"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after Delete: %v", err)
+	}
+	if err := l.Delete(ctx, "appointments/7/a.pdf"); err != nil {
+		t.Fatalf("deleting a missing key: %v", err)
+	}
+
+	// keys can't reach outside the directory
+	secret := filepath.Join(dir, "secret")
+	_ = os.WriteFile(secret, []byte("x"), 0o600)
+	if _, err := l.Get(ctx, "../secret"); err == nil {
+		t.Fatal("Get escaped the store directory")
+	}
+	if err := l.Put(ctx, "../secret", strings.NewReader("y"), 1, ""); err == nil {
+		t.Fatal("Put escaped the store directory")
+	}
+}
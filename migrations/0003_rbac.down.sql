@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS role_assignments;
+
+UPDATE users SET role = 'patient' WHERE role IN ('clinic_manager','receptionist');
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check
+  CHECK (role IN ('patient','provider','admin'));
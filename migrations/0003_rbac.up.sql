@@ -0,0 +1,26 @@
+-- Staff account types beyond provider/admin
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check
+  CHECK (role IN ('patient','provider','admin','clinic_manager','receptionist'));
+
+-- Role assignments: a role held in one clinic, or in every clinic when clinic_id IS NULL.
+-- Permissions per role live in code (internal/rbac).
+CREATE TABLE IF NOT EXISTS role_assignments (
+  id          BIGSERIAL PRIMARY KEY,
+  user_id     BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  clinic_id   BIGINT REFERENCES clinics(id) ON DELETE CASCADE,
+  role        TEXT NOT NULL CHECK (role IN ('admin','clinic_manager','receptionist','provider')),
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS role_assignments_user_clinic_role
+  ON role_assignments (user_id, COALESCE(clinic_id, 0), role);
+
+-- Backfill: existing admins are global, providers are scoped to their clinic.
+INSERT INTO role_assignments (user_id, clinic_id, role)
+SELECT id, NULL, 'admin' FROM users WHERE role = 'admin'
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_assignments (user_id, clinic_id, role)
+SELECT user_id, clinic_id, 'provider' FROM providers
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,7 @@
+-- Link staff users to their corporate IdP identity (OIDC issuer + subject).
+ALTER TABLE users ADD COLUMN IF NOT EXISTS oidc_issuer  TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS oidc_subject TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_oidc_identity
+  ON users (oidc_issuer, oidc_subject)
+  WHERE oidc_subject IS NOT NULL;
@@ -0,0 +1,29 @@
+-- TOTP second factor (RFC 6238). enabled_at stays NULL until the first code is confirmed.
+CREATE TABLE IF NOT EXISTS user_mfa (
+  user_id         BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  totp_secret     TEXT NOT NULL,
+  enabled_at      TIMESTAMPTZ,
+  last_used_step  BIGINT NOT NULL DEFAULT 0,   -- replay protection
+  failed_attempts INTEGER NOT NULL DEFAULT 0,
+  last_failed_at  TIMESTAMPTZ,
+  created_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- One-time recovery codes (SHA-256 of the normalized code)
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+  id          BIGSERIAL PRIMARY KEY,
+  user_id     BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  code_hash   TEXT NOT NULL,
+  used_at     TIMESTAMPTZ,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+  UNIQUE (user_id, code_hash)
+);
+
+-- Single-row security policy managed by admins
+CREATE TABLE IF NOT EXISTS security_policy (
+  id                 BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+  require_staff_mfa  BOOLEAN NOT NULL DEFAULT FALSE,
+  updated_at         TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+INSERT INTO security_policy (id) VALUES (TRUE) ON CONFLICT DO NOTHING;
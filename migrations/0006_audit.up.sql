@@ -0,0 +1,35 @@
+-- Append-only record of who read or changed patient data.
+-- No foreign keys on purpose: entries must outlive the rows they mention.
+CREATE TABLE IF NOT EXISTS audit_log (
+  id             BIGSERIAL PRIMARY KEY,
+  occurred_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+  actor_user_id  BIGINT,
+  actor_role     TEXT NOT NULL DEFAULT '',
+  action         TEXT NOT NULL,            -- e.g. appointment.create, appointment.list
+  resource_type  TEXT NOT NULL,            -- e.g. appointment, provider_schedule
+  resource_id    BIGINT,
+  patient_id     BIGINT,
+  clinic_id      BIGINT,
+  request_id     TEXT NOT NULL DEFAULT '',
+  ip             TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_occurred_at ON audit_log (occurred_at);
+CREATE INDEX IF NOT EXISTS audit_log_patient     ON audit_log (patient_id, occurred_at);
+CREATE INDEX IF NOT EXISTS audit_log_actor       ON audit_log (actor_user_id, occurred_at);
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_no_change ON audit_log;
+CREATE TRIGGER audit_log_no_change
+  BEFORE UPDATE OR DELETE ON audit_log
+  FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+DROP TRIGGER IF EXISTS audit_log_no_truncate ON audit_log;
+CREATE TRIGGER audit_log_no_truncate
+  BEFORE TRUNCATE ON audit_log
+  FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
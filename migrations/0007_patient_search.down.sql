@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS patients_dob;
+DROP INDEX IF EXISTS patients_phone;
+DROP INDEX IF EXISTS patients_full_name_trgm;
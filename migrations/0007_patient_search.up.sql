@@ -0,0 +1,6 @@
+-- Front-desk patient lookup by name fragment, phone or date of birth
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS patients_full_name_trgm ON patients USING gin (full_name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS patients_phone ON patients (phone);
+CREATE INDEX IF NOT EXISTS patients_dob ON patients (dob);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS patient_guardians;
+-- Fails if login-less dependents exist; remove them first.
+ALTER TABLE patients ALTER COLUMN user_id SET NOT NULL;
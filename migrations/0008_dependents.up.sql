@@ -0,0 +1,13 @@
+-- Dependents: children or relatives booked for by another account.
+-- A dependent without their own login has patients.user_id = NULL.
+ALTER TABLE patients ALTER COLUMN user_id DROP NOT NULL;
+
+CREATE TABLE IF NOT EXISTS patient_guardians (
+  guardian_user_id  BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  patient_id        BIGINT NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+  relationship      TEXT NOT NULL CHECK (relationship IN ('parent','guardian','carer','spouse','other')),
+  created_at        TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (guardian_user_id, patient_id)
+);
+
+CREATE INDEX IF NOT EXISTS patient_guardians_patient ON patient_guardians (patient_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS appointments_patient_start;
+ALTER TABLE appointments
+  DROP COLUMN IF EXISTS cancelled_by,
+  DROP COLUMN IF EXISTS cancelled_at,
+  DROP COLUMN IF EXISTS cancellation_reason;
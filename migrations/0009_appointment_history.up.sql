@@ -0,0 +1,8 @@
+-- Who cancelled an appointment, when and why, for the patient's history.
+ALTER TABLE appointments
+  ADD COLUMN IF NOT EXISTS cancellation_reason TEXT,
+  ADD COLUMN IF NOT EXISTS cancelled_at        TIMESTAMPTZ,
+  ADD COLUMN IF NOT EXISTS cancelled_by        BIGINT REFERENCES users(id) ON DELETE SET NULL;
+
+-- History listing pages by (start_time, id) per patient.
+CREATE INDEX IF NOT EXISTS appointments_patient_start ON appointments (patient_id, start_time, id);
@@ -0,0 +1,21 @@
+-- Data-subject erasure requests. Approval anonymizes the person in place:
+-- appointment rows stay (clinics.RESTRICT FKs and statistics need them), but
+-- names, contact details and free text are wiped.
+CREATE TABLE IF NOT EXISTS erasure_requests (
+  id             BIGSERIAL PRIMARY KEY,
+  user_id        BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,  -- requester
+  patient_id     BIGINT REFERENCES patients(id),                          -- profile to erase, if any
+  erase_account  BOOLEAN NOT NULL,                                        -- false = a dependent only
+  reason         TEXT,
+  status         TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending','rejected','completed')),
+  requested_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+  reviewed_by    BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  reviewed_at    TIMESTAMPTZ,
+  review_note    TEXT,
+  CHECK (erase_account OR patient_id IS NOT NULL)
+);
+
+-- At most one open request per subject.
+CREATE UNIQUE INDEX IF NOT EXISTS erasure_requests_pending
+  ON erasure_requests (user_id, COALESCE(patient_id, 0)) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS erasure_requests_status ON erasure_requests (status, id);
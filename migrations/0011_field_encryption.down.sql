@@ -0,0 +1,8 @@
+-- Run the re-encryption command with -decrypt first; encrypted dates cannot
+-- be cast back.
+DROP INDEX IF EXISTS patients_dob_bidx;
+DROP INDEX IF EXISTS patients_phone_bidx;
+ALTER TABLE patients ALTER COLUMN dob TYPE DATE USING dob::date;
+CREATE INDEX IF NOT EXISTS patients_phone ON patients (phone);
+CREATE INDEX IF NOT EXISTS patients_dob ON patients (dob);
+DROP TABLE IF EXISTS encryption_keys;
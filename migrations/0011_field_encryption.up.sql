@@ -0,0 +1,28 @@
+-- Application-level encryption of patients.phone, patients.dob and
+-- appointments.notes. Data keys are stored wrapped by a KEK kept outside the
+-- database (keyfile or KMS); see internal/fieldcrypt.
+--
+-- Part of this migration: run `go run ./cmd/reencrypt` right after applying
+-- it (it creates the keys if the server hasn't yet). Until then, existing
+-- patients keep plaintext phone/dob, which still displays but no longer
+-- matches phone or dob search; the server logs a warning at startup and
+-- `medctl check-config` reports them.
+CREATE TABLE IF NOT EXISTS encryption_keys (
+  id           BIGSERIAL PRIMARY KEY,
+  purpose      TEXT NOT NULL CHECK (purpose IN ('data','blind_index')),
+  version      INTEGER NOT NULL,
+  wrapped_key  BYTEA NOT NULL,
+  kek_id       TEXT NOT NULL,
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+  UNIQUE (purpose, version)
+);
+
+-- Ciphertext is text; existing dates stay readable as legacy plaintext until
+-- `go run ./cmd/reencrypt` seals them.
+ALTER TABLE patients ALTER COLUMN dob TYPE TEXT USING to_char(dob, 'YYYY-MM-DD');
+
+-- Exact-match search goes through the blind index segment of the ciphertext.
+DROP INDEX IF EXISTS patients_phone;
+DROP INDEX IF EXISTS patients_dob;
+CREATE INDEX IF NOT EXISTS patients_phone_bidx ON patients ((split_part(phone, ':', 3)));
+CREATE INDEX IF NOT EXISTS patients_dob_bidx   ON patients ((split_part(dob, ':', 3)));
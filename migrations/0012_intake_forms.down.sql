@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS intake_responses;
+DROP TABLE IF EXISTS intake_forms;
@@ -0,0 +1,22 @@
+-- Pre-visit questionnaires. Each service has versioned form schemas (the
+-- latest is live); a response keeps the version it was answered against.
+CREATE TABLE IF NOT EXISTS intake_forms (
+  id          BIGSERIAL PRIMARY KEY,
+  service_id  BIGINT NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+  version     INTEGER NOT NULL,
+  schema      JSONB NOT NULL,
+  created_by  BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+  UNIQUE (service_id, version)
+);
+
+-- answers is JSON encrypted by the application (internal/fieldcrypt).
+CREATE TABLE IF NOT EXISTS intake_responses (
+  id              BIGSERIAL PRIMARY KEY,
+  appointment_id  BIGINT NOT NULL UNIQUE REFERENCES appointments(id) ON DELETE CASCADE,
+  form_id         BIGINT NOT NULL REFERENCES intake_forms(id) ON DELETE RESTRICT,
+  answers         TEXT NOT NULL,
+  submitted_by    BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  submitted_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,14 @@
+-- Provider clinical notes, kept apart from the booking notes on
+-- appointments. Every edit is a new version; signing the latest version
+-- locks the note. body is encrypted by the application (internal/fieldcrypt).
+CREATE TABLE IF NOT EXISTS visit_notes (
+  id              BIGSERIAL PRIMARY KEY,
+  appointment_id  BIGINT NOT NULL REFERENCES appointments(id) ON DELETE CASCADE,
+  version         INTEGER NOT NULL,
+  body            TEXT NOT NULL,
+  author_id       BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+  signed_at       TIMESTAMPTZ,
+  signed_by       BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  UNIQUE (appointment_id, version)
+);
@@ -0,0 +1,15 @@
+-- Files uploaded against an appointment (referral letters, lab results).
+-- The bytes live in object storage under storage_key; filename is
+-- encrypted by the application (internal/fieldcrypt).
+CREATE TABLE IF NOT EXISTS attachments (
+  id              BIGSERIAL PRIMARY KEY,
+  appointment_id  BIGINT NOT NULL REFERENCES appointments(id) ON DELETE CASCADE,
+  storage_key     TEXT NOT NULL UNIQUE,
+  filename        TEXT NOT NULL,
+  content_type    TEXT NOT NULL,
+  size_bytes      BIGINT NOT NULL CHECK (size_bytes > 0),
+  sha256          TEXT NOT NULL,
+  uploaded_by     BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  created_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS attachments_appointment ON attachments (appointment_id, id);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS appointment_resources;
+DROP TABLE IF EXISTS service_resource_kinds;
+DROP TABLE IF EXISTS resource_availabilities;
+DROP TABLE IF EXISTS resources;
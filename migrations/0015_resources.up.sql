@@ -0,0 +1,45 @@
+-- Rooms and equipment a service may need besides the provider. kind is
+-- free-form ("room", "ultrasound", "dental_chair"); a service needs one
+-- resource of each kind it lists.
+CREATE TABLE IF NOT EXISTS resources (
+  id          BIGSERIAL PRIMARY KEY,
+  clinic_id   BIGINT NOT NULL REFERENCES clinics(id) ON DELETE CASCADE,
+  kind        TEXT NOT NULL,
+  name        TEXT NOT NULL,
+  active      BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+  UNIQUE (clinic_id, name)
+);
+CREATE INDEX IF NOT EXISTS resources_clinic_kind ON resources (clinic_id, kind) WHERE active;
+
+-- Weekly opening windows (1=Mon ... 7=Sun), like availabilities. A resource
+-- without any rows is usable whenever the provider is.
+CREATE TABLE IF NOT EXISTS resource_availabilities (
+  id           BIGSERIAL PRIMARY KEY,
+  resource_id  BIGINT NOT NULL REFERENCES resources(id) ON DELETE CASCADE,
+  weekday      INTEGER NOT NULL CHECK (weekday BETWEEN 1 AND 7),
+  start_hhmm   TEXT NOT NULL,
+  end_hhmm     TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS resource_availabilities_resource ON resource_availabilities (resource_id, weekday);
+
+CREATE TABLE IF NOT EXISTS service_resource_kinds (
+  service_id  BIGINT NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+  kind        TEXT NOT NULL,
+  PRIMARY KEY (service_id, kind)
+);
+
+-- Resources held by an appointment. Rows are removed on cancellation, and
+-- the exclusion constraint makes double-booking a resource impossible.
+CREATE TABLE IF NOT EXISTS appointment_resources (
+  appointment_id  BIGINT NOT NULL REFERENCES appointments(id) ON DELETE CASCADE,
+  resource_id     BIGINT NOT NULL REFERENCES resources(id) ON DELETE RESTRICT,
+  start_time      TIMESTAMPTZ NOT NULL,
+  end_time        TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (appointment_id, resource_id),
+  EXCLUDE USING gist (
+    resource_id WITH =,
+    tstzrange(start_time, end_time, '[)') WITH &&
+  )
+);
@@ -0,0 +1,17 @@
+-- Which providers offer which services. duration_min overrides the
+-- service's default for that provider (e.g. a slower new hire).
+CREATE TABLE IF NOT EXISTS provider_services (
+  provider_id   BIGINT NOT NULL REFERENCES providers(id) ON DELETE CASCADE,
+  service_id    BIGINT NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+  duration_min  INTEGER CHECK (duration_min > 0),
+  PRIMARY KEY (provider_id, service_id)
+);
+CREATE INDEX IF NOT EXISTS provider_services_service ON provider_services (service_id);
+
+-- Keep today's behaviour for existing data: every provider offers every
+-- service of their clinic until an admin narrows it down.
+INSERT INTO provider_services (provider_id, service_id)
+SELECT p.id, s.id
+FROM providers p
+JOIN services s ON s.clinic_id = p.clinic_id
+ON CONFLICT DO NOTHING;
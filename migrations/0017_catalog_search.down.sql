@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS services_clinic;
+DROP INDEX IF EXISTS providers_clinic;
+DROP INDEX IF EXISTS services_name_trgm;
+DROP INDEX IF EXISTS providers_full_name_trgm;
+DROP INDEX IF EXISTS clinics_name_trgm;
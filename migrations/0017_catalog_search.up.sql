@@ -0,0 +1,7 @@
+-- Patient-app catalog browsing: name search and per-clinic filters.
+-- pg_trgm comes from 0007_patient_search.
+CREATE INDEX IF NOT EXISTS clinics_name_trgm ON clinics USING gin (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS providers_full_name_trgm ON providers USING gin (full_name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS services_name_trgm ON services USING gin (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS providers_clinic ON providers (clinic_id, id);
+CREATE INDEX IF NOT EXISTS services_clinic ON services (clinic_id, id);
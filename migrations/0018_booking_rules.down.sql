@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS service_booking_rules;
+DROP TABLE IF EXISTS clinic_booking_rules;
@@ -0,0 +1,19 @@
+-- When patients may book. A clinic sets the defaults; a service row
+-- overrides them column by column (NULL = inherit, or no limit at clinic
+-- level). same_day_cutoff is HH:MM clinic time after which today's slots
+-- are no longer offered.
+CREATE TABLE IF NOT EXISTS clinic_booking_rules (
+  clinic_id         BIGINT PRIMARY KEY REFERENCES clinics(id) ON DELETE CASCADE,
+  min_lead_minutes  INTEGER CHECK (min_lead_minutes >= 0),
+  max_advance_days  INTEGER CHECK (max_advance_days > 0),
+  same_day_cutoff   TEXT CHECK (same_day_cutoff ~ '^([01][0-9]|2[0-3]):[0-5][0-9]$'),
+  updated_at        TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS service_booking_rules (
+  service_id        BIGINT PRIMARY KEY REFERENCES services(id) ON DELETE CASCADE,
+  min_lead_minutes  INTEGER CHECK (min_lead_minutes >= 0),
+  max_advance_days  INTEGER CHECK (max_advance_days > 0),
+  same_day_cutoff   TEXT CHECK (same_day_cutoff ~ '^([01][0-9]|2[0-3]):[0-5][0-9]$'),
+  updated_at        TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,13 @@
+-- Replay protection for retried writes (Idempotency-Key header). Keys are
+-- per user and live for 24 hours; status_code is NULL while the first
+-- request is still running. response_body is field-encrypted.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+  user_id        BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  key            TEXT NOT NULL,
+  request_hash   TEXT NOT NULL,
+  status_code    INTEGER,
+  response_body  TEXT,
+  created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (user_id, key)
+);
+CREATE INDEX IF NOT EXISTS idempotency_keys_created ON idempotency_keys (created_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS services_name_fts;
+DROP INDEX IF EXISTS providers_full_name_fts;
+DROP INDEX IF EXISTS clinics_name_fts;
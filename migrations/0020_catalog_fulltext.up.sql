@@ -0,0 +1,6 @@
+-- Full-text side of the catalog name search (the substring side uses the
+-- trigram indexes from 0017). The 'simple' configuration keeps names as
+-- typed: no stemming, no stop words.
+CREATE INDEX IF NOT EXISTS clinics_name_fts ON clinics USING gin (to_tsvector('simple', name));
+CREATE INDEX IF NOT EXISTS providers_full_name_fts ON providers USING gin (to_tsvector('simple', full_name));
+CREATE INDEX IF NOT EXISTS services_name_fts ON services USING gin (to_tsvector('simple', name));
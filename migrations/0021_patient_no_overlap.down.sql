@@ -0,0 +1 @@
+ALTER TABLE appointments DROP CONSTRAINT IF EXISTS appointments_patient_no_overlap;
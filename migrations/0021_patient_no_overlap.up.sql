@@ -0,0 +1,15 @@
+-- A patient can't hold two scheduled visits at once, with any providers.
+-- The booking handler checks this first for a friendlier error; the
+-- constraint closes the race between that check and the insert.
+-- Fails if overlapping scheduled visits already exist; find them with
+--   SELECT a.patient_id, a.id, b.id FROM appointments a JOIN appointments b
+--     ON a.patient_id = b.patient_id AND a.id < b.id
+--    AND tstzrange(a.start_time, a.end_time, '[)') && tstzrange(b.start_time, b.end_time, '[)')
+--   WHERE a.status = 'scheduled' AND b.status = 'scheduled';
+-- and cancel or move one of each pair first.
+ALTER TABLE appointments
+  ADD CONSTRAINT appointments_patient_no_overlap
+  EXCLUDE USING gist (
+    patient_id WITH =,
+    tstzrange(start_time, end_time, '[)') WITH &&
+  ) WHERE (status = 'scheduled');
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS appointments_start_provider;
@@ -0,0 +1,2 @@
+-- Keyset order of the day-list export (ListAppointmentsForExport).
+CREATE INDEX IF NOT EXISTS appointments_start_provider ON appointments (start_time, provider_id, id);
@@ -0,0 +1,4 @@
+-- The request holding an in-flight key: only it may store or release the
+-- response, so a claim taken over from a request presumed dead can't be
+-- overwritten when that request finishes after all.
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS claim_token TEXT NOT NULL DEFAULT '';